@@ -5,13 +5,23 @@ const (
 	FileTypeXlsx = ".xlsx" // xlsx文件
 	FileTypeXls  = ".xls"  // xls文件
 	FileTypeCsv  = ".csv"  // csv文件
+	FileTypeTsv  = ".tsv"  // 制表符分隔的文本文件
+	FileTypeTxt  = ".txt"  // 文本文件, 按 csv 读取并自动检测分隔符
 	FileTypeZip  = ".zip"  // zip压缩文件
+	FileTypeHtml = ".html" // html表格, 常见于扩展名为 .xls 的导出文件
+	FileTypeOds  = ".ods"  // OpenDocument 表格文件
 )
 
 // 文件编码格式
 const (
-	CharsetUTF8     = "UTF-8"
-	CharsetGBK      = "GBK"
-	CharsetGB18030  = "GB18030"
-	CharsetISO88591 = "ISO-8859-1"
+	CharsetUTF8        = "UTF-8"
+	CharsetGBK         = "GBK"
+	CharsetGB18030     = "GB18030"
+	CharsetISO88591    = "ISO-8859-1"
+	CharsetUTF16LE     = "UTF-16LE" // Excel 另存为 "Unicode 文本" 的编码
+	CharsetUTF16BE     = "UTF-16BE"
+	CharsetBig5        = "Big5"
+	CharsetShiftJIS    = "Shift_JIS"
+	CharsetEUCKR       = "EUC-KR"
+	CharsetWindows1252 = "windows-1252"
 )
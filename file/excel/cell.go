@@ -0,0 +1,365 @@
+package excelutil
+
+import (
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"go_file/utils/timeutil"
+
+	"github.com/xuri/excelize/v2"
+)
+
+// CellKind 单元格数据类型
+type CellKind int
+
+const (
+	CellKindEmpty  CellKind = iota // 空单元格
+	CellKindString                 // 文本
+	CellKindInt                    // 整数
+	CellKindFloat                  // 小数
+	CellKindBool                   // 布尔值
+	CellKindDate                   // 日期时间
+	CellKindError                  // 错误值, 如 #DIV/0!
+)
+
+var cellKindNames = [...]string{"empty", "string", "int", "float", "bool", "date", "error"}
+
+func (k CellKind) String() string {
+	if k < 0 || int(k) >= len(cellKindNames) {
+		return "unknown"
+	}
+	return cellKindNames[k]
+}
+
+// Cell 带类型的单元格
+type Cell struct {
+	Kind   CellKind  // 数据类型
+	Text   string    // 原始文本, 和 ExcelSheet.Rows 中的值相同
+	Int    int64     // CellKindInt 的值
+	Float  float64   // CellKindInt 和 CellKindFloat 的值, 百分比为实际的值, 如 12.5% 为 0.125
+	Bool   bool      // CellKindBool 的值
+	Time   time.Time // CellKindDate 的值
+	Format string    // 数字格式代码, 如 "0.00%", "yyyy-mm-dd"; 文本文件和常规格式为空
+}
+
+// Value 返回单元格的值: int64, float64, bool, time.Time 或 string, 空单元格返回 nil
+func (c Cell) Value() any {
+	switch c.Kind {
+	case CellKindEmpty:
+		return nil
+	case CellKindInt:
+		return c.Int
+	case CellKindFloat:
+		return c.Float
+	case CellKindBool:
+		return c.Bool
+	case CellKindDate:
+		return c.Time
+	}
+	return c.Text
+}
+
+// cellErrorValues Excel 的错误值
+var cellErrorValues = map[string]bool{
+	"#NULL!": true, "#DIV/0!": true, "#VALUE!": true, "#REF!": true, "#NAME?": true,
+	"#NUM!": true, "#N/A": true, "#GETTING_DATA": true, "#SPILL!": true, "#CALC!": true,
+}
+
+var (
+	thousandsRegexp = regexp.MustCompile(`^[-+]?\d{1,3}(,\d{3})+(\.\d+)?$`)
+	// dateLikeRegexp 可能是日期时间的文本, 避免把普通文本交给日期解析
+	dateLikeRegexp = regexp.MustCompile(`^\d{1,4}[-/.年]\d{1,2}([-/.月]\d{1,4}日?)?([ T]\d{1,2}:\d{2}(:\d{2})?)?|^\d{1,2}:\d{2}(:\d{2})?$`)
+)
+
+// ParseCell 根据文本推断单元格的类型: 错误值、TRUE/FALSE、数字(支持千分位和百分比)、日期时间, 其余为文本.
+// 有前导零的整数和超过 15 位的整数(如编号、身份证号)作为文本
+func ParseCell(text string) Cell {
+	c := Cell{Text: text}
+	inferCell(&c)
+	return c
+}
+
+func inferCell(c *Cell) {
+	text := strings.TrimSpace(c.Text)
+	switch {
+	case text == "":
+		c.Kind = CellKindEmpty
+	case cellErrorValues[text]:
+		c.Kind = CellKindError
+	case strings.EqualFold(text, "TRUE"), strings.EqualFold(text, "FALSE"):
+		c.Kind, c.Bool = CellKindBool, strings.EqualFold(text, "TRUE")
+	case parseCellNumber(c, text):
+	case dateLikeRegexp.MatchString(text):
+		if t, err := timeutil.ParseDate(text); err == nil {
+			c.Kind, c.Time = CellKindDate, t
+			return
+		}
+		c.Kind = CellKindString
+	default:
+		c.Kind = CellKindString
+	}
+}
+
+// parseCellNumber 解析数字文本, 不是数字时返回 false
+func parseCellNumber(c *Cell, text string) bool {
+	percent := strings.HasSuffix(text, "%")
+	number := strings.TrimSuffix(text, "%")
+	if thousandsRegexp.MatchString(number) {
+		number = strings.ReplaceAll(number, ",", "")
+	} else if !decimalRegexp.MatchString(number) {
+		return false
+	}
+	digits := strings.TrimLeft(number, "+-")
+	if !percent && len(digits) > 1 && digits[0] == '0' && digits[1] != '.' {
+		// 有前导零的是编号
+		return false
+	}
+	value, err := strconv.ParseFloat(number, 64)
+	if err != nil {
+		return false
+	}
+	if percent {
+		c.Kind, c.Float = CellKindFloat, value/100
+		return true
+	}
+	if !strings.Contains(number, ".") {
+		if len(digits) > maxExactDigits {
+			return false
+		}
+		setCellNumber(c, value)
+		return true
+	}
+	c.Kind, c.Float = CellKindFloat, value
+	return true
+}
+
+// setCellNumber 设置数值, 没有小数部分且不超过 15 位的为整数
+func setCellNumber(c *Cell, value float64) {
+	c.Float = value
+	if value == float64(int64(value)) && value < 1e15 && value > -1e15 {
+		c.Kind, c.Int = CellKindInt, int64(value)
+		return
+	}
+	c.Kind = CellKindFloat
+}
+
+// cellHint 原始数据读取器提供的单元格类型, kind 为 CellKindEmpty 时按文本推断
+type cellHint struct {
+	kind   CellKind // CellKindFloat 表示数值, 根据 raw 判断是否为整数
+	format string   // 数字格式代码
+	raw    string   // 单元格保存的原始值
+}
+
+// typedSource 能提供单元格类型的原始数据读取器
+type typedSource interface {
+	// CellHints 当前行每个单元格的类型
+	CellHints() []cellHint
+}
+
+// newCell 按读取器提供的类型创建单元格, 原始值和类型不一致时按文本推断
+func newCell(text string, hint cellHint, date1904 bool) Cell {
+	c := Cell{Text: text, Format: hint.format}
+	if text == "" {
+		return c
+	}
+	switch hint.kind {
+	case CellKindString:
+		c.Kind = CellKindString
+		return c
+	case CellKindError:
+		c.Kind = CellKindError
+		return c
+	case CellKindBool:
+		c.Kind, c.Bool = CellKindBool, hint.raw == "1" || strings.EqualFold(text, "TRUE")
+		return c
+	case CellKindFloat, CellKindDate:
+		value, err := strconv.ParseFloat(hint.raw, 64)
+		if err == nil && hint.kind == CellKindFloat {
+			setCellNumber(&c, value)
+			return c
+		}
+		if err == nil {
+			if t, err := serialToTime(value, date1904); err == nil {
+				c.Kind, c.Time = CellKindDate, t
+				return c
+			}
+		} else if t, err := timeutil.ParseDate(hint.raw); err == nil {
+			// .xlsx 中 ISO 8601 格式保存的日期
+			c.Kind, c.Time = CellKindDate, t
+			return c
+		}
+	}
+	inferCell(&c)
+	return c
+}
+
+// newCells 创建一行带类型的单元格, hints 可以为 nil
+func newCells(cells []string, hints []cellHint, date1904 bool) []Cell {
+	row := make([]Cell, len(cells))
+	for i, text := range cells {
+		var hint cellHint
+		if i < len(hints) {
+			hint = hints[i]
+		}
+		row[i] = newCell(text, hint, date1904)
+	}
+	return row
+}
+
+// numFmtHint 根据数字格式返回数值单元格的类型
+func numFmtHint(id int, code, raw string) cellHint {
+	hint := cellHint{kind: CellKindFloat, format: code, raw: raw}
+	if hint.format == "" {
+		hint.format = builtInNumFmtCodes[id]
+	}
+	if dateFormatLayout(id, code) != "" {
+		hint.kind = CellKindDate
+	}
+	return hint
+}
+
+// numFmt 数字格式, 内置格式的 code 为空
+type numFmt struct {
+	id   int
+	code string
+}
+
+// xlsxHinter 读取 .xlsx 单元格的类型和数字格式
+type xlsxHinter struct {
+	file    *excelize.File
+	formats map[int]numFmt // 样式下标 -> 数字格式, 缓存
+}
+
+func newXLSXHinter(f *excelize.File) *xlsxHinter {
+	return &xlsxHinter{file: f, formats: make(map[int]numFmt)}
+}
+
+// row 读取表单第 rowNum 行的单元格类型, raw 为单元格保存的原始值;
+// attrs 为流式读取的单元格属性, 为 nil 时从 excelize 读取, 会加载整个表单
+func (h *xlsxHinter) row(sheet string, rowNum int, cells, raw []string, attrs []xlsxCellAttr) ([]cellHint, error) {
+	hints := make([]cellHint, len(cells))
+	for i, text := range cells {
+		if text == "" {
+			continue
+		}
+		attr, err := xlsxCellAttrAt(h.file, sheet, rowNum, i, attrs)
+		if err != nil {
+			return nil, err
+		}
+		if i < len(raw) {
+			hints[i].raw = raw[i]
+		}
+		switch attr.cellType {
+		case excelize.CellTypeSharedString, excelize.CellTypeInlineString, excelize.CellTypeFormula:
+			hints[i].kind = CellKindString
+		case excelize.CellTypeBool:
+			hints[i].kind = CellKindBool
+		case excelize.CellTypeError:
+			hints[i].kind = CellKindError
+		case excelize.CellTypeDate:
+			hints[i].kind = CellKindDate
+		case excelize.CellTypeNumber, excelize.CellTypeUnset:
+			if strings.HasPrefix(text, "=") {
+				// FormulaText 方式读取的公式
+				hints[i].kind = CellKindString
+				continue
+			}
+			format, ok := h.formats[attr.style]
+			if !ok {
+				if style, err := h.file.GetStyle(attr.style); err == nil {
+					format.id = style.NumFmt
+					if style.CustomNumFmt != nil {
+						format = numFmt{id: customNumFmtID, code: *style.CustomNumFmt}
+					}
+				}
+				h.formats[attr.style] = format
+			}
+			hints[i] = numFmtHint(format.id, format.code, hints[i].raw)
+		}
+	}
+	return hints, nil
+}
+
+// xlsxCellAttrAt 返回第 rowNum 行第 col 列(从 0 开始)单元格的类型和样式, 有流式读取的属性时直接使用,
+// 否则从 excelize 读取, 会加载整个表单
+func xlsxCellAttrAt(f *excelize.File, sheet string, rowNum, col int, attrs []xlsxCellAttr) (xlsxCellAttr, error) {
+	if attrs != nil {
+		if col < len(attrs) {
+			return attrs[col], nil
+		}
+		return xlsxCellAttr{}, nil
+	}
+	cell, err := excelize.CoordinatesToCellName(col+1, rowNum)
+	if err != nil {
+		return xlsxCellAttr{}, err
+	}
+	var attr xlsxCellAttr
+	if attr.cellType, err = f.GetCellType(sheet, cell); err != nil {
+		return attr, err
+	}
+	attr.style, err = f.GetCellStyle(sheet, cell)
+	return attr, err
+}
+
+// setCells 根据 Rows 设置带类型的数据和列类型, hints[i] 为第 i 行的单元格类型, 可以为 nil
+func (s *ExcelSheet) setCells(hints [][]cellHint, date1904 bool) {
+	width := 0
+	s.Cells = make([][]Cell, len(s.Rows))
+	for i, row := range s.Rows {
+		var rowHints []cellHint
+		if i < len(hints) {
+			rowHints = hints[i]
+		}
+		s.Cells[i] = newCells(row, rowHints, date1904)
+		width = max(width, len(row))
+	}
+	if len(s.Cells) > 0 {
+		// 第一行作为表头不参与推断
+		s.ColumnTypes = inferColumnTypes(s.Cells[1:], width)
+	}
+}
+
+// setXLSXCells 按 .xlsx 表单的单元格类型和数字格式设置带类型的数据
+func (s *ExcelSheet) setXLSXCells(f *excelize.File, date1904 bool) error {
+	raw, err := f.GetRows(s.SheetName, excelize.Options{RawCellValue: true})
+	if err != nil {
+		return err
+	}
+	hinter := newXLSXHinter(f)
+	hints := make([][]cellHint, len(s.Rows))
+	for i, row := range s.Rows {
+		var rawRow []string
+		if i < len(raw) {
+			rawRow = raw[i]
+		}
+		if hints[i], err = hinter.row(s.SheetName, i+1, row, rawRow, nil); err != nil {
+			return err
+		}
+	}
+	s.setCells(hints, date1904)
+	return nil
+}
+
+// inferColumnTypes 推断每一列的类型: 忽略空单元格和错误值, 整数和小数混合时为小数,
+// 其他类型混合时为文本, 没有数据的列为 CellKindEmpty
+func inferColumnTypes(rows [][]Cell, width int) []CellKind {
+	kinds := make([]CellKind, width)
+	for _, row := range rows {
+		for i, c := range row {
+			if i >= width || c.Kind == CellKindEmpty || c.Kind == CellKindError {
+				continue
+			}
+			switch kind := kinds[i]; {
+			case kind == CellKindEmpty, kind == c.Kind:
+				kinds[i] = c.Kind
+			case kind == CellKindInt && c.Kind == CellKindFloat, kind == CellKindFloat && c.Kind == CellKindInt:
+				kinds[i] = CellKindFloat
+			default:
+				kinds[i] = CellKindString
+			}
+		}
+	}
+	return kinds
+}
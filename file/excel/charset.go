@@ -0,0 +1,248 @@
+package excelutil
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"io"
+	"strings"
+	"unicode/utf8"
+
+	"go_file/common"
+
+	"github.com/gogs/chardet"
+	"golang.org/x/text/encoding"
+	"golang.org/x/text/encoding/charmap"
+	"golang.org/x/text/encoding/htmlindex"
+	"golang.org/x/text/encoding/japanese"
+	"golang.org/x/text/encoding/korean"
+	"golang.org/x/text/encoding/simplifiedchinese"
+	"golang.org/x/text/encoding/traditionalchinese"
+	"golang.org/x/text/encoding/unicode"
+	"golang.org/x/text/transform"
+)
+
+var (
+	utf16LEBOM = []byte{0xFF, 0xFE}
+	utf16BEBOM = []byte{0xFE, 0xFF}
+)
+
+// charsetEncodings 常用的编码, 其他编码按 WHATWG 编码名称查找
+var charsetEncodings = map[string]encoding.Encoding{
+	common.CharsetUTF8:        unicode.UTF8,
+	common.CharsetGBK:         simplifiedchinese.GBK,
+	common.CharsetGB18030:     simplifiedchinese.GB18030,
+	common.CharsetISO88591:    charmap.ISO8859_1,
+	common.CharsetUTF16LE:     unicode.UTF16(unicode.LittleEndian, unicode.IgnoreBOM),
+	common.CharsetUTF16BE:     unicode.UTF16(unicode.BigEndian, unicode.IgnoreBOM),
+	common.CharsetBig5:        traditionalchinese.Big5,
+	common.CharsetShiftJIS:    japanese.ShiftJIS,
+	common.CharsetEUCKR:       korean.EUCKR,
+	common.CharsetWindows1252: charmap.Windows1252,
+}
+
+// lookupEncoding 根据编码名称查找编码, 不区分大小写; UTF-8 返回 nil, 表示不需要解码
+func lookupEncoding(name string) (encoding.Encoding, error) {
+	enc, ok := charsetEncodings[name]
+	if !ok {
+		for charset, e := range charsetEncodings {
+			if strings.EqualFold(charset, name) {
+				enc, ok = e, true
+				break
+			}
+		}
+	}
+	if !ok {
+		var err error
+		if enc, err = htmlindex.Get(name); err != nil {
+			return nil, fmt.Errorf("不支持的文件编码:%s", name)
+		}
+	}
+	if enc == unicode.UTF8 {
+		return nil, nil
+	}
+	return enc, nil
+}
+
+// encodingName 返回编码的名称, nil 表示 UTF-8
+func encodingName(enc encoding.Encoding) string {
+	if enc == nil {
+		return common.CharsetUTF8
+	}
+	for name, e := range charsetEncodings {
+		if e == enc {
+			return name
+		}
+	}
+	if name, err := htmlindex.Name(enc); err == nil {
+		return name
+	}
+	return ""
+}
+
+// textEncoding 确定文本数据的编码, 返回 nil 表示 UTF-8; bom 为开头 BOM 的字节数, 读取时需要跳过.
+// BOM 优先, 其次是 charset 指定的编码, 最后按内容检测
+func textEncoding(sample []byte, charset string) (enc encoding.Encoding, bom int, err error) {
+	switch {
+	case bytes.HasPrefix(sample, utf8BOM):
+		return nil, len(utf8BOM), nil
+	case bytes.HasPrefix(sample, utf16LEBOM):
+		return charsetEncodings[common.CharsetUTF16LE], len(utf16LEBOM), nil
+	case bytes.HasPrefix(sample, utf16BEBOM):
+		return charsetEncodings[common.CharsetUTF16BE], len(utf16BEBOM), nil
+	}
+	if charset == "" {
+		if charset, err = detectCharset(sample); err != nil {
+			return nil, 0, err
+		}
+	}
+	enc, err = lookupEncoding(charset)
+	return enc, 0, err
+}
+
+// detectCharset 按内容检测编码, 合法的 UTF-8(包括纯 ASCII)直接认为是 UTF-8, 避免短文件被误判
+func detectCharset(sample []byte) (string, error) {
+	if charset := utf16Charset(sample); charset != "" {
+		return charset, nil
+	}
+	if validUTF8(sample) {
+		return common.CharsetUTF8, nil
+	}
+	results, err := chardet.NewTextDetector().DetectAll(sample)
+	if err != nil {
+		return "", err
+	}
+	best := results[0].Charset
+	if multiByteCharsets[best] || !validGBK(sample) {
+		return best, nil
+	}
+	// 拉丁字母编码(Windows-1252 等)的重音字母后面跟 ASCII 字母时也能按 GBK 解码, 只有基本都是常用汉字时才继续
+	if latinCharsets[best] && !mostlyGB2312(sample) {
+		return best, nil
+	}
+	// 中文较少时 chardet 容易把多字节编码误判为单字节编码, 如 ISO-8859-5、windows-1251;
+	// 找到常用字的多字节编码置信度大于 10, 优先使用, 都没有找到时按 GBK 处理
+	for _, result := range results[1:] {
+		if multiByteCharsets[result.Charset] && result.Confidence > 10 {
+			return result.Charset, nil
+		}
+	}
+	return common.CharsetGB18030, nil
+}
+
+// latinCharsets chardet 检测结果中的拉丁字母单字节编码
+var latinCharsets = map[string]bool{
+	common.CharsetISO88591: true, "ISO-8859-2": true, "ISO-8859-9": true,
+	common.CharsetWindows1252: true, "windows-1250": true, "windows-1254": true,
+}
+
+// multiByteCharsets chardet 检测结果中的多字节编码
+var multiByteCharsets = map[string]bool{
+	common.CharsetGB18030: true, common.CharsetBig5: true, common.CharsetShiftJIS: true, "EUC-JP": true,
+	common.CharsetEUCKR: true,
+	"ISO-2022-JP":       true, "ISO-2022-KR": true, "ISO-2022-CN": true,
+}
+
+// validGBK 判断采样数据是否为合法的 GBK 双字节编码, 忽略末尾被截断的字符
+func validGBK(sample []byte) bool {
+	for i := 0; i < len(sample); i++ {
+		c := sample[i]
+		if c < 0x80 {
+			continue
+		}
+		if c == 0x80 || c == 0xFF {
+			return false
+		}
+		if i+1 == len(sample) {
+			return true
+		}
+		if next := sample[i+1]; next < 0x40 || next == 0x7F || next == 0xFF {
+			return false
+		}
+		i++
+	}
+	return true
+}
+
+// mostlyGB2312 判断采样数据中的双字节字符是否基本都在 GB2312 区(两个字节都不小于 0xA1),
+// 即常用汉字和全角符号; 拉丁字母编码的重音字母后面通常是 ASCII 字母, 按 GBK 解码只会落在扩展区的生僻字上
+func mostlyGB2312(sample []byte) bool {
+	var total, gb2312 int
+	for i := 0; i+1 < len(sample); i++ {
+		if sample[i] < 0x80 {
+			continue
+		}
+		total++
+		if sample[i] >= 0xA1 && sample[i+1] >= 0xA1 {
+			gb2312++
+		}
+		i++
+	}
+	return total > 0 && gb2312*10 >= total*9
+}
+
+// validUTF8 判断采样数据是否为合法的 UTF-8, 忽略末尾被截断的字符
+func validUTF8(sample []byte) bool {
+	for i := 0; i < utf8.UTFMax && len(sample) > 0; i++ {
+		if utf8.Valid(sample) {
+			return true
+		}
+		sample = sample[:len(sample)-1]
+	}
+	return len(sample) == 0
+}
+
+// utf16Charset 检测没有 BOM 的 UTF-16 文本: ASCII 字符的高位字节为 0, 0 几乎都出现在奇数位(LE)或偶数位(BE)
+func utf16Charset(sample []byte) string {
+	sample = sample[:min(len(sample), 1024)]
+	n := len(sample) / 2
+	if n < 2 {
+		return ""
+	}
+	var even, odd int
+	for i := 0; i+1 < len(sample); i += 2 {
+		if sample[i] == 0 {
+			even++
+		}
+		if sample[i+1] == 0 {
+			odd++
+		}
+	}
+	switch {
+	case odd*4 >= n && even*8 < odd:
+		return common.CharsetUTF16LE
+	case even*4 >= n && odd*8 < even:
+		return common.CharsetUTF16BE
+	}
+	return ""
+}
+
+// newTextReader 把文本数据转换为 UTF-8, 跳过 BOM; charset 为空时按 textEncoding 的规则检测编码.
+// sample 为开头解码后的部分数据, 末尾可能是不完整的行; enc 为原文件的编码, UTF-8 时为 nil
+func newTextReader(r io.Reader, charset string) (text io.Reader, sample string, enc encoding.Encoding, err error) {
+	br := bufio.NewReaderSize(r, csvSampleSize)
+	// Peek 在数据小于采样大小时会返回 EOF, 已读取的数据仍可用于检测
+	head, err := br.Peek(csvSampleSize)
+	if err != nil && err != io.EOF && err != bufio.ErrBufferFull {
+		return nil, "", nil, err
+	}
+	enc, bom, err := textEncoding(head, charset)
+	if err != nil {
+		return nil, "", nil, err
+	}
+	head = head[bom:]
+	if enc == nil {
+		sample = string(head)
+	} else {
+		// 末尾不完整的字符会被替换, 不影响检测
+		decoded, _, _ := transform.Bytes(enc.NewDecoder(), head)
+		sample = string(decoded)
+	}
+	if _, err = br.Discard(bom); err != nil {
+		return nil, "", nil, err
+	}
+	if enc == nil {
+		return br, sample, nil, nil
+	}
+	return transform.NewReader(br, enc.NewDecoder()), sample, enc, nil
+}
@@ -0,0 +1,120 @@
+package excelutil
+
+import (
+	"testing"
+	"unicode/utf16"
+
+	"go_file/common"
+
+	"golang.org/x/text/encoding"
+	"golang.org/x/text/encoding/charmap"
+	"golang.org/x/text/encoding/japanese"
+	"golang.org/x/text/encoding/korean"
+	"golang.org/x/text/encoding/simplifiedchinese"
+	"golang.org/x/text/encoding/traditionalchinese"
+)
+
+func TestDetectCharset(t *testing.T) {
+	gbk, err := simplifiedchinese.GBK.NewEncoder().String("编码,名称,数量\n001,螺丝,100\n")
+	if err != nil {
+		t.Fatal(err)
+	}
+	utf16LE := func(s string) []byte {
+		var b []byte
+		for _, u := range utf16.Encode([]rune(s)) {
+			b = append(b, byte(u), byte(u>>8))
+		}
+		return b
+	}
+	utf16BE := func(s string) []byte {
+		var b []byte
+		for _, u := range utf16.Encode([]rune(s)) {
+			b = append(b, byte(u>>8), byte(u))
+		}
+		return b
+	}
+	tests := []struct {
+		name   string
+		sample []byte
+		want   string
+	}{
+		{"ASCII", []byte("code,name\n1,a\n"), common.CharsetUTF8},
+		{"UTF-8", []byte("编码,名称\n001,螺丝\n"), common.CharsetUTF8},
+		{"末尾截断的 UTF-8", []byte("编码,名称")[:10], common.CharsetUTF8},
+		{"GBK", []byte(gbk), common.CharsetGB18030},
+		{"末尾截断的 GBK", []byte(gbk)[:len(gbk)-len("100\n")-1], common.CharsetGB18030},
+		{"没有 BOM 的 UTF-16LE", utf16LE("code,name\n1,螺丝\n"), common.CharsetUTF16LE},
+		{"没有 BOM 的 UTF-16BE", utf16BE("code,name\n1,螺丝\n"), common.CharsetUTF16BE},
+	}
+	for _, tt := range tests {
+		got, err := detectCharset(tt.sample)
+		if err != nil || got != tt.want {
+			t.Errorf("%s: detectCharset() = %q, %v, want %q", tt.name, got, err, tt.want)
+		}
+	}
+}
+
+// 检测出的编码要能正确解码原文, chardet 对相近的编码(如 ISO-8859-1 和 windows-1252)可能返回任意一个
+func TestDetectCharsetDecode(t *testing.T) {
+	tests := []struct {
+		name string
+		enc  encoding.Encoding
+		text string
+	}{
+		{"Windows-1252", charmap.Windows1252, "Name;Adresse;Ort\nMüller;Straße 5;Köln\nGärtner;Hauptstraße 12;München\n"},
+		{"EUC-KR", korean.EUCKR, "이름,주소,수량\n홍길동,서울시 강남구,100\n김철수,부산시,200\n"},
+		{"Big5", traditionalchinese.Big5, "客戶名稱,聯絡電話,地址\n台灣電子股份有限公司,02-1234,台北市信義區\n"},
+		{"Shift-JIS", japanese.ShiftJIS, "番号,名前,数量\n001,ねじ,100\n002,ナット,200\n"},
+		{"GBK", simplifiedchinese.GBK, "编码,名称,数量\n001,螺丝,100\n"},
+	}
+	for _, tt := range tests {
+		sample, err := tt.enc.NewEncoder().String(tt.text)
+		if err != nil {
+			t.Fatal(err)
+		}
+		charset, err := detectCharset([]byte(sample))
+		if err != nil {
+			t.Errorf("%s: detectCharset() error = %v", tt.name, err)
+			continue
+		}
+		enc, err := lookupEncoding(charset)
+		if err != nil || enc == nil {
+			t.Errorf("%s: lookupEncoding(%q) = %v, %v", tt.name, charset, enc, err)
+			continue
+		}
+		if got, _ := enc.NewDecoder().String(sample); got != tt.text {
+			t.Errorf("%s: detectCharset() = %q, decoded %q, want %q", tt.name, charset, got, tt.text)
+		}
+	}
+}
+
+func TestMostlyGB2312(t *testing.T) {
+	latin, _ := charmap.Windows1252.NewEncoder().String("Müller;Straße 5")
+	gbk, _ := simplifiedchinese.GBK.NewEncoder().String("编码,名称")
+	if mostlyGB2312([]byte(latin)) {
+		t.Errorf("mostlyGB2312(%q) = true, want false", latin)
+	}
+	if !mostlyGB2312([]byte(gbk)) {
+		t.Errorf("mostlyGB2312(%q) = false, want true", gbk)
+	}
+}
+
+func TestValidGBK(t *testing.T) {
+	tests := []struct {
+		name   string
+		sample []byte
+		want   bool
+	}{
+		{"ASCII", []byte("abc"), true},
+		{"双字节", []byte{0xB1, 0xE0, 0xC2, 0xEB}, true},
+		{"末尾截断", []byte{0xB1, 0xE0, 0xC2}, true},
+		{"非法首字节", []byte{0x80, 0x41}, false},
+		{"非法尾字节", []byte{0xB1, 0x20}, false},
+		{"0xFF", []byte{0x41, 0xFF}, false},
+	}
+	for _, tt := range tests {
+		if got := validGBK(tt.sample); got != tt.want {
+			t.Errorf("%s: validGBK() = %v, want %v", tt.name, got, tt.want)
+		}
+	}
+}
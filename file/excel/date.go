@@ -0,0 +1,85 @@
+package excelutil
+
+import (
+	"strconv"
+	"strings"
+	"time"
+
+	"go_file/utils/timeutil"
+)
+
+// DateOnlyLayout 只有日期的输出格式
+const DateOnlyLayout = "2006-01-02"
+
+// RuleDate 严格模式下日期列无法解析时的规则名称
+const RuleDate = "date"
+
+// Excel 日期序列号的有效范围, 对应 1900-01-01 到 9999-12-31
+const (
+	minExcelSerial = 1
+	maxExcelSerial = 2958465
+)
+
+// DateColumn 日期列配置
+type DateColumn struct {
+	Title        string         // 源表头, 支持别名
+	InputLayouts []string       // 输入格式, 按顺序尝试, 都不匹配时自动识别
+	OutputLayout string         // 输出格式, 默认为 timeutil.DefaultTimeLayout, DateOnly 时默认为 DateOnlyLayout
+	Location     *time.Location // 不带时区的数据所在时区, 也是输出的时区; 为空时按 time.Local 解析, 输出保留原时区
+	DateOnly     bool           // 只保留日期
+	ExcelSerial  bool           // 支持 Excel 日期序列号, 如 45123.5
+	Strict       bool           // 严格模式, 无法解析时记录到 Violations, 否则保留原始数据
+}
+
+// outputLayout 返回输出格式
+func (c *DateColumn) outputLayout() string {
+	switch {
+	case c.OutputLayout != "":
+		return c.OutputLayout
+	case c.DateOnly:
+		return DateOnlyLayout
+	}
+	return timeutil.DefaultTimeLayout
+}
+
+// location 返回解析不带时区的数据时使用的时区
+func (c *DateColumn) location() *time.Location {
+	if c.Location != nil {
+		return c.Location
+	}
+	return time.Local
+}
+
+// parse 解析日期, 依次尝试 Excel 日期序列号、输入格式和自动识别, date1904 表示使用 1904 日期系统;
+// 超出序列号范围的数字(如 20230716)继续按输入格式和自动识别解析
+func (c *DateColumn) parse(value string, date1904 bool) (time.Time, error) {
+	value = strings.TrimSpace(value)
+	loc := c.location()
+	if c.ExcelSerial {
+		serial, err := strconv.ParseFloat(value, 64)
+		if err == nil && serial >= minExcelSerial && serial <= maxExcelSerial {
+			if t, err := serialToTime(serial, date1904); err == nil {
+				// 序列号没有时区, 按配置的时区解释
+				return time.Date(t.Year(), t.Month(), t.Day(), t.Hour(), t.Minute(), t.Second(), t.Nanosecond(), loc), nil
+			}
+		}
+	}
+	for _, layout := range c.InputLayouts {
+		if t, err := time.ParseInLocation(layout, value, loc); err == nil {
+			return t, nil
+		}
+	}
+	return timeutil.ParseDateIn(value, loc)
+}
+
+// format 把日期转换为输出格式
+func (c *DateColumn) format(value string, date1904 bool) (string, error) {
+	t, err := c.parse(value, date1904)
+	if err != nil {
+		return "", err
+	}
+	if c.Location != nil {
+		t = t.In(c.Location)
+	}
+	return t.Format(c.outputLayout()), nil
+}
@@ -0,0 +1,38 @@
+package excelutil
+
+import (
+	"testing"
+	"time"
+)
+
+func TestDateColumnFormat(t *testing.T) {
+	shanghai := time.FixedZone("CST", 8*3600)
+	tests := []struct {
+		name     string
+		column   DateColumn
+		value    string
+		date1904 bool
+		want     string
+		wantErr  bool
+	}{
+		{"自动识别", DateColumn{Location: time.UTC}, "2023/7/16 8:30", false, "2023-07-16 08:30:00", false},
+		{"只保留日期", DateColumn{Location: time.UTC, DateOnly: true}, "2023-07-16 08:30:00", false, "2023-07-16", false},
+		{"输出格式", DateColumn{Location: time.UTC, OutputLayout: "2006年01月02日"}, "2023-07-16", false, "2023年07月16日", false},
+		{"输入格式优先", DateColumn{Location: time.UTC, InputLayouts: []string{"02/01/2006"}, DateOnly: true}, "03/07/2023", false, "2023-07-03", false},
+		{"输入格式不匹配时自动识别", DateColumn{Location: time.UTC, InputLayouts: []string{"02/01/2006"}, DateOnly: true}, "2023-07-16", false, "2023-07-16", false},
+		{"序列号", DateColumn{Location: time.UTC, ExcelSerial: true}, "45123.5", false, "2023-07-16 12:00:00", false},
+		{"1904日期系统的序列号", DateColumn{Location: time.UTC, ExcelSerial: true, DateOnly: true}, "43661", true, "2023-07-16", false},
+		{"序列号按配置的时区解释", DateColumn{Location: shanghai, ExcelSerial: true}, "45123", false, "2023-07-16 00:00:00", false},
+		{"超出序列号范围的数字按日期解析", DateColumn{Location: time.UTC, ExcelSerial: true, DateOnly: true}, "20230716", false, "2023-07-16", false},
+		{"超出序列号范围时使用输入格式", DateColumn{Location: time.UTC, ExcelSerial: true, InputLayouts: []string{"20060102"}, DateOnly: true}, "20230716", false, "2023-07-16", false},
+		{"不支持序列号", DateColumn{Location: time.UTC, InputLayouts: []string{"2006-01-02"}}, "45123", false, "", true},
+		{"无法解析", DateColumn{Location: time.UTC, ExcelSerial: true}, "明天", false, "", true},
+		{"输出转换为配置的时区", DateColumn{Location: shanghai}, "2023-07-16T00:00:00Z", false, "2023-07-16 08:00:00", false},
+	}
+	for _, tt := range tests {
+		got, err := tt.column.format(tt.value, tt.date1904)
+		if (err != nil) != tt.wantErr || got != tt.want {
+			t.Errorf("%s: format(%q) = %q, %v, want %q, error %v", tt.name, tt.value, got, err, tt.want, tt.wantErr)
+		}
+	}
+}
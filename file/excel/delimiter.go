@@ -0,0 +1,67 @@
+package excelutil
+
+import (
+	"encoding/csv"
+	"path/filepath"
+	"strings"
+
+	"go_file/common"
+)
+
+// sniffLines 检测分隔符时最多使用的行数
+const sniffLines = 20
+
+// csvDelimiters 自动检测的分隔符, 得分相同时靠前的优先
+var csvDelimiters = []rune{',', ';', '\t', '|'}
+
+// defaultDelimiter 无法检测分隔符时使用的默认分隔符, 格式提示或扩展名为 .tsv 时为制表符
+func defaultDelimiter(fileName, fileType string) rune {
+	if fileType == common.FileTypeTsv || strings.ToLower(filepath.Ext(fileName)) == common.FileTypeTsv {
+		return '\t'
+	}
+	return ','
+}
+
+// sniffDelimiter 根据采样数据检测分隔符: 优先选择各行字段数最一致的, 一致程度相同时选择字段数多的;
+// 所有分隔符都只能分出一列时返回 fallback
+func sniffDelimiter(sample string, fallback rune) rune {
+	// 去掉可能不完整的最后一行
+	if i := strings.LastIndexByte(sample, '\n'); i > 0 {
+		sample = sample[:i]
+	}
+	var (
+		best            = fallback
+		bestConsistency float64
+		bestFields      int
+	)
+	for _, delimiter := range csvDelimiters {
+		reader := csv.NewReader(strings.NewReader(sample))
+		reader.Comma = delimiter
+		reader.FieldsPerRecord = -1
+		reader.LazyQuotes = true
+		counts := make(map[int]int)
+		lines := 0
+		for ; lines < sniffLines; lines++ {
+			record, err := reader.Read()
+			if err != nil {
+				break
+			}
+			counts[len(record)]++
+		}
+		// 出现次数最多的字段数
+		fields, count := 0, 0
+		for n, c := range counts {
+			if n > 1 && (c > count || c == count && n > fields) {
+				fields, count = n, c
+			}
+		}
+		if fields < 2 {
+			continue
+		}
+		consistency := float64(count) / float64(lines)
+		if consistency > bestConsistency || consistency == bestConsistency && fields > bestFields {
+			best, bestConsistency, bestFields = delimiter, consistency, fields
+		}
+	}
+	return best
+}
@@ -0,0 +1,50 @@
+package excelutil
+
+import (
+	"testing"
+
+	"go_file/common"
+)
+
+func TestSniffDelimiter(t *testing.T) {
+	tests := []struct {
+		name     string
+		sample   string
+		fallback rune
+		want     rune
+	}{
+		{"逗号", "a,b,c\n1,2,3\n4,5,6\n", ',', ','},
+		{"分号", "a;b;c\n1;2,5;3\n4;5;6\n", ',', ';'},
+		{"制表符", "a\tb\n1\t2\n", ',', '\t'},
+		{"竖线", "a|b|c\n1|2|3\n", ',', '|'},
+		{"引号中的分隔符", "\"a,b\";c\n\"1,2\";3\n\"4,5\";6\n", ',', ';'},
+		{"字段数一致的优先", "a,b;c;d\n1;2;3\n4;5;6\n7,8;9;0\n", ',', ';'},
+		{"只有一列时使用默认值", "a\nb\nc\n", '\t', '\t'},
+		{"忽略不完整的最后一行", "a;b\n1;2\n3,4,5,6,7", ',', ';'},
+	}
+	for _, tt := range tests {
+		if got := sniffDelimiter(tt.sample, tt.fallback); got != tt.want {
+			t.Errorf("%s: sniffDelimiter() = %q, want %q", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestDefaultDelimiter(t *testing.T) {
+	tests := []struct {
+		fileName string
+		fileType string
+		want     rune
+	}{
+		{"data.csv", "", ','},
+		{"data.tsv", "", '\t'},
+		{"DATA.TSV", "", '\t'},
+		{"data.txt", common.FileTypeTxt, ','},
+		{"upload", common.FileTypeTsv, '\t'},
+		{"upload", common.FileTypeCsv, ','},
+	}
+	for _, tt := range tests {
+		if got := defaultDelimiter(tt.fileName, tt.fileType); got != tt.want {
+			t.Errorf("defaultDelimiter(%q, %q) = %q, want %q", tt.fileName, tt.fileType, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,256 @@
+package excelutil
+
+import (
+	"archive/zip"
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"go_file/common"
+
+	"github.com/extrame/ole2"
+	"golang.org/x/text/transform"
+)
+
+// sniffSize 检测文件格式时读取的字节数
+const sniffSize = 8 * 1024
+
+// odsMimeType .ods 文件中 mimetype 文件的内容
+const odsMimeType = "application/vnd.oasis.opendocument.spreadsheet"
+
+// ole2SectorSize OLE2 复合文件头部和扇区的大小, extrame/ole2 只支持 512 字节的扇区
+const ole2SectorSize = 512
+
+// errCorruptOLE2 OLE2 复合文件(.xls 或加密的 .xlsx)被截断或已损坏
+var errCorruptOLE2 = errors.New("文件已损坏或不完整")
+
+var (
+	ole2Magic = []byte{0xD0, 0xCF, 0x11, 0xE0, 0xA1, 0xB1, 0x1A, 0xE1}
+	zipMagic  = []byte("PK\x03\x04")
+	utf8BOM   = []byte{0xEF, 0xBB, 0xBF}
+)
+
+// UnsupportedFormatError 文件内容不是支持的表格格式
+type UnsupportedFormatError struct {
+	FileName string // 文件名
+	Format   string // 检测到的格式, 如 .zip、.ods, 无法识别时为空
+}
+
+func (e *UnsupportedFormatError) Error() string {
+	msg := "不支持的文件格式"
+	if e.FileName != "" {
+		msg += ":" + e.FileName
+	}
+	if e.Format != "" {
+		msg += fmt.Sprintf("(%s)", e.Format)
+	}
+	return msg
+}
+
+// EmptyFileError 文件没有内容, 通常是上传失败或导出中断
+type EmptyFileError struct {
+	FileName string // 文件名, 从 io.Reader 读取时为空
+}
+
+func (e *EmptyFileError) Error() string {
+	if e.FileName == "" {
+		return "文件内容为空"
+	}
+	return "文件内容为空:" + e.FileName
+}
+
+// supportedFileTypes 可以读取的文件格式
+var supportedFileTypes = map[string]bool{
+	common.FileTypeXlsx: true,
+	common.FileTypeXls:  true,
+	common.FileTypeCsv:  true,
+	common.FileTypeHtml: true,
+	common.FileTypeOds:  true,
+}
+
+// DetectFileType 根据文件内容检测文件格式, 返回 common.FileTypeXlsx 等常量, 和扩展名无关;
+// 空文件返回 *EmptyFileError, 不是支持的格式时返回 *UnsupportedFormatError
+func DetectFileType(fileName string) (string, error) {
+	file, err := os.Open(fileName)
+	if err != nil {
+		return "", err
+	}
+	defer file.Close()
+	info, err := file.Stat()
+	if err != nil {
+		return "", err
+	}
+	if info.Size() == 0 {
+		return "", &EmptyFileError{FileName: fileName}
+	}
+	fileType, err := detectFileType(file, info.Size())
+	if err != nil {
+		return "", err
+	}
+	if !supportedFileTypes[fileType] {
+		return "", &UnsupportedFormatError{FileName: fileName, Format: fileType}
+	}
+	return fileType, nil
+}
+
+// detectFileType 根据文件头检测格式, zip 和 OLE2 文件需要读取目录区分具体格式;
+// 无法识别时返回空字符串
+func detectFileType(r io.ReaderAt, size int64) (string, error) {
+	head := make([]byte, min(size, sniffSize))
+	if _, err := r.ReadAt(head, 0); err != nil && err != io.EOF {
+		return "", err
+	}
+	switch {
+	case bytes.HasPrefix(head, ole2Magic):
+		return detectOLE2(r, size)
+	case bytes.HasPrefix(head, zipMagic):
+		return detectZip(r, size)
+	case isHTML(head):
+		return common.FileTypeHtml, nil
+	case isText(head):
+		return common.FileTypeCsv, nil
+	}
+	return "", nil
+}
+
+// detectOLE2 OLE2 复合文件中有 Workbook 的是 .xls, 有 EncryptedPackage 的是加密的 .xlsx;
+// extrame/ole2 不检查数据是否完整, 截断或损坏的文件可能 panic, 这时返回 errCorruptOLE2
+func detectOLE2(r io.ReaderAt, size int64) (fileType string, err error) {
+	header := make([]byte, ole2SectorSize)
+	if _, err = r.ReadAt(header, 0); err != nil {
+		return "", errCorruptOLE2
+	}
+	// 目录所在的扇区需要在文件中, 扇区从头部之后开始编号
+	if dirStart := int64(binary.LittleEndian.Uint32(header[48:])); (dirStart+2)*ole2SectorSize > size {
+		return "", errCorruptOLE2
+	}
+	defer func() {
+		if recover() != nil {
+			fileType, err = "", errCorruptOLE2
+		}
+	}()
+	ole, err := ole2.Open(io.NewSectionReader(r, 0, size), "utf-8")
+	if err != nil {
+		return "", err
+	}
+	dir, err := ole.ListDir()
+	if err != nil {
+		return "", err
+	}
+	for _, f := range dir {
+		switch f.Name() {
+		case "Workbook", "Book":
+			return common.FileTypeXls, nil
+		case "EncryptedPackage":
+			return common.FileTypeXlsx, nil
+		}
+	}
+	return "", nil
+}
+
+// detectZip 根据 zip 中的文件区分 .xlsx 和 .ods, 其他 zip 文件返回 common.FileTypeZip
+func detectZip(r io.ReaderAt, size int64) (string, error) {
+	zr, err := zip.NewReader(r, size)
+	if err != nil {
+		return "", err
+	}
+	for _, f := range zr.File {
+		switch f.Name {
+		case "xl/workbook.xml":
+			return common.FileTypeXlsx, nil
+		case "mimetype":
+			rc, err := f.Open()
+			if err != nil {
+				return "", err
+			}
+			mimeType, err := io.ReadAll(io.LimitReader(rc, int64(len(odsMimeType))))
+			rc.Close()
+			if err != nil {
+				return "", err
+			}
+			if string(mimeType) == odsMimeType {
+				return common.FileTypeOds, nil
+			}
+		}
+	}
+	return common.FileTypeZip, nil
+}
+
+// isHTML 判断是否为 html 表格, 跳过 BOM 和空白后以 html 标签开头
+func isHTML(head []byte) bool {
+	head = bytes.ToLower(bytes.TrimSpace(bytes.TrimPrefix(head, utf8BOM)))
+	for _, prefix := range []string{"<!doctype html", "<html", "<table", "<meta", "<head", "<body"} {
+		if bytes.HasPrefix(head, []byte(prefix)) {
+			return true
+		}
+	}
+	// 带 xml 声明的 xhtml
+	return bytes.HasPrefix(head, []byte("<?xml")) && bytes.Contains(head, []byte("<html"))
+}
+
+// isText 判断是否为文本文件, 二进制文件中几乎都会有除制表符和换行外的控制字符;
+// UTF-16 文本先解码再判断
+func isText(head []byte) bool {
+	if len(head) == 0 {
+		return false
+	}
+	text := string(head)
+	if bytes.HasPrefix(head, utf16LEBOM) || bytes.HasPrefix(head, utf16BEBOM) || utf16Charset(head) != "" {
+		enc, bom, err := textEncoding(head, "")
+		if err != nil {
+			return false
+		}
+		decoded, _, _ := transform.Bytes(enc.NewDecoder(), head[bom:])
+		text = string(decoded)
+	}
+	for _, c := range text {
+		if c < 0x20 && c != '\t' && c != '\n' && c != '\r' && c != '\f' {
+			return false
+		}
+	}
+	return true
+}
+
+// toReaderAt 把 io.Reader 转换为 io.ReaderAt, 本身支持随机读取的(如 *os.File、multipart.File)直接使用,
+// 否则读取全部数据到内存; 没有数据时返回 *EmptyFileError
+func toReaderAt(r io.Reader) (io.ReaderAt, int64, error) {
+	if ra, ok := r.(interface {
+		io.ReaderAt
+		io.Seeker
+	}); ok {
+		size, err := ra.Seek(0, io.SeekEnd)
+		if err != nil {
+			return nil, 0, err
+		}
+		if _, err = ra.Seek(0, io.SeekStart); err != nil {
+			return nil, 0, err
+		}
+		if size == 0 {
+			return nil, 0, &EmptyFileError{}
+		}
+		return ra, size, nil
+	}
+	data, err := io.ReadAll(r)
+	if err != nil {
+		return nil, 0, err
+	}
+	if len(data) == 0 {
+		return nil, 0, &EmptyFileError{}
+	}
+	return bytes.NewReader(data), int64(len(data)), nil
+}
+
+// fileTypeOrExt 检测文件格式, 文件无法读取时按扩展名判断
+func fileTypeOrExt(fileName string) string {
+	if fileType, err := DetectFileType(fileName); err == nil {
+		return fileType
+	} else if _, ok := err.(*UnsupportedFormatError); ok {
+		return ""
+	}
+	return strings.ToLower(filepath.Ext(fileName))
+}
@@ -0,0 +1,257 @@
+package excelutil
+
+import (
+	"archive/zip"
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"io"
+	"os"
+	"reflect"
+	"strings"
+	"testing"
+	"unicode/utf16"
+
+	"go_file/common"
+
+	"github.com/xuri/excelize/v2"
+)
+
+// newOLE2 生成只包含一个空数据流的 OLE2 复合文件
+func newOLE2(stream string) []byte {
+	return newOLE2File(stream, nil)
+}
+
+// newOLE2File 生成只包含一个数据流的 OLE2 复合文件: 头部、一个 FAT 扇区、一个目录扇区和数据流的扇区;
+// 有数据时小数据流阈值设置为 0, 数据流保存在普通扇区中, 不需要生成小扇区
+func newOLE2File(stream string, content []byte) []byte {
+	const (
+		endOfChain = 0xFFFFFFFE
+		freeSect   = 0xFFFFFFFF
+		fatSect    = 0xFFFFFFFD
+	)
+	sectors := (len(content) + 511) / 512 // 一个 FAT 扇区最多记录 126 个数据扇区
+	data := make([]byte, 512*(3+sectors))
+	copy(data[512*3:], content)
+	le := binary.LittleEndian
+	copy(data, ole2Magic)
+	le.PutUint16(data[24:], 0x3E)
+	le.PutUint16(data[26:], 3)
+	le.PutUint16(data[28:], 0xFFFE)
+	le.PutUint16(data[30:], 9)
+	le.PutUint16(data[32:], 6)
+	le.PutUint32(data[44:], 1) // FAT 扇区数
+	le.PutUint32(data[48:], 1) // 目录起始扇区
+	if len(content) == 0 {
+		le.PutUint32(data[56:], 4096)
+	}
+	le.PutUint32(data[60:], endOfChain)
+	le.PutUint32(data[68:], endOfChain)
+	for i := 0; i < 109; i++ {
+		le.PutUint32(data[76+i*4:], freeSect)
+	}
+	le.PutUint32(data[76:], 0)
+	fat := data[512:1024]
+	for i := 0; i < 128; i++ {
+		le.PutUint32(fat[i*4:], freeSect)
+	}
+	le.PutUint32(fat[0:], fatSect)
+	le.PutUint32(fat[4:], endOfChain)
+	for i := 2; i < 2+sectors; i++ {
+		le.PutUint32(fat[i*4:], uint32(i+1))
+	}
+	if sectors > 0 {
+		le.PutUint32(fat[(1+sectors)*4:], endOfChain)
+	}
+	entry := func(i int, name string, kind byte, child, start uint32, size int) {
+		e := data[1024+i*128 : 1024+(i+1)*128]
+		units := utf16.Encode([]rune(name))
+		for j, u := range units {
+			le.PutUint16(e[j*2:], u)
+		}
+		le.PutUint16(e[64:], uint16(len(units)+1)*2)
+		e[66], e[67] = kind, 1
+		le.PutUint32(e[68:], freeSect)
+		le.PutUint32(e[72:], freeSect)
+		le.PutUint32(e[76:], child)
+		le.PutUint32(e[116:], start)
+		le.PutUint32(e[120:], uint32(size))
+	}
+	entry(0, "Root Entry", 5, 1, endOfChain, 0)
+	if sectors > 0 {
+		entry(1, stream, 2, freeSect, 2, len(content))
+	} else {
+		entry(1, stream, 2, freeSect, endOfChain, 0)
+	}
+	return data
+}
+
+// newZip 生成包含指定文件的 zip, files 为文件名和内容, 按顺序写入
+func newZip(t *testing.T, files ...[2]string) []byte {
+	t.Helper()
+	var buf bytes.Buffer
+	zw := zip.NewWriter(&buf)
+	for _, file := range files {
+		w, err := zw.Create(file[0])
+		if err != nil {
+			t.Fatal(err)
+		}
+		if _, err = w.Write([]byte(file[1])); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := zw.Close(); err != nil {
+		t.Fatal(err)
+	}
+	return buf.Bytes()
+}
+
+func TestDetectFileType(t *testing.T) {
+	f := excelize.NewFile()
+	xlsx, err := f.WriteToBuffer()
+	if err != nil {
+		t.Fatal(err)
+	}
+	encrypted, err := os.ReadFile(writeEncryptedXLSX(t, "123456"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	utf16LE := []byte{0xFF, 0xFE}
+	for _, u := range utf16.Encode([]rune("编码\t名称\r\n")) {
+		utf16LE = append(utf16LE, byte(u), byte(u>>8))
+	}
+	tests := []struct {
+		name string
+		data []byte
+		want string
+	}{
+		{"xlsx", xlsx.Bytes(), common.FileTypeXlsx},
+		{"加密的 xlsx", encrypted, common.FileTypeXlsx},
+		{"xls", newOLE2("Workbook"), common.FileTypeXls},
+		{"BIFF5 xls", newOLE2("Book"), common.FileTypeXls},
+		{"其他 OLE2 文件", newOLE2("WordDocument"), ""},
+		{"ods", newZip(t, [2]string{"mimetype", odsMimeType}, [2]string{"content.xml", "<x/>"}), common.FileTypeOds},
+		{"其他 zip 文件", newZip(t, [2]string{"a.txt", "a"}), common.FileTypeZip},
+		{"html", []byte("\xEF\xBB\xBF  <html><body><table></table></body></html>"), common.FileTypeHtml},
+		{"html 片段", []byte("<TABLE><tr><td>1</td></tr></TABLE>"), common.FileTypeHtml},
+		{"xhtml", []byte(`<?xml version="1.0"?><html xmlns="http://www.w3.org/1999/xhtml"></html>`), common.FileTypeHtml},
+		{"csv", []byte("编码,名称\n001,螺丝\n"), common.FileTypeCsv},
+		{"UTF-16 文本", utf16LE, common.FileTypeCsv},
+		{"二进制", []byte{0x00, 0x01, 0x02, 0x03, 0x89, 'P', 'N', 'G'}, ""},
+	}
+	for _, tt := range tests {
+		got, err := detectFileType(bytes.NewReader(tt.data), int64(len(tt.data)))
+		if err != nil || got != tt.want {
+			t.Errorf("%s: detectFileType() = %q, %v, want %q", tt.name, got, err, tt.want)
+		}
+	}
+}
+
+func TestDetectFileTypeErrors(t *testing.T) {
+	// 扩展名为 .xls 的 zip 文件按内容判断为不支持的格式
+	fileName := writeTestFile(t, "data.xls", string(newZip(t, [2]string{"a.txt", "a"})))
+	var unsupported *UnsupportedFormatError
+	if _, err := DetectFileType(fileName); !errors.As(err, &unsupported) || unsupported.Format != common.FileTypeZip {
+		t.Errorf("DetectFileType() error = %v, want *UnsupportedFormatError", err)
+	}
+	if _, err := ReadExcelFile(fileName, nil, nil); !errors.As(err, &unsupported) {
+		t.Errorf("ReadExcelFile() error = %v, want *UnsupportedFormatError", err)
+	}
+
+	empty := writeTestFile(t, "empty.csv", "")
+	var emptyErr *EmptyFileError
+	if _, err := DetectFileType(empty); !errors.As(err, &emptyErr) || emptyErr.FileName != empty {
+		t.Errorf("DetectFileType() error = %v, want *EmptyFileError", err)
+	}
+	if _, err := ReadExcelFile(empty, nil, nil); !errors.As(err, &emptyErr) {
+		t.Errorf("ReadExcelFile() error = %v, want *EmptyFileError", err)
+	}
+	if _, err := OpenExFile(empty); !errors.As(err, &emptyErr) {
+		t.Errorf("OpenExFile() error = %v, want *EmptyFileError", err)
+	}
+	if _, err := Preview(empty, 10); !errors.As(err, &emptyErr) {
+		t.Errorf("Preview() error = %v, want *EmptyFileError", err)
+	}
+}
+
+// 截断或损坏的 OLE2 文件返回错误, 不会 panic
+func TestDetectCorruptOLE2(t *testing.T) {
+	full := newOLE2("Workbook")
+	badName := append([]byte(nil), full...)
+	binary.LittleEndian.PutUint16(badName[512*2+128+64:], 0) // 目录项名称长度为 0
+	tests := []struct {
+		name string
+		data []byte
+	}{
+		{"只有文件头标识", full[:len(ole2Magic)]},
+		{"头部不完整", full[:100]},
+		{"缺少目录扇区", full[:512*2+100]},
+		{"目录项损坏", badName},
+	}
+	for _, tt := range tests {
+		if got, err := detectFileType(bytes.NewReader(tt.data), int64(len(tt.data))); !errors.Is(err, errCorruptOLE2) {
+			t.Errorf("%s: detectFileType() = %q, %v, want errCorruptOLE2", tt.name, got, err)
+		}
+	}
+	fileName := writeTestFile(t, "data.xls", string(full[:512*2+100]))
+	if _, err := ReadExcelFile(fileName, nil, nil); !errors.Is(err, errCorruptOLE2) {
+		t.Errorf("ReadExcelFile() error = %v, want errCorruptOLE2", err)
+	}
+}
+
+func TestReadExcelFromReader(t *testing.T) {
+	data, err := os.ReadFile(writeTestXLSX(t, "a.xlsx", testSheet{name: "物料", rows: [][]string{{"编码", "名称"}, {"001", "螺丝"}}}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	dstTitleMap := map[string]string{"编码": "code", "名称": "name"}
+	want := [][]string{{"001", "螺丝"}}
+	tests := []struct {
+		name     string
+		reader   io.Reader
+		fileType string
+	}{
+		{"xlsx 按内容检测", bytes.NewReader(data), ""},
+		{"不支持随机读取的 io.Reader", io.MultiReader(bytes.NewReader(data)), ""},
+		{"csv 格式提示", strings.NewReader("编码,名称\n001,螺丝\n"), common.FileTypeCsv},
+		{"tsv 格式提示", strings.NewReader("编码\t名称\n001\t螺丝\n"), common.FileTypeTsv},
+	}
+	for _, tt := range tests {
+		file, err := ReadExcelFromReader(tt.reader, tt.fileType, nil, dstTitleMap)
+		if err != nil {
+			t.Errorf("%s: ReadExcelFromReader() error = %v", tt.name, err)
+			continue
+		}
+		if file.FileName != "" || !reflect.DeepEqual(file.Sheets[0].Rows, want) {
+			t.Errorf("%s: FileName = %q, rows = %q, want %q", tt.name, file.FileName, file.Sheets[0].Rows, want)
+		}
+	}
+	file, err := ReadExcelFromBytes(data, "", nil, dstTitleMap)
+	if err != nil || !reflect.DeepEqual(file.Sheets[0].Rows, want) {
+		t.Errorf("ReadExcelFromBytes() = %v, %v", file, err)
+	}
+
+	var emptyErr *EmptyFileError
+	for _, r := range []io.Reader{bytes.NewReader(nil), io.MultiReader()} {
+		if _, err = ReadExcelFromReader(r, common.FileTypeCsv, nil, dstTitleMap); !errors.As(err, &emptyErr) {
+			t.Errorf("ReadExcelFromReader() empty error = %v, want *EmptyFileError", err)
+		}
+	}
+	if _, err = OpenExReader(strings.NewReader(""), ""); !errors.As(err, &emptyErr) {
+		t.Errorf("OpenExReader() empty error = %v, want *EmptyFileError", err)
+	}
+	var unsupported *UnsupportedFormatError
+	if _, err = ReadExcelFromBytes([]byte{0x00, 0x01, 0x02}, "", nil, dstTitleMap); !errors.As(err, &unsupported) {
+		t.Errorf("ReadExcelFromBytes() binary error = %v, want *UnsupportedFormatError", err)
+	}
+}
+
+func TestOpenExReader(t *testing.T) {
+	file, err := OpenExReader(strings.NewReader("编码;名称\n001;螺丝\n"), "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := [][]string{{"编码", "名称"}, {"001", "螺丝"}}; !reflect.DeepEqual(file.Sheets[0].Rows, want) {
+		t.Errorf("rows = %q, want %q", file.Sheets[0].Rows, want)
+	}
+}
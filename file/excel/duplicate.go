@@ -0,0 +1,255 @@
+package excelutil
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+
+	"github.com/xuri/excelize/v2"
+)
+
+// DuplicatePolicy 主键重复的数据的处理方式
+type DuplicatePolicy int
+
+const (
+	DuplicateError     DuplicatePolicy = iota // 保留全部行, 重复的行记录到 Violations, 默认
+	DuplicateKeepFirst                        // 只保留第一次出现的行
+	DuplicateKeepLast                         // 只保留最后一次出现的行
+	DuplicateMerge                            // 合并到第一次出现的行, 其中的空单元格使用后面的行的值
+)
+
+// duplicatePolicyNames 导入配置中的处理方式名称
+var duplicatePolicyNames = map[string]DuplicatePolicy{
+	"":          DuplicateError,
+	"error":     DuplicateError,
+	"keepFirst": DuplicateKeepFirst,
+	"keepLast":  DuplicateKeepLast,
+	"merge":     DuplicateMerge,
+}
+
+// DuplicateKey 重复的主键
+type DuplicateKey struct {
+	Key  []string  // 主键的值, 和 WithUniqueKey 设置的表头对应
+	Rows []*RowRef // 出现的位置, 按读取的顺序排列
+}
+
+// RowRef 数据行的位置
+type RowRef struct {
+	FileName  string // 文件名, 从 io.Reader 读取时为空
+	SheetName string // 表单名称
+	Row       int    // 行号, 从 1 开始
+}
+
+func (r *RowRef) String() string {
+	if r.FileName == "" {
+		return fmt.Sprintf("表单[%s]第%d行", r.SheetName, r.Row)
+	}
+	return fmt.Sprintf("文件%s表单[%s]第%d行", r.FileName, r.SheetName, r.Row)
+}
+
+// sheetKeys 表单中主键列的位置, 用于检查重复
+type sheetKeys struct {
+	cols    []int    // 主键列在 ExcelSheet.Header 中的下标, 数据全为空被去掉的列为 -1
+	srcCols []int    // 主键列在原始数据中的下标
+	titles  []string // 主键列在表单中的表头
+	rowNums []int    // ExcelSheet.Rows 每一行的行号
+}
+
+// bindSheetKeys 根据表头找到主键列, keep 为去掉空列后保留的映射列下标
+func (r *RowReader) bindSheetKeys(keep []int) (*sheetKeys, error) {
+	keys := &sheetKeys{}
+	for _, title := range r.options.uniqueKey {
+		canonical := r.matcher.canonical(title)
+		col, srcCol := -1, -1
+		for i, idx := range r.colIndexes {
+			if r.matcher.canonical(r.srcHeader[idx]) == canonical {
+				srcCol = idx
+				for j, k := range keep {
+					if k == i {
+						col = j
+					}
+				}
+				break
+			}
+		}
+		if srcCol < 0 {
+			return nil, fmt.Errorf("表单[%s]没有主键列%s或主键列没有映射", r.SheetName(), title)
+		}
+		keys.cols = append(keys.cols, col)
+		keys.srcCols = append(keys.srcCols, srcCol)
+		keys.titles = append(keys.titles, r.srcHeader[srcCol])
+	}
+	return keys, nil
+}
+
+// key 返回一行数据的主键, 有主键列为空时返回 nil, 不参与检查
+func (k *sheetKeys) key(row []string) []string {
+	key := make([]string, len(k.cols))
+	for j, col := range k.cols {
+		if col >= 0 && col < len(row) {
+			key[j] = strings.TrimSpace(row[col])
+		}
+		if key[j] == "" {
+			return nil
+		}
+	}
+	return key
+}
+
+// keyedRow 主键检查中的一行数据
+type keyedRow struct {
+	file  *ExcelFile
+	sheet *ExcelSheet
+	index int // 在 ExcelSheet.Rows 中的下标
+	ref   *RowRef
+}
+
+// checkDuplicates 按主键检查多个文件中的重复数据, 按 policy 处理并记录到每个文件的导入报告中
+func checkDuplicates(files []*ExcelFile, policy DuplicatePolicy) {
+	groups := make(map[string][]*keyedRow)
+	var order []string
+	keys := make(map[string][]string)
+	for _, file := range files {
+		for _, sheet := range file.Sheets {
+			if sheet.keys == nil {
+				continue
+			}
+			for i, row := range sheet.Rows {
+				key := sheet.keys.key(row)
+				if key == nil {
+					continue
+				}
+				joined := strings.Join(key, "\x00")
+				if _, ok := groups[joined]; !ok {
+					order = append(order, joined)
+					keys[joined] = key
+				}
+				groups[joined] = append(groups[joined], &keyedRow{
+					file:  file,
+					sheet: sheet,
+					index: i,
+					ref:   &RowRef{FileName: file.FileName, SheetName: sheet.SheetName, Row: sheet.keys.rowNums[i]},
+				})
+			}
+		}
+	}
+	removed := make(map[*ExcelSheet]map[int]bool)
+	remove := func(row *keyedRow) {
+		if removed[row.sheet] == nil {
+			removed[row.sheet] = make(map[int]bool)
+		}
+		removed[row.sheet][row.index] = true
+	}
+	for _, joined := range order {
+		rows := groups[joined]
+		if len(rows) < 2 {
+			continue
+		}
+		duplicate := &DuplicateKey{Key: keys[joined]}
+		reported := make(map[*ExcelFile]bool)
+		for _, row := range rows {
+			duplicate.Rows = append(duplicate.Rows, row.ref)
+			if !reported[row.file] && row.file.Report != nil {
+				row.file.Report.Duplicates = append(row.file.Report.Duplicates, duplicate)
+				reported[row.file] = true
+			}
+		}
+		switch policy {
+		case DuplicateError:
+			for _, row := range rows[1:] {
+				row.file.Violations = append(row.file.Violations, duplicateViolation(row, rows[0].ref, duplicate.Key))
+			}
+		case DuplicateKeepFirst:
+			for _, row := range rows[1:] {
+				remove(row)
+			}
+		case DuplicateKeepLast:
+			for _, row := range rows[:len(rows)-1] {
+				remove(row)
+			}
+		case DuplicateMerge:
+			for _, row := range rows[1:] {
+				mergeRow(rows[0], row)
+				remove(row)
+			}
+		}
+	}
+	for _, file := range files {
+		removeRows(file, removed)
+	}
+}
+
+// duplicateViolation 创建重复行的校验错误, 位置为第一个主键列
+func duplicateViolation(row *keyedRow, first *RowRef, key []string) *Violation {
+	keys := row.sheet.keys
+	column, _ := excelize.ColumnNumberToName(keys.srcCols[0] + 1)
+	position := first.String()
+	if first.FileName == row.ref.FileName {
+		position = (&RowRef{SheetName: first.SheetName, Row: first.Row}).String()
+	}
+	return &Violation{
+		Sheet:   row.sheet.SheetName,
+		Row:     row.ref.Row,
+		Column:  column,
+		Title:   strings.Join(keys.titles, "/"),
+		Rule:    RuleUnique,
+		Value:   strings.Join(key, "/"),
+		Message: fmt.Sprintf("和%s重复", position),
+	}
+}
+
+// mergeRow 用 src 中的值填充 dst 中的空单元格, 两行的表头可能不同, 按表头对应
+func mergeRow(dst, src *keyedRow) {
+	dstRow := dst.sheet.Rows[dst.index]
+	srcRow := src.sheet.Rows[src.index]
+	for i, title := range dst.sheet.Header {
+		if i >= len(dstRow) || dstRow[i] != "" {
+			continue
+		}
+		j := slices.Index(src.sheet.Header, title)
+		if j < 0 || j >= len(srcRow) || srcRow[j] == "" {
+			continue
+		}
+		dstRow[i] = srcRow[j]
+		if dst.index < len(dst.sheet.Cells) && src.index < len(src.sheet.Cells) && j < len(src.sheet.Cells[src.index]) {
+			dst.sheet.Cells[dst.index][i] = src.sheet.Cells[src.index][j]
+		}
+	}
+}
+
+// removeRows 去掉文件中被标记的行, 并更新行数和导入报告
+func removeRows(file *ExcelFile, removed map[*ExcelSheet]map[int]bool) {
+	total := 0
+	for i, sheet := range file.Sheets {
+		if marks := removed[sheet]; len(marks) > 0 {
+			rows := sheet.Rows[:0]
+			var cells [][]Cell
+			var rowNums, dropped []int
+			for j, row := range sheet.Rows {
+				if marks[j] {
+					dropped = append(dropped, sheet.keys.rowNums[j])
+					continue
+				}
+				rows = append(rows, row)
+				rowNums = append(rowNums, sheet.keys.rowNums[j])
+				if j < len(sheet.Cells) {
+					cells = append(cells, sheet.Cells[j])
+				}
+			}
+			sheet.Rows, sheet.keys.rowNums = rows, rowNums
+			if sheet.Cells != nil {
+				sheet.Cells = cells
+				sheet.ColumnTypes = inferColumnTypes(cells, len(sheet.Header))
+			}
+			if file.Report != nil && i < len(file.Report.Sheets) {
+				file.Report.Sheets[i].DuplicateRows = dropped
+				file.Report.Sheets[i].RowCount = len(rows)
+			}
+		}
+		total += len(sheet.Rows)
+	}
+	file.TotalRow = total
+	if file.Report != nil {
+		file.Report.TotalRow = total
+	}
+}
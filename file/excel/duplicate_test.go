@@ -0,0 +1,255 @@
+package excelutil
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"github.com/xuri/excelize/v2"
+)
+
+// testSheet 测试用的表单数据
+type testSheet struct {
+	name string
+	rows [][]string
+}
+
+// writeTestXLSX 在临时目录中生成 .xlsx 文件, 返回文件路径
+func writeTestXLSX(t *testing.T, name string, sheets ...testSheet) string {
+	t.Helper()
+	f := excelize.NewFile()
+	defer f.Close()
+	for i, sheet := range sheets {
+		if i == 0 {
+			if err := f.SetSheetName("Sheet1", sheet.name); err != nil {
+				t.Fatal(err)
+			}
+		} else if _, err := f.NewSheet(sheet.name); err != nil {
+			t.Fatal(err)
+		}
+		for j, row := range sheet.rows {
+			cell, _ := excelize.CoordinatesToCellName(1, j+1)
+			values := make([]any, len(row))
+			for k, v := range row {
+				values[k] = v
+			}
+			if err := f.SetSheetRow(sheet.name, cell, &values); err != nil {
+				t.Fatal(err)
+			}
+		}
+	}
+	path := filepath.Join(t.TempDir(), name)
+	if err := f.SaveAs(path); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+// writeTestFile 在临时目录中生成文件, 返回文件路径
+func writeTestFile(t *testing.T, name, content string) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), name)
+	if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+// sheetRows 返回文件中每个表单的数据
+func sheetRows(file *ExcelFile) map[string][][]string {
+	rows := make(map[string][][]string)
+	for _, sheet := range file.Sheets {
+		rows[sheet.SheetName] = sheet.Rows
+	}
+	return rows
+}
+
+// duplicateRefs 把重复主键的位置转换为字符串, 便于比较
+func duplicateRefs(duplicates []*DuplicateKey) [][]string {
+	var refs [][]string
+	for _, duplicate := range duplicates {
+		var ref []string
+		for _, row := range duplicate.Rows {
+			ref = append(ref, row.String())
+		}
+		refs = append(refs, ref)
+	}
+	return refs
+}
+
+var duplicateTitleMap = map[string]string{"编码": "code", "仓库": "warehouse", "名称": "name", "数量": "qty"}
+
+func TestDuplicatePolicy(t *testing.T) {
+	fileName := writeTestXLSX(t, "stock.xlsx", testSheet{name: "库存", rows: [][]string{
+		{"编码", "仓库", "名称", "数量"},
+		{"001", "北京", "螺丝", ""},
+		{"002", "北京", "螺帽", "5"},
+		{"001", "北京", "", "10"},
+		{"001", "上海", "螺丝", "7"},
+		{"001", "北京", "垫片", "20"},
+	}})
+	prefix := "文件" + fileName
+	refs := [][]string{{prefix + "表单[库存]第2行", prefix + "表单[库存]第4行", prefix + "表单[库存]第6行"}}
+	tests := []struct {
+		name       string
+		policy     DuplicatePolicy
+		rows       [][]string
+		violations []string // 校验错误的位置和信息
+		dropped    []int
+	}{
+		{
+			name:   "error",
+			policy: DuplicateError,
+			rows: [][]string{
+				{"001", "北京", "螺丝", ""}, {"002", "北京", "螺帽", "5"}, {"001", "北京", "", "10"},
+				{"001", "上海", "螺丝", "7"}, {"001", "北京", "垫片", "20"},
+			},
+			violations: []string{"4 A 和表单[库存]第2行重复", "6 A 和表单[库存]第2行重复"},
+		},
+		{
+			name:   "keepFirst",
+			policy: DuplicateKeepFirst,
+			rows: [][]string{
+				{"001", "北京", "螺丝", ""}, {"002", "北京", "螺帽", "5"}, {"001", "上海", "螺丝", "7"},
+			},
+			dropped: []int{4, 6},
+		},
+		{
+			name:   "keepLast",
+			policy: DuplicateKeepLast,
+			rows: [][]string{
+				{"002", "北京", "螺帽", "5"}, {"001", "上海", "螺丝", "7"}, {"001", "北京", "垫片", "20"},
+			},
+			dropped: []int{2, 4},
+		},
+		{
+			name:   "merge",
+			policy: DuplicateMerge,
+			rows: [][]string{
+				{"001", "北京", "螺丝", "10"}, {"002", "北京", "螺帽", "5"}, {"001", "上海", "螺丝", "7"},
+			},
+			dropped: []int{4, 6},
+		},
+	}
+	for _, tt := range tests {
+		file, err := ReadExcelFile(fileName, nil, duplicateTitleMap,
+			WithUniqueKey("编码", "仓库"), WithDuplicatePolicy(tt.policy))
+		if err != nil {
+			t.Fatalf("%s: ReadExcelFile() error = %v", tt.name, err)
+		}
+		if got := file.Sheets[0].Rows; !reflect.DeepEqual(got, tt.rows) {
+			t.Errorf("%s: rows = %q, want %q", tt.name, got, tt.rows)
+		}
+		var violations []string
+		for _, v := range file.Violations {
+			if v.Rule != RuleUnique || v.Title != "编码/仓库" || v.Value != "001/北京" {
+				t.Errorf("%s: violation = %+v", tt.name, v)
+			}
+			violations = append(violations, fmt.Sprintf("%d %s %s", v.Row, v.Column, v.Message))
+		}
+		if !reflect.DeepEqual(violations, tt.violations) {
+			t.Errorf("%s: violations = %q, want %q", tt.name, violations, tt.violations)
+		}
+		if got := duplicateRefs(file.Report.Duplicates); !reflect.DeepEqual(got, refs) {
+			t.Errorf("%s: Report.Duplicates = %q, want %q", tt.name, got, refs)
+		}
+		if got := file.Report.Sheets[0].DuplicateRows; !reflect.DeepEqual(got, tt.dropped) {
+			t.Errorf("%s: DuplicateRows = %v, want %v", tt.name, got, tt.dropped)
+		}
+		if file.TotalRow != len(tt.rows) || file.Report.TotalRow != len(tt.rows) {
+			t.Errorf("%s: TotalRow = %d, %d, want %d", tt.name, file.TotalRow, file.Report.TotalRow, len(tt.rows))
+		}
+	}
+}
+
+// 不同表单的表头顺序不同, 合并时按表头对应
+func TestDuplicateAcrossSheets(t *testing.T) {
+	fileName := writeTestXLSX(t, "stock.xlsx",
+		testSheet{name: "一月", rows: [][]string{{"编码", "名称", "数量"}, {"001", "螺丝", ""}, {"002", "螺帽", "5"}}},
+		testSheet{name: "二月", rows: [][]string{{"数量", "编码", "名称"}, {"10", "001", "垫片"}, {"3", "", "空主键"}}},
+	)
+	tests := []struct {
+		name       string
+		policy     DuplicatePolicy
+		rows       map[string][][]string
+		violations []string
+	}{
+		{
+			name:   "error",
+			policy: DuplicateError,
+			rows: map[string][][]string{
+				"一月": {{"001", "螺丝", ""}, {"002", "螺帽", "5"}},
+				"二月": {{"10", "001", "垫片"}, {"3", "", "空主键"}},
+			},
+			violations: []string{"二月 2 B 和表单[一月]第2行重复"},
+		},
+		{
+			name:   "merge",
+			policy: DuplicateMerge,
+			rows: map[string][][]string{
+				"一月": {{"001", "螺丝", "10"}, {"002", "螺帽", "5"}},
+				"二月": {{"3", "", "空主键"}},
+			},
+		},
+	}
+	for _, tt := range tests {
+		file, err := ReadExcelFile(fileName, nil, duplicateTitleMap, WithUniqueKey("编码"), WithDuplicatePolicy(tt.policy))
+		if err != nil {
+			t.Fatalf("%s: ReadExcelFile() error = %v", tt.name, err)
+		}
+		if got := sheetRows(file); !reflect.DeepEqual(got, tt.rows) {
+			t.Errorf("%s: rows = %q, want %q", tt.name, got, tt.rows)
+		}
+		var violations []string
+		for _, v := range file.Violations {
+			violations = append(violations, fmt.Sprintf("%s %d %s %s", v.Sheet, v.Row, v.Column, v.Message))
+		}
+		if !reflect.DeepEqual(violations, tt.violations) {
+			t.Errorf("%s: violations = %q, want %q", tt.name, violations, tt.violations)
+		}
+		want := [][]string{{"文件" + fileName + "表单[一月]第2行", "文件" + fileName + "表单[二月]第2行"}}
+		if got := duplicateRefs(file.Report.Duplicates); !reflect.DeepEqual(got, want) {
+			t.Errorf("%s: Report.Duplicates = %q, want %q", tt.name, got, want)
+		}
+	}
+}
+
+// 批量读取时在全部文件中检查, 校验错误记录在重复行所在的文件中
+func TestReadExcelFilesDuplicates(t *testing.T) {
+	first := writeTestFile(t, "a.csv", "编码,名称\n001,螺丝\n002,螺帽\n")
+	second := writeTestFile(t, "b.csv", "编码,名称\n003,垫片\n001,螺丝\n")
+	third := writeTestFile(t, "c.csv", "编码,名称\n004,弹簧\n")
+	files, err := ReadExcelFiles([]string{first, second, third}, nil, duplicateTitleMap, WithUniqueKey("编码"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := [][]string{{"文件" + first + "表单[csv]第2行", "文件" + second + "表单[csv]第3行"}}
+	for i, wantDuplicates := range [][][]string{want, want, nil} {
+		if got := duplicateRefs(files[i].Report.Duplicates); !reflect.DeepEqual(got, wantDuplicates) {
+			t.Errorf("files[%d].Report.Duplicates = %q, want %q", i, got, wantDuplicates)
+		}
+	}
+	if len(files[0].Violations) != 0 || len(files[2].Violations) != 0 {
+		t.Errorf("violations = %v, %v, want none", files[0].Violations, files[2].Violations)
+	}
+	if len(files[1].Violations) != 1 {
+		t.Fatalf("files[1].Violations = %v, want 1", files[1].Violations)
+	}
+	if v := files[1].Violations[0]; v.Row != 3 || v.Message != "和文件"+first+"表单[csv]第2行重复" {
+		t.Errorf("files[1].Violations[0] = %+v", v)
+	}
+
+	files, err = ReadExcelFiles([]string{first, second}, nil, duplicateTitleMap,
+		WithUniqueKey("编码"), WithDuplicatePolicy(DuplicateKeepLast))
+	if err != nil {
+		t.Fatal(err)
+	}
+	wantRows := [][][]string{{{"002", "螺帽"}}, {{"003", "垫片"}, {"001", "螺丝"}}}
+	for i, file := range files {
+		if !reflect.DeepEqual(file.Sheets[0].Rows, wantRows[i]) || file.TotalRow != len(wantRows[i]) {
+			t.Errorf("files[%d] rows = %q, TotalRow = %d, want %q", i, file.Sheets[0].Rows, file.TotalRow, wantRows[i])
+		}
+	}
+}
@@ -1,38 +1,72 @@
 package excelutil
 
 import (
-	"encoding/csv"
 	"errors"
 	"fmt"
 	"io"
-	"log"
 	"net/http"
 	"os"
 	"strconv"
 	"strings"
 	"time"
 
-	"github.com/extrame/xls"
-	"github.com/gogs/chardet"
+	"go_file/common"
+
 	"github.com/xuri/excelize/v2"
-	"github.com/zeromicro/go-zero/core/logx"
-	"golang.org/x/text/encoding"
-	"golang.org/x/text/encoding/charmap"
-	"golang.org/x/text/encoding/simplifiedchinese"
-	"golang.org/x/text/transform"
 )
 
-func OpenExFile(fileName string) (*ExcelFile, error) {
+// OpenExFile 读取文件的全部数据, 文件格式根据内容检测;
+// 空文件返回 *EmptyFileError, 不支持的格式返回 *UnsupportedFormatError;
+// opts 支持 WithPassword、WithCharset、WithDelimiter、WithFillMergedCells、WithFormulaMode、WithTypedCells;
+// 只需要展示开头的数据时使用 Preview
+func OpenExFile(fileName string, opts ...ReadOption) (*ExcelFile, error) {
+	file, err := os.Open(fileName)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+	info, err := file.Stat()
+	if err != nil {
+		return nil, err
+	}
+	if info.Size() == 0 {
+		return nil, &EmptyFileError{FileName: fileName}
+	}
+	return openExFile(file, info.Size(), fileName, "", newReadOptions(opts...))
+}
+
+// OpenExReader 从 io.Reader 读取全部数据, fileType 为 common.FileTypeXlsx 等格式提示, 为空时根据内容检测
+func OpenExReader(r io.Reader, fileType string, opts ...ReadOption) (*ExcelFile, error) {
+	ra, size, err := toReaderAt(r)
+	if err != nil {
+		return nil, err
+	}
+	return openExFile(ra, size, "", fileType, newReadOptions(opts...))
+}
+
+// openExFile 按格式读取全部数据, fileType 为空时根据内容检测
+func openExFile(r io.ReaderAt, size int64, fileName, fileType string, options *readOptions) (*ExcelFile, error) {
 	var retFile ExcelFile
 	retSheets := make([]*ExcelSheet, 0)
 	totalRow := 0
-	//打开xlsx
-	if strings.HasSuffix(fileName, ".xlsx") {
-		f, err := excelize.OpenFile(fileName)
+	if fileType == "" {
+		var err error
+		if fileType, err = detectFileType(r, size); err != nil {
+			return nil, err
+		}
+	}
+	switch fileType {
+	case common.FileTypeXlsx:
+		//打开xlsx
+		f, err := openXLSX(r, size, fileName, options.password, xlsxDateOptions)
 		if err != nil {
 			return nil, err
 		}
 		defer f.Close()
+		date1904 := false
+		if props, err := f.GetWorkbookProps(); err == nil && props.Date1904 != nil {
+			date1904 = *props.Date1904
+		}
 		sheetList := f.GetSheetList()
 		for _, name := range sheetList {
 			var retSheet ExcelSheet
@@ -40,28 +74,45 @@ func OpenExFile(fileName string) (*ExcelFile, error) {
 			if err != nil {
 				return nil, err
 			}
+			if err = expandScientific(f, name, rows); err != nil {
+				return nil, err
+			}
+			for i, row := range rows {
+				failures, err := applyFormulas(f, name, i+1, row, options.formula, nil)
+				if err != nil {
+					return nil, err
+				}
+				retSheet.FormulaErrors = append(retSheet.FormulaErrors, failures...)
+			}
+			if retSheet.Merges, err = xlsxMergeRanges(f, name); err != nil {
+				return nil, err
+			}
+			if options.fillMerged {
+				fillMergedRows(rows, retSheet.Merges)
+			}
 			totalRow += len(rows)
 			retSheet.SheetName = name
 			retSheet.Rows = rows
+			if options.typedCells {
+				if err = retSheet.setXLSXCells(f, date1904); err != nil {
+					return nil, err
+				}
+			}
 			retSheets = append(retSheets, &retSheet)
 		}
-	}
-	//打开xls
-	if strings.HasSuffix(fileName, ".xls") {
-		dealXls(fileName)
-	}
-
-	//打开csv
-	if strings.HasSuffix(fileName, ".csv") {
-		csvFile, err := dealCSV(fileName)
+	case common.FileTypeCsv, common.FileTypeTsv, common.FileTypeTxt, common.FileTypeXls, common.FileTypeHtml,
+		common.FileTypeOds:
+		//打开csv、xls、ods和html表格
+		src, err := openSheetSource(r, size, fileName, fileType, nil, options)
 		if err != nil {
 			return nil, err
 		}
-		var retSheet ExcelSheet
-		retSheet.SheetName = "csv"
-		retSheet.Rows = csvFile
-		totalRow = len(csvFile)
-		retSheets = append(retSheets, &retSheet)
+		defer src.Close()
+		if retSheets, totalRow, err = readSourceSheets(src, options); err != nil {
+			return nil, err
+		}
+	default:
+		return nil, &UnsupportedFormatError{FileName: fileName, Format: fileType}
 	}
 	retFile.Sheets = retSheets
 	retFile.TotalRow = totalRow
@@ -69,95 +120,46 @@ func OpenExFile(fileName string) (*ExcelFile, error) {
 	return &retFile, nil
 }
 
-func dealCSV(fileName string) ([][]string, error) {
-	data, err := os.ReadFile(fileName)
-	if err != nil {
-		logx.Errorf("Failed to read file: %v", err)
-		return nil, err
-	}
-
-	// 检测文件编码
-	detector := chardet.NewTextDetector()
-	result, err := detector.DetectBest(data)
-	if err != nil {
-		log.Fatalf("Failed to detect encoding: %v", err)
-	}
-
-	// 打开文件进行解码
-	file, err := os.Open(fileName)
-	if err != nil {
-		log.Fatalf("Failed to open file: %v", err)
-	}
-	defer file.Close()
-
-	// 根据检测到的编码选择解码器
-	var decoder *encoding.Decoder
-	switch result.Charset {
-	case "UTF-8":
-		decoder = nil // UTF-8 不需要解码器
-	case "ISO-8859-1":
-		decoder = charmap.ISO8859_1.NewDecoder()
-	case "GBK":
-		decoder = simplifiedchinese.GBK.NewDecoder()
-	case "GB18030":
-		decoder = simplifiedchinese.GB18030.NewDecoder()
-	default:
-		return nil, errors.New(fmt.Sprintf("Unsupported encoding: %s", result.Charset))
-	}
-
-	// 创建 CSV reader
-	var reader *csv.Reader
-	if decoder != nil {
-		reader = csv.NewReader(transform.NewReader(file, decoder))
-	} else {
-		reader = csv.NewReader(file)
-	}
-	// 读取所有记录
-	records, err := reader.ReadAll()
-	if err != nil {
-		log.Fatalf("Failed to read CSV file: %v", err)
-	}
-	return records, nil
-}
-
-func dealXls(fileName string) ([]*ExcelSheet, error) {
-	f, err := xls.Open(fileName, "utf-8")
-	if err != nil {
-		return nil, err
-	}
-	var totalRow int
-	var retSheets []*ExcelSheet
-	for i := 0; i < f.NumSheets(); i++ {
-		var retSheet ExcelSheet
-		sheet := f.GetSheet(i)
-		totalRow += int(sheet.MaxRow) - 1
-		retRows := make([][]string, 0, 64)
-		for j := 0; j < int(sheet.MaxRow); j++ {
-			retRow := make([]string, 0, 64)
-			row := sheet.Row(j)
-			if row != nil {
-				for k := row.FirstCol(); k <= row.LastCol(); k++ {
-					col := row.Col(k)
-					retRow = append(retRow, col)
-				}
-				retRows = append(retRows, retRow)
+// readSourceSheets 读取全部表单的原始数据, 按设置填充合并单元格和返回带类型的数据
+func readSourceSheets(src sheetSource, options *readOptions) ([]*ExcelSheet, int, error) {
+	retSheets := make([]*ExcelSheet, 0)
+	totalRow := 0
+	typed, _ := src.(typedSource)
+	for src.NextSheet() {
+		retSheet := &ExcelSheet{SheetName: src.SheetName(), Merges: src.MergeRanges()}
+		var hints [][]cellHint
+		for src.NextRow() {
+			retSheet.Rows = append(retSheet.Rows, append([]string(nil), src.Cells()...))
+			if typed != nil && options.typedCells {
+				hints = append(hints, typed.CellHints())
 			}
 		}
-		retSheet.Rows = retRows
-		retSheet.SheetName = sheet.Name
-		retSheets = append(retSheets, &retSheet)
+		if options.fillMerged {
+			fillMergedRows(retSheet.Rows, retSheet.Merges)
+		}
+		if options.typedCells {
+			retSheet.setCells(hints, src.Date1904())
+		}
+		totalRow += len(retSheet.Rows)
+		retSheets = append(retSheets, retSheet)
 	}
-	return retSheets, nil
+	return retSheets, totalRow, src.Err()
 }
+
+// IsXlsx 判断是否为 .xlsx 或 .xls 文件, 文件存在时根据内容判断, 否则根据扩展名判断
 func IsXlsx(fileName string) bool {
-	if strings.HasSuffix(fileName, ".xlsx") || strings.HasSuffix(fileName, ".xls") {
+	switch fileTypeOrExt(fileName) {
+	case common.FileTypeXlsx, common.FileTypeXls:
 		return true
 	}
 	return false
 }
 
+// IsExcel 判断是否为可以读取的表格文件, 文件存在时根据内容判断, 否则根据扩展名判断
 func IsExcel(fileName string) bool {
-	if strings.HasSuffix(fileName, ".xlsx") || strings.HasSuffix(fileName, ".xls") || strings.HasSuffix(fileName, ".csv") {
+	switch fileTypeOrExt(fileName) {
+	case common.FileTypeXlsx, common.FileTypeXls, common.FileTypeCsv, common.FileTypeTsv, common.FileTypeTxt,
+		common.FileTypeHtml, common.FileTypeOds:
 		return true
 	}
 	return false
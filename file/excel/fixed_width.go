@@ -0,0 +1,334 @@
+package excelutil
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"strings"
+	"unicode/utf8"
+
+	"go_file/common"
+
+	"github.com/zeromicro/go-zero/core/logx"
+	"golang.org/x/text/encoding"
+	"golang.org/x/text/width"
+)
+
+// 定长文件单元格去掉空白的方式
+const (
+	TrimBoth  = "both"  // 去掉两端的空白, 默认
+	TrimLeft  = "left"  // 只去掉开头的空白
+	TrimRight = "right" // 只去掉末尾的空白
+	TrimNone  = "none"  // 保留空白
+)
+
+// fixedWidthSpaces 定长文件中作为填充的空白, 包括 GBK 文件中常见的全角空格
+const fixedWidthSpaces = " \t　"
+
+// FixedWidthColumn 定长文本文件的列定义. 位置和宽度按原文件编码的字节数计算, 例如 GBK 中汉字占 2 个;
+// UTF-8 和 UTF-16 文件按显示宽度计算, 全角字符占 2 个, 和 GBK 文件转码后的列位置一致
+type FixedWidthColumn struct {
+	Name     string `json:"name"`              // 列名, 作为表头
+	Start    int    `json:"start"`             // 起始位置, 从 0 开始
+	Width    int    `json:"width"`             // 宽度
+	Type     string `json:"type,optional"`     // 数据类型: string, int, number, 数字会去掉前导零并处理末尾的符号
+	Decimals int    `json:"decimals,optional"` // 数字隐含的小数位数, 如 decimals 为 2 时 000012345 读取为 123.45
+	Trim     string `json:"trim,optional"`     // 去掉空白的方式: both, left, right, none, 默认为 both
+}
+
+// FixedWidthLayout 定长文本文件的格式
+type FixedWidthLayout struct {
+	Columns  []FixedWidthColumn `json:"columns"`           // 列定义
+	SkipRows int                `json:"skipRows,optional"` // 跳过开头的行数, 如报表标题
+}
+
+// Validate 检查定长文件格式是否正确
+func (l *FixedWidthLayout) Validate() error {
+	if len(l.Columns) == 0 {
+		return fmt.Errorf("定长文件格式没有列定义")
+	}
+	names := make(map[string]bool, len(l.Columns))
+	for _, column := range l.Columns {
+		name := strings.TrimSpace(column.Name)
+		if name == "" {
+			return fmt.Errorf("定长文件格式的列缺少列名")
+		}
+		if names[name] {
+			return fmt.Errorf("定长文件格式的列名%s重复", name)
+		}
+		names[name] = true
+		if column.Start < 0 || column.Width <= 0 {
+			return fmt.Errorf("定长文件格式的列%s位置不正确:start=%d,width=%d", name, column.Start, column.Width)
+		}
+		switch column.Type {
+		case "", "string", ColumnTypeInt, ColumnTypeNumber:
+		default:
+			return fmt.Errorf("定长文件格式的列%s类型%s不支持", name, column.Type)
+		}
+		if column.Decimals < 0 || column.Decimals > 0 && column.Type != ColumnTypeNumber {
+			return fmt.Errorf("定长文件格式的列%s不是数字列, 不能设置小数位数", name)
+		}
+		switch column.Trim {
+		case "", TrimBoth, TrimLeft, TrimRight, TrimNone:
+		default:
+			return fmt.Errorf("定长文件格式的列%s去空白方式%s不支持", name, column.Trim)
+		}
+	}
+	return nil
+}
+
+// ReadFixedWidthFile 按 layout 读取定长文本文件, 列名作为表头, 其余参数同 ReadExcelFile;
+// 文件编码自动检测, 检测不准确时可以通过 WithCharset 指定
+func ReadFixedWidthFile(fileName string, layout *FixedWidthLayout, checkTitles []string,
+	dstTitleMap map[string]string, opts ...ReadOption) (*ExcelFile, error) {
+	file, err := os.Open(fileName)
+	if err != nil {
+		logx.Errorf("ReadFixedWidthFile:%s, failed to open file: %v", fileName, err)
+		return nil, err
+	}
+	return readFixedWidth(fileName, file, file, layout, checkTitles, dstTitleMap, opts...)
+}
+
+// ReadFixedWidthFromReader 从 io.Reader 读取定长文本文件, 参数同 ReadFixedWidthFile
+func ReadFixedWidthFromReader(r io.Reader, layout *FixedWidthLayout, checkTitles []string,
+	dstTitleMap map[string]string, opts ...ReadOption) (*ExcelFile, error) {
+	return readFixedWidth("", r, nil, layout, checkTitles, dstTitleMap, opts...)
+}
+
+func readFixedWidth(fileName string, r io.Reader, closer io.Closer, layout *FixedWidthLayout, checkTitles []string,
+	dstTitleMap map[string]string, opts ...ReadOption) (*ExcelFile, error) {
+	options := newReadOptions(opts...)
+	src, err := newFixedWidthSource(r, closer, layout, options.charset)
+	if err != nil {
+		if closer != nil {
+			closer.Close()
+		}
+		return nil, err
+	}
+	return readExcelFile(newRowReader(fileName, src, checkTitles, dstTitleMap, options))
+}
+
+// fixedWidthSource 按列定义拆分定长文本文件的每一行, 第一行输出列名作为表头
+type fixedWidthSource struct {
+	closer    io.Closer
+	reader    *bufio.Reader
+	layout    *FixedWidthLayout
+	widths    *runeWidths
+	done      bool
+	header    bool // 是否已输出表头
+	positions []int
+	offsets   []int
+	cells     []string
+	err       error
+}
+
+func newFixedWidthSource(r io.Reader, closer io.Closer, layout *FixedWidthLayout,
+	charset string) (*fixedWidthSource, error) {
+	if err := layout.Validate(); err != nil {
+		return nil, err
+	}
+	text, _, enc, err := newTextReader(r, charset)
+	if err != nil {
+		return nil, err
+	}
+	reader := bufio.NewReader(text)
+	for i := 0; i < layout.SkipRows; i++ {
+		if _, err = reader.ReadString('\n'); err != nil {
+			break
+		}
+	}
+	return &fixedWidthSource{
+		closer: closer,
+		reader: reader,
+		layout: layout,
+		widths: newRuneWidths(enc),
+	}, nil
+}
+
+// NextSheet 定长文件只有一个表单
+func (s *fixedWidthSource) NextSheet() bool {
+	if s.done {
+		return false
+	}
+	s.done = true
+	return true
+}
+
+func (s *fixedWidthSource) SheetName() string {
+	return "fixed"
+}
+
+func (s *fixedWidthSource) SheetIndex() int {
+	return 0
+}
+
+func (s *fixedWidthSource) Hidden() bool {
+	return false
+}
+
+func (s *fixedWidthSource) MergeRanges() []MergeRange {
+	return nil
+}
+
+func (s *fixedWidthSource) NextRow() bool {
+	if s.err != nil {
+		return false
+	}
+	if !s.header {
+		s.header = true
+		s.cells = s.cells[:0]
+		for _, column := range s.layout.Columns {
+			s.cells = append(s.cells, strings.TrimSpace(column.Name))
+		}
+		return true
+	}
+	line, err := s.reader.ReadString('\n')
+	if err != nil && (err != io.EOF || line == "") {
+		if err != io.EOF {
+			s.err = err
+		}
+		return false
+	}
+	s.split(strings.TrimRight(line, "\r\n"))
+	return true
+}
+
+// split 按列定义拆分一行, 跨越列边界的全角字符属于它开始的列
+func (s *fixedWidthSource) split(line string) {
+	s.positions, s.offsets = s.positions[:0], s.offsets[:0]
+	pos := 0
+	for offset, r := range line {
+		s.positions = append(s.positions, pos)
+		s.offsets = append(s.offsets, offset)
+		pos += s.widths.width(r)
+	}
+	s.positions = append(s.positions, pos)
+	s.offsets = append(s.offsets, len(line))
+
+	s.cells = s.cells[:0]
+	last := len(s.positions) - 1
+	for _, column := range s.layout.Columns {
+		from := min(sort.SearchInts(s.positions, column.Start), last)
+		to := min(sort.SearchInts(s.positions, column.Start+column.Width), last)
+		s.cells = append(s.cells, column.value(line[s.offsets[from]:s.offsets[to]]))
+	}
+}
+
+// value 按列定义处理单元格的值
+func (c *FixedWidthColumn) value(value string) string {
+	switch c.Trim {
+	case TrimLeft:
+		value = strings.TrimLeft(value, fixedWidthSpaces)
+	case TrimRight:
+		value = strings.TrimRight(value, fixedWidthSpaces)
+	case TrimNone:
+	default:
+		value = strings.Trim(value, fixedWidthSpaces)
+	}
+	if c.Type == ColumnTypeInt || c.Type == ColumnTypeNumber {
+		return fixedWidthNumber(value, c.Decimals)
+	}
+	return value
+}
+
+// fixedWidthNumber 规范化定长文件中的数字: 去掉前导零, 末尾的符号移到开头, 按隐含的小数位数插入小数点;
+// 不是数字时原样返回
+func fixedWidthNumber(value string, decimals int) string {
+	number := strings.TrimSpace(value)
+	sign := ""
+	switch {
+	case strings.HasPrefix(number, "-"), strings.HasPrefix(number, "+"):
+		sign, number = number[:1], number[1:]
+	case strings.HasSuffix(number, "-"), strings.HasSuffix(number, "+"):
+		sign, number = number[len(number)-1:], number[:len(number)-1]
+	}
+	if sign == "+" {
+		sign = ""
+	}
+	intPart, fracPart, hasPoint := strings.Cut(number, ".")
+	if intPart == "" && fracPart == "" || !isDigits(intPart) || !isDigits(fracPart) {
+		return value
+	}
+	if !hasPoint && decimals > 0 {
+		intPart = strings.Repeat("0", max(decimals-len(intPart), 0)) + intPart
+		intPart, fracPart, hasPoint = intPart[:len(intPart)-decimals], intPart[len(intPart)-decimals:], true
+	}
+	intPart = strings.TrimLeft(intPart, "0")
+	if intPart == "" {
+		intPart = "0"
+	}
+	if hasPoint && fracPart != "" {
+		return sign + intPart + "." + fracPart
+	}
+	return sign + intPart
+}
+
+// isDigits 是否只包含数字, 空字符串返回 true
+func isDigits(s string) bool {
+	for i := 0; i < len(s); i++ {
+		if s[i] < '0' || s[i] > '9' {
+			return false
+		}
+	}
+	return true
+}
+
+func (s *fixedWidthSource) Cells() []string {
+	return s.cells
+}
+
+func (s *fixedWidthSource) RawCells() []string {
+	return s.cells
+}
+
+func (s *fixedWidthSource) Date1904() bool {
+	return false
+}
+
+func (s *fixedWidthSource) Err() error {
+	return s.err
+}
+
+func (s *fixedWidthSource) Close() error {
+	if s.closer != nil {
+		return s.closer.Close()
+	}
+	return nil
+}
+
+// runeWidths 计算字符在定长文件中占的宽度, 原文件为 GBK 等多字节编码时为编码后的字节数,
+// UTF-8 和 UTF-16 文件为显示宽度
+type runeWidths struct {
+	encoder *encoding.Encoder
+	cache   map[rune]int
+}
+
+func newRuneWidths(enc encoding.Encoding) *runeWidths {
+	w := &runeWidths{cache: make(map[rune]int)}
+	if enc != nil && enc != charsetEncodings[common.CharsetUTF16LE] && enc != charsetEncodings[common.CharsetUTF16BE] {
+		w.encoder = enc.NewEncoder()
+	}
+	return w
+}
+
+func (w *runeWidths) width(r rune) int {
+	if r < utf8.RuneSelf {
+		return 1
+	}
+	if n, ok := w.cache[r]; ok {
+		return n
+	}
+	n := 1
+	if kind := width.LookupRune(r).Kind(); kind == width.EastAsianWide || kind == width.EastAsianFullwidth {
+		n = 2
+	}
+	if w.encoder != nil {
+		if b, err := w.encoder.Bytes([]byte(string(r))); err == nil {
+			n = len(b)
+		}
+	}
+	w.cache[r] = n
+	return n
+}
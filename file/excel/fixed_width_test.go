@@ -0,0 +1,129 @@
+package excelutil
+
+import (
+	"bytes"
+	"reflect"
+	"strings"
+	"testing"
+
+	"go_file/common"
+
+	"golang.org/x/text/encoding/simplifiedchinese"
+)
+
+func TestFixedWidthSplit(t *testing.T) {
+	layout := &FixedWidthLayout{Columns: []FixedWidthColumn{
+		{Name: "编码", Start: 0, Width: 4},
+		{Name: "名称", Start: 4, Width: 6, Trim: TrimNone},
+		{Name: "规格", Start: 10, Width: 4, Trim: TrimLeft},
+		{Name: "数量", Start: 14, Width: 6, Type: ColumnTypeNumber, Decimals: 2},
+	}}
+	tests := []struct {
+		name string
+		line string
+		want []string
+	}{
+		{"ASCII", "A01 bolt   M6 001234", []string{"A01", "bolt  ", "M6 ", "12.34"}},
+		{"末尾符号", "A02 nut     M812345-", []string{"A02", "nut   ", "M8", "-123.45"}},
+		{"短行", "A03 wash", []string{"A03", "wash", "", ""}},
+		{"空行", "", []string{"", "", "", ""}},
+	}
+	for _, tt := range tests {
+		src := &fixedWidthSource{layout: layout, widths: newRuneWidths(nil)}
+		src.split(tt.line)
+		if !reflect.DeepEqual(src.cells, tt.want) {
+			t.Errorf("%s: split(%q) = %q, want %q", tt.name, tt.line, src.cells, tt.want)
+		}
+	}
+}
+
+// GBK 文件按字节计算位置, 汉字占 2 个; 跨越列边界的汉字属于它开始的列
+func TestReadFixedWidthGBK(t *testing.T) {
+	layout := &FixedWidthLayout{
+		SkipRows: 1,
+		Columns: []FixedWidthColumn{
+			{Name: "编码", Start: 0, Width: 4},
+			{Name: "名称", Start: 4, Width: 5},
+			{Name: "数量", Start: 9, Width: 4, Type: ColumnTypeInt},
+		},
+	}
+	text := "物料清单\r\n" +
+		"M01 螺丝钉012\r\n" + // "钉" 占第 8、9 个字节, 跨越名称和数量的边界
+		"M02 垫片　003\r\n" + // 全角空格作为填充, 同样跨越边界
+		"M03 弹簧\r\n" + // 短行
+		"M04 螺母    7"
+	data, err := simplifiedchinese.GBK.NewEncoder().String(text)
+	if err != nil {
+		t.Fatal(err)
+	}
+	file, err := ReadFixedWidthFromReader(bytes.NewReader([]byte(data)), layout, nil,
+		map[string]string{"编码": "code", "名称": "name", "数量": "qty"}, WithCharset(common.CharsetGBK))
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := [][]string{
+		{"M01", "螺丝钉", "12"},
+		{"M02", "垫片", "3"},
+		{"M03", "弹簧", ""},
+		{"M04", "螺母", "7"},
+	}
+	if !reflect.DeepEqual(file.Sheets[0].Rows, want) {
+		t.Errorf("rows = %q, want %q", file.Sheets[0].Rows, want)
+	}
+	if want := []string{"code", "name", "qty"}; !reflect.DeepEqual(file.Sheets[0].Header, want) {
+		t.Errorf("header = %q, want %q", file.Sheets[0].Header, want)
+	}
+}
+
+// UTF-8 文件按显示宽度计算位置, 和 GBK 文件转码后的列位置一致
+func TestReadFixedWidthUTF8(t *testing.T) {
+	layout := &FixedWidthLayout{Columns: []FixedWidthColumn{
+		{Name: "名称", Start: 0, Width: 6},
+		{Name: "数量", Start: 6, Width: 3},
+	}}
+	file, err := ReadFixedWidthFromReader(strings.NewReader("螺丝钉001\n垫片  2\n"), layout, nil,
+		map[string]string{"名称": "name", "数量": "qty"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := [][]string{{"螺丝钉", "001"}, {"垫片", "2"}}; !reflect.DeepEqual(file.Sheets[0].Rows, want) {
+		t.Errorf("rows = %q, want %q", file.Sheets[0].Rows, want)
+	}
+}
+
+func TestFixedWidthNumber(t *testing.T) {
+	tests := []struct {
+		value    string
+		decimals int
+		want     string
+	}{
+		{"000123", 0, "123"},
+		{"000123", 2, "1.23"},
+		{"5", 2, "0.05"},
+		{"0012.50", 2, "12.50"},
+		{"123-", 0, "-123"},
+		{"+0", 0, "0"},
+		{"12a", 0, "12a"},
+		{"", 0, ""},
+	}
+	for _, tt := range tests {
+		if got := fixedWidthNumber(tt.value, tt.decimals); got != tt.want {
+			t.Errorf("fixedWidthNumber(%q, %d) = %q, want %q", tt.value, tt.decimals, got, tt.want)
+		}
+	}
+}
+
+func TestFixedWidthLayoutValidate(t *testing.T) {
+	tests := []*FixedWidthLayout{
+		{},
+		{Columns: []FixedWidthColumn{{Name: "a", Width: 0}}},
+		{Columns: []FixedWidthColumn{{Name: "a", Width: 1}, {Name: "a", Start: 1, Width: 1}}},
+		{Columns: []FixedWidthColumn{{Name: "a", Width: 1, Decimals: 2}}},
+		{Columns: []FixedWidthColumn{{Name: "a", Width: 1, Trim: "middle"}}},
+	}
+	for i, layout := range tests {
+		if err := layout.Validate(); err == nil {
+			t.Errorf("tests[%d]: Validate() error = nil, want error", i)
+		}
+	}
+}
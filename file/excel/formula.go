@@ -0,0 +1,76 @@
+package excelutil
+
+import (
+	"fmt"
+
+	"github.com/xuri/excelize/v2"
+)
+
+// FormulaMode 公式单元格的读取方式
+type FormulaMode int
+
+const (
+	FormulaCached   FormulaMode = iota // 使用文件中保存的计算结果, 默认; 其他工具生成的文件可能没有保存结果
+	FormulaEvaluate                    // 使用 excelize 计算引擎重新计算, 支持跨表单引用, 计算失败时使用保存的结果
+	FormulaText                        // 返回公式文本, 如 =SUM(A1:A3)
+)
+
+// FormulaError 公式计算失败的单元格
+type FormulaError struct {
+	SheetName string // 表单名称
+	Cell      string // 单元格, 如 "C2"
+	Formula   string // 公式, 不含开头的 =
+	Message   string // 失败原因
+}
+
+func (e *FormulaError) Error() string {
+	return fmt.Sprintf("公式计算失败:%s!%s=%s:%s", e.SheetName, e.Cell, e.Formula, e.Message)
+}
+
+// formulaSource 支持公式计算的原始数据读取器
+type formulaSource interface {
+	// FormulaErrors 当前表单中已读取的行里, 公式计算失败的单元格
+	FormulaErrors() []*FormulaError
+}
+
+// applyFormulas 按 mode 处理 .xlsx 表单第 rowNum 行中的公式单元格, 返回计算失败的单元格;
+// attrs 为流式读取的单元格属性, 只读取有公式的单元格, 为 nil 时逐个检查
+func applyFormulas(f *excelize.File, sheet string, rowNum int, cells []string, mode FormulaMode,
+	attrs []xlsxCellAttr) ([]*FormulaError, error) {
+	if mode == FormulaCached {
+		return nil, nil
+	}
+	var failures []*FormulaError
+	for i := range cells {
+		if attrs != nil && (i >= len(attrs) || !attrs[i].formula) {
+			continue
+		}
+		cell, err := excelize.CoordinatesToCellName(i+1, rowNum)
+		if err != nil {
+			return nil, err
+		}
+		formula, err := f.GetCellFormula(sheet, cell)
+		if err != nil {
+			return nil, err
+		}
+		if formula == "" {
+			continue
+		}
+		if mode == FormulaText {
+			cells[i] = "=" + formula
+			continue
+		}
+		value, err := f.CalcCellValue(sheet, cell)
+		if err != nil {
+			failures = append(failures, &FormulaError{SheetName: sheet, Cell: cell, Formula: formula, Message: err.Error()})
+			continue
+		}
+		if isScientific(value) {
+			if raw, err := f.CalcCellValue(sheet, cell, excelize.Options{RawCellValue: true}); err == nil {
+				value = plainNumber(raw)
+			}
+		}
+		cells[i] = value
+	}
+	return failures, nil
+}
@@ -0,0 +1,91 @@
+package excelutil
+
+import (
+	"bytes"
+	"reflect"
+	"testing"
+
+	"github.com/xuri/excelize/v2"
+)
+
+// newFormulaXLSX 生成包含公式的 .xlsx, 公式没有保存计算结果, 第二个表单没有公式
+func newFormulaXLSX(t *testing.T) *bytes.Reader {
+	t.Helper()
+	f := excelize.NewFile()
+	rows := [][]any{{"数量", "单价", "金额"}, {2, 1.5}, {3, 2}}
+	for i, row := range rows {
+		cell, _ := excelize.CoordinatesToCellName(1, i+1)
+		if err := f.SetSheetRow("Sheet1", cell, &row); err != nil {
+			t.Fatal(err)
+		}
+	}
+	for _, formula := range [][2]string{{"C2", "A2*B2"}, {"C3", "A3*B3+Sheet2!A1"}, {"D3", "1/0"}} {
+		if err := f.SetCellFormula("Sheet1", formula[0], formula[1]); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if _, err := f.NewSheet("Sheet2"); err != nil {
+		t.Fatal(err)
+	}
+	if err := f.SetCellValue("Sheet2", "A1", 10); err != nil {
+		t.Fatal(err)
+	}
+	buf, err := f.WriteToBuffer()
+	if err != nil {
+		t.Fatal(err)
+	}
+	return bytes.NewReader(buf.Bytes())
+}
+
+func TestXLSXSourceFormulaMode(t *testing.T) {
+	tests := []struct {
+		mode         FormulaMode
+		want         [][][]string
+		wantFailures []string
+	}{
+		{
+			FormulaCached,
+			[][][]string{{{"数量", "单价", "金额"}, {"2", "1.5", ""}, {"3", "2", "", ""}}, {{"10"}}},
+			nil,
+		},
+		{
+			FormulaText,
+			[][][]string{{{"数量", "单价", "金额"}, {"2", "1.5", "=A2*B2"}, {"3", "2", "=A3*B3+Sheet2!A1", "=1/0"}}, {{"10"}}},
+			nil,
+		},
+		{
+			FormulaEvaluate,
+			[][][]string{{{"数量", "单价", "金额"}, {"2", "1.5", "3"}, {"3", "2", "16", ""}}, {{"10"}}},
+			[]string{"D3"},
+		},
+	}
+	for _, tt := range tests {
+		r := newFormulaXLSX(t)
+		src, err := newXLSXSource(r, r.Size(), "test.xlsx", nil, "", tt.mode)
+		if err != nil {
+			t.Fatal(err)
+		}
+		var sheets [][][]string
+		var failures []string
+		for src.NextSheet() {
+			var rows [][]string
+			for src.NextRow() {
+				rows = append(rows, append([]string(nil), src.Cells()...))
+			}
+			for _, failure := range src.FormulaErrors() {
+				failures = append(failures, failure.Cell)
+			}
+			sheets = append(sheets, rows)
+		}
+		if err = src.Err(); err != nil {
+			t.Fatalf("mode %d: %v", tt.mode, err)
+		}
+		src.Close()
+		if !reflect.DeepEqual(sheets, tt.want) {
+			t.Errorf("mode %d: rows = %q, want %q", tt.mode, sheets, tt.want)
+		}
+		if !reflect.DeepEqual(failures, tt.wantFailures) {
+			t.Errorf("mode %d: failures = %v, want %v", tt.mode, failures, tt.wantFailures)
+		}
+	}
+}
@@ -0,0 +1,156 @@
+package excelutil
+
+import (
+	"strings"
+)
+
+// defaultHeaderScanRows 默认查找表头时扫描的行数
+const defaultHeaderScanRows = 10
+
+// headerSeparator 多级表头拼接时父级和子级之间的分隔符
+const headerSeparator = "/"
+
+// headerCandidate 候选表头
+type headerCandidate struct {
+	index      int      // 表头最后一行在扫描行中的下标
+	header     []string // 表头, 多级表头已拼接为 "父级/子级"
+	checkScore int      // 命中 checkTitles 的数量
+	dstScore   int      // 命中 dstTitleMap 的数量
+}
+
+// better 比较两个候选表头, 优先命中 checkTitles 多的, 其次命中 dstTitleMap 多的
+func (c *headerCandidate) better(other *headerCandidate) bool {
+	if other == nil {
+		return true
+	}
+	if c.checkScore != other.checkScore {
+		return c.checkScore > other.checkScore
+	}
+	return c.dstScore > other.dstScore
+}
+
+// detectHeader 在扫描的行中查找表头所在行, 返回表头最后一行的下标和表头;
+// 每一行分别作为单行表头和与上方连续的非空行组成的多级表头打分, 都不命中时取第一个非空行,
+// 没有非空行时返回 -1
+func detectHeader(rows [][]string, checkTitles []string, matcher *titleMatcher) (int, []string) {
+	checkSet := make(map[string]bool, len(checkTitles))
+	for _, title := range checkTitles {
+		checkSet[matcher.canonical(title)] = true
+	}
+	var best, first *headerCandidate
+	for i, row := range rows {
+		header := trimHeader(row)
+		if isEmptyRow(header) {
+			continue
+		}
+		candidate := scoreHeader(i, header, checkSet, matcher)
+		if first == nil {
+			first = candidate
+		}
+		if candidate.score() > 0 && candidate.better(best) {
+			best = candidate
+		}
+		for _, stacked := range stackedHeaders(rows, i) {
+			merged := scoreHeader(i, stacked, checkSet, matcher)
+			if merged.score() > 0 && merged.better(best) {
+				best = merged
+			}
+		}
+	}
+	if best == nil {
+		best = first
+	}
+	if best == nil {
+		return -1, nil
+	}
+	return best.index, best.header
+}
+
+// stackedHeaders 返回以第 i 行为最后一级的多级表头, 依次向上多拼接一行, 直到遇到空行;
+// 结果按级数从少到多排列, 不包括单行表头
+func stackedHeaders(rows [][]string, i int) [][]string {
+	var headers [][]string
+	for j := i - 1; j >= 0 && !isEmptyRow(rows[j]); j-- {
+		levels := make([][]string, 0, i-j+1)
+		for _, row := range rows[j : i+1] {
+			levels = append(levels, trimHeader(row))
+		}
+		headers = append(headers, flattenHeader(levels...))
+	}
+	return headers
+}
+
+func (c *headerCandidate) score() int {
+	return c.checkScore + c.dstScore
+}
+
+// scoreHeader 计算表头命中 checkTitles 和 dstTitleMap 的数量
+func scoreHeader(index int, header []string, checkSet map[string]bool, matcher *titleMatcher) *headerCandidate {
+	candidate := &headerCandidate{index: index, header: header}
+	for _, title := range header {
+		if title == "" {
+			continue
+		}
+		if checkSet[matcher.canonical(title)] {
+			candidate.checkScore++
+		}
+		if _, ok := matcher.lookup(title); ok {
+			candidate.dstScore++
+		}
+	}
+	return candidate
+}
+
+// flattenHeader 把多行合并单元格组成的表头逐级拼接为 "父级/子级/...", levels 从上到下排列;
+// 横向合并的单元格只在第一列有值, 某一级为空而下级不为空的列沿用左侧同一父级下该级的值;
+// 纵向合并的单元格只在最上面一级有值, 下级都为空时拼接到有值的一级为止, 左侧沿用的值也到此结束
+func flattenHeader(levels ...[]string) []string {
+	n := 0
+	for _, level := range levels {
+		n = max(n, len(level))
+	}
+	header := make([]string, n)
+	groups := make([]string, len(levels)) // 每一级可以被右侧沿用的值
+	for i := 0; i < n; i++ {
+		deepest := -1
+		for k, level := range levels {
+			if i < len(level) && level[i] != "" {
+				deepest = k
+			}
+		}
+		var parts []string
+		for k := 0; k <= deepest; k++ {
+			if i < len(levels[k]) && levels[k][i] != "" {
+				groups[k] = levels[k][i]
+				clear(groups[k+1:])
+			}
+			if groups[k] != "" {
+				parts = append(parts, groups[k])
+			}
+		}
+		if deepest < len(levels)-1 {
+			clear(groups[max(deepest, 0):])
+		}
+		header[i] = strings.Join(parts, headerSeparator)
+	}
+	return header
+}
+
+// trimHeader 去除表头前后空格
+func trimHeader(row []string) []string {
+	header := make([]string, len(row))
+	for i, cell := range row {
+		header[i] = strings.TrimSpace(cell)
+	}
+	return header
+}
+
+// isEmptyRow 判断是否为空行
+func isEmptyRow(row []string) bool {
+	for _, cell := range row {
+		if strings.TrimSpace(cell) != "" {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,155 @@
+package excelutil
+
+import (
+	"fmt"
+	"strings"
+	"unicode"
+
+	"golang.org/x/text/width"
+)
+
+// NormalizeTitle 规范化表头, 用于表头比较:
+// 全角字符转半角, 去除空白(包括换行)和标点符号, 英文字母转小写
+func NormalizeTitle(title string) string {
+	title = width.Narrow.String(title)
+	var b strings.Builder
+	b.Grow(len(title))
+	for _, r := range title {
+		if unicode.IsSpace(r) || unicode.IsPunct(r) || unicode.IsSymbol(r) || unicode.Is(unicode.Cf, r) {
+			continue
+		}
+		b.WriteRune(unicode.ToLower(r))
+	}
+	return b.String()
+}
+
+// titleMatcher 表头匹配器, 表头规范化后再比较, 别名会归并为同一个标准表头
+type titleMatcher struct {
+	aliases map[string]string // 规范化后的别名 -> 规范化后的标准表头
+	dstKeys map[string]string // 规范化后的标准表头 -> dstTitleMap 中的源表头
+	dstMap  map[string]string
+}
+
+// newTitleMatcher 创建表头匹配器, aliases 为标准表头和它的别名列表
+func newTitleMatcher(dstTitleMap map[string]string, aliases map[string][]string) *titleMatcher {
+	m := &titleMatcher{
+		aliases: make(map[string]string),
+		dstKeys: make(map[string]string, len(dstTitleMap)),
+		dstMap:  dstTitleMap,
+	}
+	for title, list := range aliases {
+		canonical := NormalizeTitle(title)
+		for _, alias := range list {
+			m.aliases[NormalizeTitle(alias)] = canonical
+		}
+	}
+	for key := range dstTitleMap {
+		canonical := m.canonical(key)
+		// 规范化后重复时优先使用和标准表头完全一致的源表头, 都不一致时取较小的, 不受 map 遍历顺序影响
+		if exist, ok := m.dstKeys[canonical]; ok && (exist == canonical || key != canonical && exist < key) {
+			continue
+		}
+		m.dstKeys[canonical] = key
+	}
+	return m
+}
+
+// canonical 返回表头规范化并归并别名后的标准形式
+func (m *titleMatcher) canonical(title string) string {
+	normalized := NormalizeTitle(title)
+	if canonical, ok := m.aliases[normalized]; ok {
+		return canonical
+	}
+	return normalized
+}
+
+// lookup 查找表头在 dstTitleMap 中对应的源表头
+func (m *titleMatcher) lookup(title string) (string, bool) {
+	if _, ok := m.dstMap[title]; ok {
+		return title, true
+	}
+	key, ok := m.dstKeys[m.canonical(title)]
+	return key, ok
+}
+
+// dstTitle 返回表头映射后的目标表头
+func (m *titleMatcher) dstTitle(title string) (string, bool) {
+	key, ok := m.lookup(title)
+	if !ok {
+		return "", false
+	}
+	return m.dstMap[key], true
+}
+
+// missingTitles 返回表头中缺少的 checkTitles
+func (m *titleMatcher) missingTitles(header []string, checkTitles []string) []string {
+	exists := make(map[string]bool, len(header))
+	for _, title := range header {
+		exists[m.canonical(title)] = true
+	}
+	var missing []string
+	for _, title := range checkTitles {
+		if !exists[m.canonical(title)] {
+			missing = append(missing, title)
+		}
+	}
+	return missing
+}
+
+// suggestTitle 在表头中查找和 title 最接近的列, 用于错误提示
+func (m *titleMatcher) suggestTitle(header []string, title string) string {
+	target := []rune(m.canonical(title))
+	var (
+		best     string
+		bestDist = -1
+	)
+	for _, candidate := range header {
+		if candidate == "" {
+			continue
+		}
+		dist := editDistance(target, []rune(m.canonical(candidate)))
+		if bestDist < 0 || dist < bestDist {
+			best, bestDist = candidate, dist
+		}
+	}
+	return best
+}
+
+// checkExcelTitle 检测表头是否符合要求, 缺少列时提示最接近的表头
+func (m *titleMatcher) checkExcelTitle(header []string, checkTitles []string) error {
+	missing := m.missingTitles(header, checkTitles)
+	if len(missing) == 0 {
+		return nil
+	}
+	details := make([]string, 0, len(missing))
+	for _, title := range missing {
+		if suggest := m.suggestTitle(header, title); suggest != "" {
+			details = append(details, fmt.Sprintf("%s(最接近的表头:%s)", title, suggest))
+		} else {
+			details = append(details, title)
+		}
+	}
+	return fmt.Errorf("表头不符合要求,表头应包含列:%s,缺少列:%s",
+		strings.Join(checkTitles, ","), strings.Join(details, ","))
+}
+
+// editDistance 计算两个字符串的编辑距离
+func editDistance(a, b []rune) int {
+	prev := make([]int, len(b)+1)
+	cur := make([]int, len(b)+1)
+	for j := range prev {
+		prev[j] = j
+	}
+	for i := 1; i <= len(a); i++ {
+		cur[0] = i
+		for j := 1; j <= len(b); j++ {
+			cost := 1
+			if a[i-1] == b[j-1] {
+				cost = 0
+			}
+			cur[j] = min(prev[j]+1, cur[j-1]+1, prev[j-1]+cost)
+		}
+		prev, cur = cur, prev
+	}
+	return prev[len(b)]
+}
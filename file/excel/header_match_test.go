@@ -0,0 +1,100 @@
+package excelutil
+
+import (
+	"testing"
+)
+
+func TestNormalizeTitle(t *testing.T) {
+	tests := map[string]string{
+		"物料编码":        "物料编码",
+		" 物料\n编码 ":    "物料编码",
+		"ＳＫＵ":         "sku",
+		"Item Code":   "itemcode",
+		"数量（个）":       "数量个",
+		"单价(元)":       "单价元",
+		"\ufeff编码":    "编码",
+		"Ｍ０１－编码":      "m01编码",
+		"规格/长":        "规格长",
+		"UPPER_lower": "upperlower",
+	}
+	for title, want := range tests {
+		if got := NormalizeTitle(title); got != want {
+			t.Errorf("NormalizeTitle(%q) = %q, want %q", title, got, want)
+		}
+	}
+}
+
+func TestTitleMatcher(t *testing.T) {
+	dstTitleMap := map[string]string{"物料编码": "code", "数量（个）": "qty", "SKU": "sku"}
+	m := newTitleMatcher(dstTitleMap, map[string][]string{"物料编码": {"料号", "物料编号"}})
+	tests := []struct {
+		title string
+		want  string
+		ok    bool
+	}{
+		{"物料编码", "code", true},
+		{" 物料 编码", "code", true},
+		{"料号", "code", true},
+		{"物料编号 ", "code", true},
+		{"数量(个)", "qty", true},
+		{"ｓｋｕ", "sku", true},
+		{"名称", "", false},
+	}
+	for _, tt := range tests {
+		if got, ok := m.dstTitle(tt.title); got != tt.want || ok != tt.ok {
+			t.Errorf("dstTitle(%q) = %q, %v, want %q, %v", tt.title, got, ok, tt.want, tt.ok)
+		}
+	}
+	// 别名和标准表头都在表头中时, 检查必须存在的表头时按同一列处理
+	if missing := m.missingTitles([]string{"料号", "数量(个)"}, []string{"物料编码", "数量（个）"}); missing != nil {
+		t.Errorf("missingTitles() = %q, want nil", missing)
+	}
+}
+
+// 规范化后重复的源表头优先使用和标准表头完全一致的
+func TestTitleMatcherDuplicateKeys(t *testing.T) {
+	for i := 0; i < 20; i++ {
+		m := newTitleMatcher(map[string]string{"sku": "exact", "S K U": "spaced", "SKU ": "trailing"}, nil)
+		if got, _ := m.dstTitle("ＳＫＵ"); got != "exact" {
+			t.Fatalf("dstTitle() = %q, want %q", got, "exact")
+		}
+		m = newTitleMatcher(map[string]string{"S K U": "spaced", "SKU ": "trailing"}, nil)
+		if got, _ := m.dstTitle("sku"); got != "spaced" {
+			t.Fatalf("dstTitle() = %q, want %q", got, "spaced")
+		}
+	}
+}
+
+func TestCheckExcelTitle(t *testing.T) {
+	m := newTitleMatcher(nil, map[string][]string{"物料编码": {"料号"}})
+	if err := m.checkExcelTitle([]string{"料号", "名称"}, []string{"物料编码", "名称"}); err != nil {
+		t.Errorf("checkExcelTitle() error = %v, want nil", err)
+	}
+	err := m.checkExcelTitle([]string{"物料编吗", "", "规格型号", "单价"}, []string{"物料编码", "规格", "数量"})
+	want := "表头不符合要求,表头应包含列:物料编码,规格,数量," +
+		"缺少列:物料编码(最接近的表头:物料编吗),规格(最接近的表头:规格型号),数量(最接近的表头:单价)"
+	if err == nil || err.Error() != want {
+		t.Errorf("checkExcelTitle() error = %v, want %q", err, want)
+	}
+	if err = m.checkExcelTitle(nil, []string{"编码"}); err == nil || err.Error() != "表头不符合要求,表头应包含列:编码,缺少列:编码" {
+		t.Errorf("checkExcelTitle() empty header error = %v", err)
+	}
+}
+
+func TestEditDistance(t *testing.T) {
+	tests := []struct {
+		a, b string
+		want int
+	}{
+		{"", "", 0},
+		{"编码", "", 2},
+		{"物料编码", "物料编吗", 1},
+		{"规格", "规格型号", 2},
+		{"kitten", "sitting", 3},
+	}
+	for _, tt := range tests {
+		if got := editDistance([]rune(tt.a), []rune(tt.b)); got != tt.want {
+			t.Errorf("editDistance(%q, %q) = %d, want %d", tt.a, tt.b, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,118 @@
+package excelutil
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestFlattenHeader(t *testing.T) {
+	tests := []struct {
+		name   string
+		levels [][]string
+		want   []string
+	}{
+		{"单行", [][]string{{"编码", "名称"}}, []string{"编码", "名称"}},
+		{
+			"两级",
+			[][]string{{"编码", "规格", "", "数量"}, {"", "长", "宽", ""}},
+			[]string{"编码", "规格/长", "规格/宽", "数量"},
+		},
+		{
+			"三级",
+			[][]string{
+				{"编码", "库存", "", "", "", "备注"},
+				{"", "北京", "", "上海", "", ""},
+				{"", "数量", "金额", "数量", "金额", ""},
+			},
+			[]string{"编码", "库存/北京/数量", "库存/北京/金额", "库存/上海/数量", "库存/上海/金额", "备注"},
+		},
+		{
+			// 中间一级纵向合并到最后一级
+			"中间级纵向合并",
+			[][]string{{"库存", "", ""}, {"北京", "", "合计"}, {"数量", "金额", ""}},
+			[]string{"库存/北京/数量", "库存/北京/金额", "库存/合计"},
+		},
+		{
+			"纵向合并后面的子级不沿用",
+			[][]string{{"编码", "", "名称"}, {"", "", ""}, {"", "长", ""}},
+			[]string{"编码", "长", "名称"},
+		},
+		{"长度不同", [][]string{{"规格"}, {"长", "宽"}}, []string{"规格/长", "规格/宽"}},
+	}
+	for _, tt := range tests {
+		if got := flattenHeader(tt.levels...); !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("%s: flattenHeader() = %q, want %q", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestDetectHeader(t *testing.T) {
+	dstTitleMap := map[string]string{
+		"编码": "code", "名称": "name", "规格/长": "length", "规格/宽": "width",
+		"库存/北京/数量": "bj_qty", "库存/上海/数量": "sh_qty",
+	}
+	tests := []struct {
+		name        string
+		rows        [][]string
+		checkTitles []string
+		wantIndex   int
+		wantHeader  []string
+	}{
+		{"没有数据", [][]string{{}, {" "}}, nil, -1, nil},
+		{"都不命中时取第一个非空行", [][]string{{}, {"a", "b"}, {"c"}}, nil, 1, []string{"a", "b"}},
+		{
+			"跳过标题行",
+			[][]string{{"物料清单"}, {"导出时间:2023-07-16"}, {" 编码 ", "名称"}, {"001", "螺丝"}},
+			nil, 2, []string{"编码", "名称"},
+		},
+		{
+			"优先命中 checkTitles",
+			[][]string{{"名称", "备注"}, {"编码", "说明"}},
+			[]string{"编码"}, 1, []string{"编码", "说明"},
+		},
+		{
+			"两级表头",
+			[][]string{{"物料清单"}, {"编码", "规格", ""}, {"", "长", "宽"}, {"001", "10", "20"}},
+			nil, 2, []string{"编码", "规格/长", "规格/宽"},
+		},
+		{
+			"三级表头",
+			[][]string{
+				{"编码", "库存", "", "", ""},
+				{"", "北京", "", "上海", ""},
+				{"", "数量", "金额", "数量", "金额"},
+				{"001", "1", "2", "3", "4"},
+			},
+			nil, 2, []string{"编码", "库存/北京/数量", "库存/北京/金额", "库存/上海/数量", "库存/上海/金额"},
+		},
+	}
+	for _, tt := range tests {
+		index, header := detectHeader(tt.rows, tt.checkTitles, newTitleMatcher(dstTitleMap, nil))
+		if index != tt.wantIndex || !reflect.DeepEqual(header, tt.wantHeader) {
+			t.Errorf("%s: detectHeader() = %d, %q, want %d, %q", tt.name, index, header, tt.wantIndex, tt.wantHeader)
+		}
+	}
+}
+
+// 三级表头的文件按拼接后的表头映射列, 表头行号为最后一级所在的行
+func TestReadMultiLevelHeader(t *testing.T) {
+	fileName := writeTestXLSX(t, "stock.xlsx", testSheet{name: "库存", rows: [][]string{
+		{"库存报表"},
+		{"编码", "库存", "", "", ""},
+		{"", "北京", "", "上海", ""},
+		{"", "数量", "金额", "数量", "金额"},
+		{"001", "1", "2", "3", "4"},
+	}})
+	file, err := ReadExcelFile(fileName, []string{"编码"},
+		map[string]string{"编码": "code", "库存/北京/数量": "bj_qty", "库存/上海/金额": "sh_amount"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	sheet := file.Sheets[0]
+	if sheet.HeaderRow != 4 || !reflect.DeepEqual(sheet.Header, []string{"code", "bj_qty", "sh_amount"}) {
+		t.Errorf("HeaderRow = %d, Header = %q", sheet.HeaderRow, sheet.Header)
+	}
+	if want := [][]string{{"001", "1", "4"}}; !reflect.DeepEqual(sheet.Rows, want) {
+		t.Errorf("rows = %q, want %q", sheet.Rows, want)
+	}
+}
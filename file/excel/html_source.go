@@ -0,0 +1,216 @@
+package excelutil
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+
+	"golang.org/x/net/html"
+	"golang.org/x/net/html/atom"
+	"golang.org/x/net/html/charset"
+	"golang.org/x/text/transform"
+)
+
+// htmlSource 读取 html 表格, 很多系统导出的 .xls 实际是 html, 每个 <table> 作为一个表单
+type htmlSource struct {
+	closer    io.Closer
+	tokenizer *html.Tokenizer
+	index     int
+	inTable   bool
+	depth     int  // 嵌套表格的层数, 嵌套表格的内容作为单元格文本
+	rowOpened bool // 上一行因遇到新的 <tr> 结束, 新行已经开始
+	cells     []string
+	err       error
+}
+
+// newHTMLSource 优先使用 encodingName 指定的编码, 其次是 BOM 和 <meta> 中声明的编码, 都没有时按内容检测
+func newHTMLSource(rd io.Reader, closer io.Closer, encodingName string) (*htmlSource, error) {
+	br := bufio.NewReaderSize(rd, csvSampleSize)
+	sample, err := br.Peek(csvSampleSize)
+	if err != nil && err != io.EOF && err != bufio.ErrBufferFull {
+		return nil, err
+	}
+	if encodingName == "" {
+		if enc, _, certain := charset.DetermineEncoding(sample, "text/html"); certain {
+			r := transform.NewReader(br, enc.NewDecoder())
+			return &htmlSource{closer: closer, tokenizer: html.NewTokenizer(r)}, nil
+		}
+	}
+	r, _, _, err := newTextReader(br, encodingName)
+	if err != nil {
+		return nil, err
+	}
+	return &htmlSource{closer: closer, tokenizer: html.NewTokenizer(r)}, nil
+}
+
+// NextSheet 跳到下一个 <table>
+func (s *htmlSource) NextSheet() bool {
+	for s.inTable && s.NextRow() {
+	}
+	for s.err == nil {
+		tt := s.tokenizer.Next()
+		if tt == html.ErrorToken {
+			s.setErr()
+			return false
+		}
+		if tt == html.StartTagToken && s.atom() == atom.Table {
+			s.index++
+			s.inTable, s.depth, s.rowOpened = true, 0, false
+			return true
+		}
+	}
+	return false
+}
+
+func (s *htmlSource) SheetName() string {
+	return fmt.Sprintf("Sheet%d", s.index)
+}
+
+// SheetIndex 第几个 <table>, 从 0 开始
+func (s *htmlSource) SheetIndex() int {
+	return s.index - 1
+}
+
+func (s *htmlSource) Hidden() bool {
+	return false
+}
+
+// MergeRanges colspan 已经按空单元格展开, 不返回合并区域
+func (s *htmlSource) MergeRanges() []MergeRange {
+	return nil
+}
+
+// NextRow 读取 <tr> 中的 <td>/<th>, colspan 大于 1 时补空单元格; 兼容省略结束标签的写法
+func (s *htmlSource) NextRow() bool {
+	if s.err != nil || !s.inTable {
+		return false
+	}
+	s.cells = s.cells[:0]
+	inRow := s.rowOpened
+	s.rowOpened = false
+	var (
+		text    strings.Builder
+		inCell  bool
+		colspan int
+	)
+	endCell := func() {
+		if !inCell {
+			return
+		}
+		s.cells = append(s.cells, strings.Join(strings.Fields(text.String()), " "))
+		for i := 1; i < colspan; i++ {
+			s.cells = append(s.cells, "")
+		}
+		inCell = false
+	}
+	for {
+		switch s.tokenizer.Next() {
+		case html.ErrorToken:
+			s.setErr()
+			s.inTable = false
+			endCell()
+			return inRow && s.err == nil
+		case html.TextToken:
+			if inCell {
+				text.Write(s.tokenizer.Text())
+			}
+		case html.StartTagToken, html.SelfClosingTagToken:
+			switch s.atom() {
+			case atom.Table:
+				s.depth++
+			case atom.Br:
+				text.WriteByte(' ')
+			case atom.Tr:
+				if s.depth > 0 {
+					continue
+				}
+				if inRow {
+					endCell()
+					s.rowOpened = true
+					return true
+				}
+				inRow = true
+			case atom.Td, atom.Th:
+				if s.depth > 0 {
+					continue
+				}
+				endCell()
+				inRow, inCell, colspan = true, true, s.colspan()
+				text.Reset()
+			}
+		case html.EndTagToken:
+			switch s.atom() {
+			case atom.Td, atom.Th:
+				if s.depth == 0 {
+					endCell()
+				}
+			case atom.Tr:
+				if s.depth == 0 && inRow {
+					endCell()
+					return true
+				}
+			case atom.Table:
+				if s.depth > 0 {
+					s.depth--
+					continue
+				}
+				s.inTable = false
+				endCell()
+				return inRow
+			}
+		}
+	}
+}
+
+// atom 当前标签名
+func (s *htmlSource) atom() atom.Atom {
+	name, _ := s.tokenizer.TagName()
+	return atom.Lookup(name)
+}
+
+// colspan 当前标签的 colspan 属性, 需要在 atom 之后调用
+func (s *htmlSource) colspan() int {
+	for {
+		key, val, more := s.tokenizer.TagAttr()
+		if string(key) == "colspan" {
+			if n, err := strconv.Atoi(strings.TrimSpace(string(val))); err == nil && n > 1 {
+				return n
+			}
+		}
+		if !more {
+			return 1
+		}
+	}
+}
+
+// setErr 记录读取错误, 读到文件末尾不是错误
+func (s *htmlSource) setErr() {
+	if err := s.tokenizer.Err(); err != io.EOF {
+		s.err = err
+	}
+}
+
+func (s *htmlSource) Cells() []string {
+	return s.cells
+}
+
+func (s *htmlSource) RawCells() []string {
+	return s.cells
+}
+
+func (s *htmlSource) Date1904() bool {
+	return false
+}
+
+func (s *htmlSource) Err() error {
+	return s.err
+}
+
+func (s *htmlSource) Close() error {
+	if s.closer != nil {
+		return s.closer.Close()
+	}
+	return nil
+}
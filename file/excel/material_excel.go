@@ -1,411 +1,264 @@
 package excelutil
 
 import (
-	"encoding/csv"
-	"fmt"
+	"bytes"
 	"io"
-	"os"
-	"path/filepath"
-	"strings"
+	"time"
 
 	"go_file/common"
-	"go_file/utils/sliceutil"
-	"go_file/utils/timeutil"
-
-	"github.com/extrame/xls"
-	"github.com/gogs/chardet"
-	"github.com/tealeg/xlsx"
-	"github.com/zeromicro/go-zero/core/logx"
-	"golang.org/x/text/encoding"
-	"golang.org/x/text/encoding/charmap"
-	"golang.org/x/text/encoding/simplifiedchinese"
-	"golang.org/x/text/transform"
 )
 
 type ExcelFile struct {
-	FileName string        // 文件名
-	Sheets   []*ExcelSheet // 表单信息
-	TotalRow int
+	FileName   string        // 文件名
+	Sheets     []*ExcelSheet // 表单信息
+	TotalRow   int
+	Violations []*Violation  // 不符合校验规则的单元格
+	Report     *ImportReport // 导入报告
 }
 
 type ExcelSheet struct {
-	SheetName string     // 表单名称
-	Header    []string   // 表头
-	Rows      [][]string // 数据
+	SheetName string       // 表单名称
+	HeaderRow int          // 表头所在行号, 从1开始, 多级表头为最后一级所在的行号
+	Header    []string     // 表头
+	Rows      [][]string   // 数据
+	Merges    []MergeRange // 合并单元格区域, 位置为原始表单中的行列号
+
+	FormulaErrors []*FormulaError // 公式计算失败的单元格, 只在 FormulaEvaluate 方式下记录
+
+	Cells       [][]Cell   // 带类型的数据, 和 Rows 对应, 只在设置 WithTypedCells 时返回
+	ColumnTypes []CellKind // 推断的列类型, 和 Header 对应; OpenExFile 中为每一列, 第一行作为表头不参与推断
+
+	keys *sheetKeys // 设置 WithUniqueKey 时的主键列
 }
 
 // ReadExcelFile 读取Excel文件, 提取指定表头数据
-// dstTitleMap 待提取的表头和要转为的目标表头映射
-func ReadExcelFile(fileName string, checkTitles []string, dstTitleMap map[string]string) (
+// dstTitleMap 待提取的表头和要转为的目标表头映射, 读取时不会修改
+// 表头不在第一行时会在前几行中查找, 多级表头会逐级拼接为 "父级/子级"
+// 文件格式根据内容检测, 和扩展名无关, 空文件返回 *EmptyFileError, 不支持的格式返回 *UnsupportedFormatError
+func ReadExcelFile(fileName string, checkTitles []string, dstTitleMap map[string]string, opts ...ReadOption) (
 	*ExcelFile, error) {
-	ext := strings.ToLower(filepath.Ext(fileName))
-	switch ext {
+	fileType, err := DetectFileType(fileName)
+	if err != nil {
+		return nil, err
+	}
+	switch fileType {
 	case common.FileTypeXlsx:
-		return ProcessXLSXFile(fileName, checkTitles, dstTitleMap)
+		return ProcessXLSXFile(fileName, checkTitles, dstTitleMap, opts...)
 	case common.FileTypeXls:
-		return ProcessXLSFile(fileName, checkTitles, dstTitleMap)
+		return ProcessXLSFile(fileName, checkTitles, dstTitleMap, opts...)
 	case common.FileTypeCsv:
-		return ProcessCSVFile(fileName, checkTitles, dstTitleMap)
+		return ProcessCSVFile(fileName, checkTitles, dstTitleMap, opts...)
+	case common.FileTypeHtml:
+		return ProcessHTMLFile(fileName, checkTitles, dstTitleMap, opts...)
+	case common.FileTypeOds:
+		return ProcessODSFile(fileName, checkTitles, dstTitleMap, opts...)
 	}
-	return nil, nil
+	return nil, &UnsupportedFormatError{FileName: fileName, Format: fileType}
 }
 
-// ProcessXLSXFile 处理 .xlsx 文件, 提取指定表头数据
-// dstTitleMap 待提取的表头
-// delNullCell 是否删除空单元格的行
-func ProcessXLSXFile(fileName string, checkTitles []string, dstTitleMap map[string]string) (
-	*ExcelFile, error) {
-	xlFile, err := xlsx.OpenFile(fileName)
-	if err != nil {
-		return nil, err
-	}
-	retSheets := make([]*ExcelSheet, 0)
-	totalRow := 0
-	for _, sheet := range xlFile.Sheets {
-		var excelSheet ExcelSheet
-		excelSheet.SheetName = sheet.Name
-		header, err := extractHeader(sheet.Rows)
+// ReadExcelFiles 批量读取多个文件, 参数同 ReadExcelFile; 设置 WithUniqueKey 时在全部文件中检查主键重复,
+// 每个文件的 ImportReport.Duplicates 记录和该文件有关的重复主键
+func ReadExcelFiles(fileNames []string, checkTitles []string, dstTitleMap map[string]string, opts ...ReadOption) (
+	[]*ExcelFile, error) {
+	files := make([]*ExcelFile, 0, len(fileNames))
+	opts = append(opts[:len(opts):len(opts)], func(o *readOptions) { o.batch = true })
+	for _, fileName := range fileNames {
+		file, err := ReadExcelFile(fileName, checkTitles, dstTitleMap, opts...)
 		if err != nil {
 			return nil, err
 		}
-		// 标记每一列是否有非空数据,并检测表头是否符合要求
-		columnIsEmpty, err := checkExcelTitle(header, checkTitles)
-		if err != nil {
-			return nil, err
-		}
-		// 遍历所有行，检测列是否有非空数据
-		for _, row := range sheet.Rows[1:] {
-			for i, cell := range row.Cells {
-				if cell.String() != "" {
-					columnIsEmpty[i] = false
-				}
-			}
-		}
-		// 根据数据是否为空更新 dstTitleMap
-		for i, empty := range columnIsEmpty {
-			if empty {
-				delete(dstTitleMap, header[i])
-			}
-		}
-		for _, title := range header {
-			if dst, ok := dstTitleMap[title]; ok {
-				excelSheet.Header = append(excelSheet.Header, dst)
-			}
-		}
-		// 处理每一行数据,提取需要的列数据
-		for _, row := range sheet.Rows[1:] { // 跳过表头
-			mappedData := mapRowData(header, row.Cells, dstTitleMap)
-			if len(mappedData) > 0 {
-				rowData := make([]string, 0)
-				for _, title := range excelSheet.Header {
-					if value, ok := mappedData[title]; ok {
-						rowData = append(rowData, value)
-					}
-				}
-				totalRow++
-				excelSheet.Rows = append(excelSheet.Rows, rowData)
-			}
-		}
-		retSheets = append(retSheets, &excelSheet)
-	}
-	retFile := &ExcelFile{}
-	retFile.FileName = fileName
-	retFile.Sheets = retSheets
-	retFile.TotalRow = totalRow
-	return retFile, nil
-}
-
-// checkExcelTitle 检测表头是否符合要求
-func checkExcelTitle(header []string, checkTitles []string) (map[int]bool, error) {
-	columnIsEmpty := make(map[int]bool)
-	var titleNum int
-	for i := range header {
-		columnIsEmpty[i] = true
-		if sliceutil.StringInSlice(checkTitles, header[i]) {
-			titleNum++
-		}
+		files = append(files, file)
 	}
-	if titleNum != len(checkTitles) {
-		var fullTitle string
-		for _, title := range checkTitles {
-			if fullTitle == "" {
-				fullTitle = title
-			} else {
-				fullTitle += "," + title
-			}
-		}
-		return nil, fmt.Errorf("表头不符合要求,表头应包含列:%s", fullTitle)
+	if options := newReadOptions(opts...); len(options.uniqueKey) > 0 {
+		checkDuplicates(files, options.duplicate)
 	}
-	return columnIsEmpty, nil
+	return files, nil
 }
 
-// ProcessCSVFile 处理 .csv 文件
-func ProcessCSVFile(fileName string, checkTitles []string, dstTitleMap map[string]string) (
-	*ExcelFile, error) {
-	// 读取文件内容
-	data, err := os.ReadFile(fileName)
+// ReadExcelFromReader 从 io.Reader 读取表格数据, 例如上传的文件, 不需要先写入临时文件.
+// fileType 为 common.FileTypeXlsx 等格式提示, 为空时根据内容检测; 其余参数同 ReadExcelFile
+func ReadExcelFromReader(r io.Reader, fileType string, checkTitles []string, dstTitleMap map[string]string,
+	opts ...ReadOption) (*ExcelFile, error) {
+	ra, size, err := toReaderAt(r)
 	if err != nil {
-		logx.Errorf("ProcessCSVFile Failed to read file: %v", err)
 		return nil, err
 	}
-	// 检测文件编码
-	detector := chardet.NewTextDetector()
-	result, err := detector.DetectBest(data)
-	if err != nil {
-		return nil, err
+	if fileType == "" {
+		if fileType, err = detectFileType(ra, size); err != nil {
+			return nil, err
+		}
 	}
-
-	// 打开文件进行解码
-	file, err := os.Open(fileName)
+	options := newReadOptions(opts...)
+	src, err := openSheetSource(ra, size, "", fileType, nil, options)
 	if err != nil {
-		logx.Errorf("ProcessCSVFile Failed to open file: %v", err)
 		return nil, err
 	}
-	defer file.Close()
+	return readExcelFile(newRowReader("", src, checkTitles, dstTitleMap, options))
+}
 
-	// 根据检测到的编码选择解码器
-	var decoder *encoding.Decoder
-	switch result.Charset {
-	case common.CharsetISO88591:
-		decoder = charmap.ISO8859_1.NewDecoder()
-	case common.CharsetGBK:
-		decoder = simplifiedchinese.GBK.NewDecoder()
-	case common.CharsetGB18030:
-		decoder = simplifiedchinese.GB18030.NewDecoder()
-	default:
-	}
-	var reader *csv.Reader
-	if decoder != nil {
-		reader = csv.NewReader(transform.NewReader(file, decoder))
-	} else {
-		reader = csv.NewReader(file)
-	}
-	reader.FieldsPerRecord = -1 // 允许可变数量的字段
-	reader.LazyQuotes = true
-	header, err := reader.Read()
-	if err != nil {
-		return nil, err
-	}
-	// 去除表头前后空格
-	for i, v := range header {
-		header[i] = strings.TrimSpace(v)
-	}
-	// 标记每一列是否有非空数据,并检测表头是否符合要求
-	columnIsEmpty, err := checkExcelTitle(header, checkTitles)
-	if err != nil {
-		return nil, err
-	}
-	records := [][]string{}
-	for {
-		record, err := reader.Read()
-		if err == io.EOF {
-			break
-		}
-		if err != nil {
-			return nil, err
-		}
-		records = append(records, record)
-		for i, value := range record {
-			if value != "" {
-				columnIsEmpty[i] = false
-			}
-		}
-	}
-	// 根据数据是否为空更新 dstTitleMap
-	for i, empty := range columnIsEmpty {
-		if empty {
-			delete(dstTitleMap, header[i])
-		}
-	}
-	// csv 文件只有一个表单
-	excelSheet := &ExcelSheet{SheetName: "csv"}
-	// 把表头改为映射后的表头
-	for _, title := range header {
-		if dst, ok := dstTitleMap[title]; ok {
-			excelSheet.Header = append(excelSheet.Header, dst)
-		}
-	}
-	totalRow := 0
-	// 处理每一行数据
-	for _, record := range records {
-		mappedData := mapCSVRowData(header, record, dstTitleMap)
-		if len(mappedData) > 0 {
-			rowData := make([]string, 0)
-			for _, title := range excelSheet.Header {
-				if value, ok := mappedData[title]; ok {
-					rowData = append(rowData, value)
-				}
-			}
-			totalRow++
-			excelSheet.Rows = append(excelSheet.Rows, rowData)
-		}
-	}
-	retSheets := make([]*ExcelSheet, 0)
-	retSheets = append(retSheets, excelSheet)
-	retFile := &ExcelFile{}
-	retFile.FileName = fileName
-	retFile.Sheets = retSheets
-	retFile.TotalRow = totalRow
-	return retFile, nil
+// ReadExcelFromBytes 从内存中的数据读取表格数据, 参数同 ReadExcelFromReader
+func ReadExcelFromBytes(data []byte, fileType string, checkTitles []string, dstTitleMap map[string]string,
+	opts ...ReadOption) (*ExcelFile, error) {
+	return ReadExcelFromReader(bytes.NewReader(data), fileType, checkTitles, dstTitleMap, opts...)
 }
 
-// ProcessXLSFile 处理 .xls 文件
-func ProcessXLSFile(fileName string, checkTitles []string, dstTitleMap map[string]string) (
+// ProcessXLSXFile 处理 .xlsx 文件, 提取指定表头数据
+// dstTitleMap 待提取的表头
+// 数据全为空的列会被去掉, 记录在 ExcelFile.Report 中
+func ProcessXLSXFile(fileName string, checkTitles []string, dstTitleMap map[string]string, opts ...ReadOption) (
 	*ExcelFile, error) {
-	workbook, err := xls.Open(fileName, "utf-8")
+	options := newReadOptions(opts...)
+	src, err := openFileSource(fileName, common.FileTypeXlsx, options)
 	if err != nil {
-		logx.Errorf("ProcessXLSFile:%s, to open file: %v", fileName, err)
 		return nil, err
 	}
-	var totalRow int
+	return readExcelFile(newRowReader(fileName, src, checkTitles, dstTitleMap, options))
+}
+
+// readExcelFile 通过 RowReader 读取全部数据, 并去掉数据全为空的列
+func readExcelFile(r *RowReader) (*ExcelFile, error) {
+	defer r.Close()
+	report := &ImportReport{StartTime: time.Now()}
 	retSheets := make([]*ExcelSheet, 0)
-	for i := 0; i < workbook.NumSheets(); i++ {
-		sheet := workbook.GetSheet(i)
-		if sheet == nil {
-			continue
-		}
-		excelSheet := &ExcelSheet{SheetName: sheet.Name}
-		header, err := extractXLSHeader(sheet)
-		if err != nil {
-			return nil, err
+	totalRow := 0
+	var violations []*Violation
+	for r.NextSheet() {
+		sheetStart := time.Now()
+		excelSheet := &ExcelSheet{SheetName: r.SheetName(), HeaderRow: r.HeaderRow(), Merges: r.MergeRanges()}
+		sheetReport := &SheetReport{
+			SheetName:       r.SheetName(),
+			HeaderRow:       r.HeaderRow(),
+			UnmappedColumns: r.UnmappedColumns(),
 		}
-		// 标记每一列是否有非空数据,并检测表头是否符合要求
-		columnIsEmpty, err := checkExcelTitle(header, checkTitles)
-		if err != nil {
-			return nil, err
+		header := r.Header()
+		// 标记每一列是否有非空数据
+		columnIsEmpty := make([]bool, len(header))
+		for i := range columnIsEmpty {
+			columnIsEmpty[i] = true
 		}
-		// 遍历所有行，检测列是否有非空数据
-		for j := 1; j <= int(sheet.MaxRow); j++ {
-			row := sheet.Row(j)
-			for i := 0; i < row.LastCol(); i++ {
-				if row.Col(i) != "" {
+		var rowNums []int
+		for r.Next() {
+			rowNums = append(rowNums, r.RowNum())
+			row := r.Row()
+			for i, value := range row {
+				if value != "" {
 					columnIsEmpty[i] = false
 				}
 			}
+			excelSheet.Rows = append(excelSheet.Rows, row)
+			if cells := r.Cells(); cells != nil {
+				excelSheet.Cells = append(excelSheet.Cells, cells)
+			}
+			violations = append(violations, r.Violations()...)
+		}
+		if r.Err() != nil {
+			break
 		}
-		// 根据数据是否为空更新 dstTitleMap
+		// 去掉数据全为空的列
+		srcHeader := r.SourceHeader()
+		keep := make([]int, 0, len(header))
 		for i, empty := range columnIsEmpty {
 			if empty {
-				delete(dstTitleMap, header[i])
+				sheetReport.DroppedColumns = append(sheetReport.DroppedColumns, srcHeader[r.colIndexes[i]])
+				continue
 			}
+			keep = append(keep, i)
+			excelSheet.Header = append(excelSheet.Header, header[i])
 		}
-		for _, title := range header {
-			if dst, ok := dstTitleMap[title]; ok {
-				excelSheet.Header = append(excelSheet.Header, dst)
+		if len(keep) != len(header) {
+			for j, row := range excelSheet.Rows {
+				rowData := make([]string, 0, len(keep))
+				for _, i := range keep {
+					rowData = append(rowData, row[i])
+				}
+				excelSheet.Rows[j] = rowData
 			}
-		}
-		// 处理每一行数据
-		for j := 1; j <= int(sheet.MaxRow); j++ { // 跳过表头
-			row := sheet.Row(j)
-			mappedData := mapXLSRowData(header, row, dstTitleMap)
-			if len(mappedData) > 0 {
-				rowData := make([]string, 0)
-				for _, title := range excelSheet.Header {
-					if value, ok := mappedData[title]; ok {
-						rowData = append(rowData, value)
-					}
+			for j, cells := range excelSheet.Cells {
+				typed := make([]Cell, 0, len(keep))
+				for _, i := range keep {
+					typed = append(typed, cells[i])
 				}
-				totalRow++
-				excelSheet.Rows = append(excelSheet.Rows, rowData)
+				excelSheet.Cells[j] = typed
+			}
+		}
+		if excelSheet.Cells != nil {
+			excelSheet.ColumnTypes = inferColumnTypes(excelSheet.Cells, len(excelSheet.Header))
+		}
+		if len(r.options.uniqueKey) > 0 {
+			keys, err := r.bindSheetKeys(keep)
+			if err != nil {
+				return nil, err
 			}
+			keys.rowNums = rowNums
+			excelSheet.keys = keys
 		}
+		totalRow += len(excelSheet.Rows)
 		retSheets = append(retSheets, excelSheet)
+		sheetReport.RowCount = len(excelSheet.Rows)
+		sheetReport.SkippedRows = r.SkippedRows()
+		sheetReport.PrecisionLostColumns = r.PrecisionLostColumns()
+		sheetReport.FormulaErrors = r.FormulaErrors()
+		sheetReport.Duration = time.Since(sheetStart)
+		report.Sheets = append(report.Sheets, sheetReport)
 	}
+	if err := r.Err(); err != nil {
+		return nil, err
+	}
+	report.TotalRow = totalRow
+	report.SkippedSheets = r.SkippedSheets()
+	report.Duration = time.Since(report.StartTime)
 	retFile := &ExcelFile{}
-	retFile.FileName = fileName
+	retFile.FileName = r.fileName
 	retFile.Sheets = retSheets
 	retFile.TotalRow = totalRow
-	return retFile, nil
-}
-
-// extractHeader 提取 .xlsx 文件的表头
-func extractHeader(rows []*xlsx.Row) ([]string, error) {
-	if len(rows) == 0 {
-		return nil, fmt.Errorf("no rows found in sheet")
-	}
-	headerRow := rows[0]
-	header := make([]string, len(headerRow.Cells))
-	for i, cell := range headerRow.Cells {
-		header[i] = strings.TrimSpace(cell.String())
+	retFile.Violations = violations
+	retFile.Report = report
+	if len(r.options.uniqueKey) > 0 && !r.options.batch {
+		checkDuplicates([]*ExcelFile{retFile}, r.options.duplicate)
 	}
-	return header, nil
+	return retFile, nil
 }
 
-// extractXLSHeader 提取 .xls 文件的表头
-func extractXLSHeader(sheet *xls.WorkSheet) ([]string, error) {
-	if sheet.MaxRow == 0 {
-		return nil, fmt.Errorf("no rows found in sheet")
-	}
-	headerRow := sheet.Row(0)
-	header := make([]string, headerRow.LastCol())
-	for j := 0; j < headerRow.LastCol(); j++ {
-		header[j] = strings.TrimSpace(headerRow.Col(j))
+// ProcessCSVFile 处理 .csv 文件
+func ProcessCSVFile(fileName string, checkTitles []string, dstTitleMap map[string]string, opts ...ReadOption) (
+	*ExcelFile, error) {
+	options := newReadOptions(opts...)
+	src, err := openFileSource(fileName, common.FileTypeCsv, options)
+	if err != nil {
+		return nil, err
 	}
-	return header, nil
+	return readExcelFile(newRowReader(fileName, src, checkTitles, dstTitleMap, options))
 }
 
-// mapRowData 根据映射关系提取列数据，过滤掉包含空单元格的行，并统一时间格式
-func mapRowData(header []string, cells []*xlsx.Cell, dstTitleMap map[string]string) map[string]string {
-	mappedData := make(map[string]string)
-	for i, cell := range cells {
-		// 跳过没有表头的列
-		if i >= len(header) {
-			continue
-		}
-		srcTitle := header[i]
-		dstTitle, ok := dstTitleMap[srcTitle]
-		if !ok {
-			continue
-		}
-		value := cell.String()
-		if strings.Contains(dstTitle, "时间") {
-			parsedTime, err := timeutil.FormatDate(value, timeutil.DefaultTimeLayout)
-			if err == nil {
-				value = parsedTime
-			}
-		}
-		mappedData[dstTitle] = value
+// ProcessXLSFile 处理 .xls 文件
+func ProcessXLSFile(fileName string, checkTitles []string, dstTitleMap map[string]string, opts ...ReadOption) (
+	*ExcelFile, error) {
+	options := newReadOptions(opts...)
+	src, err := openFileSource(fileName, common.FileTypeXls, options)
+	if err != nil {
+		return nil, err
 	}
-	return mappedData
+	return readExcelFile(newRowReader(fileName, src, checkTitles, dstTitleMap, options))
 }
 
-// mapCSVRowData mapCSVRowData 根据映射关系提取并重命名列数据，过滤掉包含空单元格的行，并统一时间格式（CSV）
-func mapCSVRowData(header []string, record []string, dstTitleMap map[string]string) map[string]string {
-	mappedData := make(map[string]string)
-	for i, value := range record {
-		srcTitle := header[i]
-		dstTitle, ok := dstTitleMap[srcTitle]
-		if !ok {
-			continue
-		}
-		if strings.Contains(dstTitle, "时间") {
-			parsedTime, err := timeutil.FormatDate(value, timeutil.DefaultTimeLayout)
-			if err == nil {
-				value = parsedTime
-			}
-		}
-		mappedData[dstTitle] = value
+// ProcessHTMLFile 处理 html 表格文件, 每个 <table> 作为一个表单
+func ProcessHTMLFile(fileName string, checkTitles []string, dstTitleMap map[string]string, opts ...ReadOption) (
+	*ExcelFile, error) {
+	options := newReadOptions(opts...)
+	src, err := openFileSource(fileName, common.FileTypeHtml, options)
+	if err != nil {
+		return nil, err
 	}
-	return mappedData
+	return readExcelFile(newRowReader(fileName, src, checkTitles, dstTitleMap, options))
 }
 
-// 根据映射关系提取并重命名列数据，过滤掉包含空单元格的行，并统一时间格式（XLS）
-func mapXLSRowData(header []string, row *xls.Row, dstTitleMap map[string]string) map[string]string {
-	mappedData := make(map[string]string)
-	for i := 0; i < row.LastCol(); i++ {
-		value := row.Col(i)
-		srcTitle := header[i]
-		dstTitle, ok := dstTitleMap[srcTitle]
-		if !ok {
-			continue
-		}
-		if strings.Contains(dstTitle, "时间") {
-			parsedTime, err := timeutil.FormatDate(value, timeutil.DefaultTimeLayout)
-			if err == nil {
-				value = parsedTime
-			}
-		}
-		mappedData[dstTitle] = value
+// ProcessODSFile 处理 .ods 文件
+func ProcessODSFile(fileName string, checkTitles []string, dstTitleMap map[string]string, opts ...ReadOption) (
+	*ExcelFile, error) {
+	options := newReadOptions(opts...)
+	src, err := openFileSource(fileName, common.FileTypeOds, options)
+	if err != nil {
+		return nil, err
 	}
-	return mappedData
+	return readExcelFile(newRowReader(fileName, src, checkTitles, dstTitleMap, options))
 }
@@ -0,0 +1,50 @@
+package excelutil
+
+import (
+	"maps"
+	"reflect"
+	"testing"
+)
+
+// 读取时不修改调用方的 dstTitleMap, 数据全为空的列和没有映射的列记录在导入报告中
+func TestReadExcelFileReport(t *testing.T) {
+	rows := [][]string{{"编码", "名称", "规格", "备注"}, {"001", "螺丝", "", "常用"}, {"002", "螺帽", "", ""}}
+	csvContent := "编码,名称,规格,备注\n001,螺丝,,常用\n002,螺帽,,\n"
+	tests := []struct {
+		name     string
+		fileName string
+	}{
+		{"xlsx", writeTestXLSX(t, "material.xlsx", testSheet{name: "物料", rows: rows})},
+		{"csv", writeTestFile(t, "material.csv", csvContent)},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dstTitleMap := map[string]string{"编码": "code", "名称": "name", "规格": "spec", "单位": "unit"}
+			want := maps.Clone(dstTitleMap)
+			file, err := ReadExcelFile(tt.fileName, []string{"编码"}, dstTitleMap,
+				WithTitleAliases(map[string][]string{"名称": {"物料名称"}}))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !reflect.DeepEqual(dstTitleMap, want) {
+				t.Errorf("dstTitleMap = %v, want unchanged %v", dstTitleMap, want)
+			}
+			sheet, report := file.Sheets[0], file.Report.Sheets[0]
+			if want := []string{"code", "name"}; !reflect.DeepEqual(sheet.Header, want) {
+				t.Errorf("Header = %v, want %v", sheet.Header, want)
+			}
+			if want := [][]string{{"001", "螺丝"}, {"002", "螺帽"}}; !reflect.DeepEqual(sheet.Rows, want) {
+				t.Errorf("Rows = %v, want %v", sheet.Rows, want)
+			}
+			if want := []string{"规格"}; !reflect.DeepEqual(report.DroppedColumns, want) {
+				t.Errorf("DroppedColumns = %v, want %v", report.DroppedColumns, want)
+			}
+			if want := []string{"备注"}; !reflect.DeepEqual(report.UnmappedColumns, want) {
+				t.Errorf("UnmappedColumns = %v, want %v", report.UnmappedColumns, want)
+			}
+			if report.RowCount != 2 || file.Report.TotalRow != 2 {
+				t.Errorf("RowCount = %d, TotalRow = %d, want 2", report.RowCount, file.Report.TotalRow)
+			}
+		})
+	}
+}
@@ -0,0 +1,196 @@
+package excelutil
+
+import (
+	"archive/zip"
+	"bufio"
+	"bytes"
+	"fmt"
+	"io"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/xuri/excelize/v2"
+)
+
+// MergeRange 合并单元格区域, 使用原始表单中的位置, 行号和列号都从 1 开始, 包含结束的行和列
+type MergeRange struct {
+	Ref      string // 区域, 如 "A2:A11"
+	StartRow int    // 起始行号
+	StartCol int    // 起始列号
+	EndRow   int    // 结束行号
+	EndCol   int    // 结束列号
+}
+
+// newMergeRange 根据行列号创建合并区域
+func newMergeRange(startRow, startCol, endRow, endCol int) MergeRange {
+	start, _ := excelize.CoordinatesToCellName(startCol, startRow)
+	end, _ := excelize.CoordinatesToCellName(endCol, endRow)
+	return MergeRange{
+		Ref:      fmt.Sprintf("%s:%s", start, end),
+		StartRow: startRow,
+		StartCol: startCol,
+		EndRow:   endRow,
+		EndCol:   endCol,
+	}
+}
+
+// xlsxMergeRanges 读取 .xlsx 表单的合并单元格区域
+func xlsxMergeRanges(f *excelize.File, sheet string) ([]MergeRange, error) {
+	cells, err := f.GetMergeCells(sheet)
+	if err != nil {
+		return nil, err
+	}
+	ranges := make([]MergeRange, 0, len(cells))
+	for _, cell := range cells {
+		startCol, startRow, err := excelize.CellNameToCoordinates(cell.GetStartAxis())
+		if err != nil {
+			return nil, err
+		}
+		endCol, endRow, err := excelize.CellNameToCoordinates(cell.GetEndAxis())
+		if err != nil {
+			return nil, err
+		}
+		ranges = append(ranges, newMergeRange(startRow, startCol, endRow, endCol))
+	}
+	return ranges, nil
+}
+
+// mergeRefRegexp 匹配 <mergeCell> 的 ref 属性
+var mergeRefRegexp = regexp.MustCompile(`\sref="([A-Za-z]+\d+(?::[A-Za-z]+\d+)?)"`)
+
+// zipMergeRanges 流式读取 zip 中表单 XML 的合并单元格区域, 不加载整个表单
+func zipMergeRanges(f *zip.File) ([]MergeRange, error) {
+	rc, err := f.Open()
+	if err != nil {
+		return nil, err
+	}
+	defer rc.Close()
+	return scanMergeRanges(rc)
+}
+
+// scanMergeRanges 逐个检查 XML 标签, 只解析 <mergeCell ref="A1:B2"/>, 支持带命名空间前缀的标签;
+// 表单数据中的文本不会包含 '<', 不会误判
+func scanMergeRanges(r io.Reader) ([]MergeRange, error) {
+	br := bufio.NewReaderSize(r, 64<<10)
+	var ranges []MergeRange
+	for {
+		if _, err := br.ReadSlice('<'); err != nil {
+			if err == bufio.ErrBufferFull {
+				continue
+			}
+			if err == io.EOF {
+				return ranges, nil
+			}
+			return nil, err
+		}
+		head, _ := br.Peek(32)
+		if i := bytes.IndexByte(head, ':'); i >= 0 && !bytes.ContainsAny(head[:i], " />") {
+			head = head[i+1:]
+		}
+		const name = "mergeCell"
+		if !bytes.HasPrefix(head, []byte(name)) || len(head) <= len(name) ||
+			!strings.ContainsRune(" \t\r\n/>", rune(head[len(name)])) {
+			continue
+		}
+		tag, err := br.ReadSlice('>')
+		if err != nil {
+			return nil, fmt.Errorf("合并单元格数据不完整:%v", err)
+		}
+		match := mergeRefRegexp.FindSubmatch(tag)
+		if match == nil {
+			continue
+		}
+		start, end, ok := strings.Cut(string(match[1]), ":")
+		if !ok {
+			end = start
+		}
+		startCol, startRow, err := excelize.CellNameToCoordinates(start)
+		if err != nil {
+			return nil, err
+		}
+		endCol, endRow, err := excelize.CellNameToCoordinates(end)
+		if err != nil {
+			return nil, err
+		}
+		ranges = append(ranges, newMergeRange(startRow, startCol, endRow, endCol))
+	}
+}
+
+// mergeFiller 按行把合并区域左上角单元格的值填充到区域内的其他空单元格, 需要按行号顺序读取每一行
+type mergeFiller struct {
+	ranges []MergeRange // 按起始行号排序
+	next   int          // 下一个还没有开始的区域
+	active []*activeMerge
+}
+
+// activeMerge 当前行所在的合并区域
+type activeMerge struct {
+	MergeRange
+	value string
+}
+
+func newMergeFiller(ranges []MergeRange) *mergeFiller {
+	if len(ranges) == 0 {
+		return nil
+	}
+	sorted := append([]MergeRange(nil), ranges...)
+	sort.Slice(sorted, func(i, j int) bool {
+		return sorted[i].StartRow < sorted[j].StartRow
+	})
+	return &mergeFiller{ranges: sorted}
+}
+
+// observe 读取到第 rowNum 行, 记录从这一行开始的区域的值
+func (f *mergeFiller) observe(rowNum int, cells []string) {
+	if f == nil {
+		return
+	}
+	active := f.active[:0]
+	for _, m := range f.active {
+		if m.EndRow >= rowNum {
+			active = append(active, m)
+		}
+	}
+	f.active = active
+	for ; f.next < len(f.ranges) && f.ranges[f.next].StartRow <= rowNum; f.next++ {
+		m := &activeMerge{MergeRange: f.ranges[f.next]}
+		if m.EndRow < rowNum {
+			continue
+		}
+		if m.StartRow == rowNum && m.StartCol <= len(cells) {
+			m.value = cells[m.StartCol-1]
+		}
+		f.active = append(f.active, m)
+	}
+}
+
+// fill 填充 observe 过的当前行, 单元格不够时补齐
+func (f *mergeFiller) fill(cells []string) []string {
+	if f == nil {
+		return cells
+	}
+	for _, m := range f.active {
+		if m.value == "" {
+			continue
+		}
+		for len(cells) < m.EndCol {
+			cells = append(cells, "")
+		}
+		for col := m.StartCol - 1; col < m.EndCol; col++ {
+			if cells[col] == "" {
+				cells[col] = m.value
+			}
+		}
+	}
+	return cells
+}
+
+// fillMergedRows 填充整个表单的原始数据, rows[i] 为第 i+1 行
+func fillMergedRows(rows [][]string, ranges []MergeRange) {
+	filler := newMergeFiller(ranges)
+	for i := range rows {
+		filler.observe(i+1, rows[i])
+		rows[i] = filler.fill(rows[i])
+	}
+}
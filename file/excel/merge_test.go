@@ -0,0 +1,72 @@
+package excelutil
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestScanMergeRanges(t *testing.T) {
+	tests := []struct {
+		name string
+		xml  string
+		want []MergeRange
+	}{
+		{
+			"普通标签",
+			`<worksheet><sheetData><row r="1"><c r="A1"><v>1</v></c></row></sheetData>` +
+				`<mergeCells count="2"><mergeCell ref="A1:B1"/><mergeCell ref="C2:C5"/></mergeCells></worksheet>`,
+			[]MergeRange{newMergeRange(1, 1, 1, 2), newMergeRange(2, 3, 5, 3)},
+		},
+		{
+			"命名空间前缀和换行",
+			"<x:worksheet><x:mergeCells><x:mergeCell\n ref=\"AA10:AB12\"></x:mergeCell></x:mergeCells></x:worksheet>",
+			[]MergeRange{newMergeRange(10, 27, 12, 28)},
+		},
+		{
+			"单个单元格",
+			`<mergeCells><mergeCell ref="D4"/></mergeCells>`,
+			[]MergeRange{newMergeRange(4, 4, 4, 4)},
+		},
+		{
+			"mergeCells 不是区域",
+			`<mergeCells count="0"></mergeCells><mergeCellX ref="A1:B2"/>`,
+			nil,
+		},
+		{
+			// 超过缓冲区大小的单元格数据
+			"长数据",
+			`<sheetData><row><c t="inlineStr"><is><t>` + strings.Repeat("a", 100<<10) + `</t></is></c></row></sheetData>` +
+				`<mergeCells><mergeCell ref="A1:A2"/></mergeCells>`,
+			[]MergeRange{newMergeRange(1, 1, 2, 1)},
+		},
+	}
+	for _, tt := range tests {
+		got, err := scanMergeRanges(strings.NewReader(tt.xml))
+		if err != nil || !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("%s: scanMergeRanges() = %v, %v, want %v", tt.name, got, err, tt.want)
+		}
+	}
+	if _, err := scanMergeRanges(strings.NewReader(`<mergeCell ref="A1:B2"`)); err == nil {
+		t.Errorf("scanMergeRanges() incomplete tag error = nil, want error")
+	}
+}
+
+func TestFillMergedRows(t *testing.T) {
+	rows := [][]string{
+		{"规格", "", "日期"},
+		{"A", "1", "2023-07-16"},
+		{"", "2"},
+		{"", "3", ""},
+	}
+	fillMergedRows(rows, []MergeRange{newMergeRange(1, 1, 1, 2), newMergeRange(2, 1, 4, 1), newMergeRange(2, 3, 3, 3)})
+	want := [][]string{
+		{"规格", "规格", "日期"},
+		{"A", "1", "2023-07-16"},
+		{"A", "2", "2023-07-16"},
+		{"A", "3", ""},
+	}
+	if !reflect.DeepEqual(rows, want) {
+		t.Errorf("fillMergedRows() = %q, want %q", rows, want)
+	}
+}
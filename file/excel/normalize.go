@@ -0,0 +1,173 @@
+package excelutil
+
+import (
+	"fmt"
+	"strings"
+	"sync"
+	"unicode"
+
+	"golang.org/x/text/width"
+)
+
+// 内置的规范化方式
+const (
+	NormalizeWidth  = "width"  // 全角字母、数字、符号和空格转换为半角
+	NormalizeSpace  = "space"  // 不换行空格等空白转换为普通空格, 去掉零宽字符, 去掉两端空白并合并连续空白(包括换行)
+	NormalizeNumber = "number" // 去掉货币符号和千分位, 百分比转换为小数, 如 "¥1,234.50" 为 "1234.50", "12.5%" 为 "0.125"
+	NormalizeBool   = "bool"   // 是/否、Y/N、true/false、1/0 等转换为 TRUE/FALSE
+	NormalizeUpper  = "upper"  // 转换为大写
+	NormalizeLower  = "lower"  // 转换为小写
+)
+
+// Normalizer 单元格值的规范化函数, 只对非空的值调用; 无法规范化时返回错误, 读取时保留原值并记录到 Violations
+type Normalizer func(value string) (string, error)
+
+var (
+	normalizersMu sync.RWMutex
+	normalizers   = map[string]Normalizer{
+		NormalizeWidth:  normalizeWidth,
+		NormalizeSpace:  normalizeSpace,
+		NormalizeNumber: normalizeNumber,
+		NormalizeBool:   normalizeBool,
+		NormalizeUpper:  func(value string) (string, error) { return strings.ToUpper(value), nil },
+		NormalizeLower:  func(value string) (string, error) { return strings.ToLower(value), nil },
+	}
+)
+
+// RegisterNormalizer 注册自定义的规范化方式, 之后可以在 WithNormalizers 和导入配置中按名称使用; 同名时覆盖
+func RegisterNormalizer(name string, fn Normalizer) error {
+	if name == "" || fn == nil {
+		return fmt.Errorf("规范化方式的名称和函数不能为空")
+	}
+	normalizersMu.Lock()
+	defer normalizersMu.Unlock()
+	normalizers[name] = fn
+	return nil
+}
+
+// lookupNormalizers 按名称查找规范化函数, 名称未注册时返回错误
+func lookupNormalizers(names []string) ([]Normalizer, error) {
+	normalizersMu.RLock()
+	defer normalizersMu.RUnlock()
+	fns := make([]Normalizer, 0, len(names))
+	for _, name := range names {
+		fn, ok := normalizers[name]
+		if !ok {
+			return nil, fmt.Errorf("规范化方式%s不存在", name)
+		}
+		fns = append(fns, fn)
+	}
+	return fns, nil
+}
+
+// normalizeValue 依次执行规范化函数, 值为空后不再继续
+func normalizeValue(value string, fns []Normalizer) (string, error) {
+	for _, fn := range fns {
+		if value == "" {
+			break
+		}
+		normalized, err := fn(value)
+		if err != nil {
+			return value, err
+		}
+		value = normalized
+	}
+	return value, nil
+}
+
+func normalizeWidth(value string) (string, error) {
+	return width.Fold.String(value), nil
+}
+
+// zeroWidthRunes 零宽字符, 常见于从网页和聊天工具复制的数据
+const zeroWidthRunes = "\u200b\u200c\u200d\u2060\ufeff"
+
+func normalizeSpace(value string) (string, error) {
+	value = strings.Map(func(r rune) rune {
+		switch {
+		case strings.ContainsRune(zeroWidthRunes, r):
+			return -1
+		case unicode.IsSpace(r):
+			return ' '
+		}
+		return r
+	}, value)
+	return strings.Join(strings.Fields(value), " "), nil
+}
+
+// currencySymbols 数字前后可能出现的货币符号和单位
+var currencySymbols = []string{"¥", "￥", "$", "＄", "€", "£", "RMB", "CNY", "USD", "元"}
+
+func normalizeNumber(value string) (string, error) {
+	number := strings.TrimSpace(value)
+	negative := false
+	if strings.HasPrefix(number, "(") && strings.HasSuffix(number, ")") {
+		// 会计格式的负数, 如 (1,234.00)
+		number, negative = strings.TrimSpace(number[1:len(number)-1]), true
+	}
+	percent := strings.HasSuffix(number, "%")
+	number = strings.TrimSpace(strings.TrimSuffix(number, "%"))
+	sign := ""
+	if strings.HasPrefix(number, "-") || strings.HasPrefix(number, "+") {
+		sign, number = number[:1], strings.TrimSpace(number[1:])
+	}
+	for _, symbol := range currencySymbols {
+		number = strings.TrimSpace(strings.TrimSuffix(strings.TrimPrefix(number, symbol), symbol))
+	}
+	if sign == "" && strings.HasPrefix(number, "-") {
+		// 负号在货币符号之后, 如 ¥-12.00
+		sign, number = "-", number[1:]
+	}
+	if thousandsRegexp.MatchString(number) {
+		number = strings.ReplaceAll(number, ",", "")
+	}
+	if number == "" || strings.ContainsAny(number, "+-") || !decimalRegexp.MatchString(number) {
+		return value, fmt.Errorf("不是有效的数字")
+	}
+	if percent {
+		number = shiftDecimal(number, 2)
+	}
+	if negative {
+		sign = "-"
+	}
+	if sign == "+" || strings.Trim(number, "0.") == "" {
+		sign = ""
+	}
+	return sign + number, nil
+}
+
+// shiftDecimal 把不带符号的小数文本的小数点左移 n 位, 不经过浮点数, 避免 33.3% 变成 0.33299999999999996
+func shiftDecimal(number string, n int) string {
+	intPart, fracPart, _ := strings.Cut(number, ".")
+	if len(intPart) < n+1 {
+		intPart = strings.Repeat("0", n+1-len(intPart)) + intPart
+	}
+	intPart, fracPart = intPart[:len(intPart)-n], intPart[len(intPart)-n:]+fracPart
+	intPart = strings.TrimLeft(intPart, "0")
+	if intPart == "" {
+		intPart = "0"
+	}
+	fracPart = strings.TrimRight(fracPart, "0")
+	if fracPart == "" {
+		return intPart
+	}
+	return intPart + "." + fracPart
+}
+
+// boolValues 布尔值的常见写法, 按小写比较
+var boolValues = map[string]bool{
+	"是": true, "否": false, "y": true, "n": false, "yes": true, "no": false, "true": true, "false": false,
+	"1": true, "0": false, "对": true, "错": false, "有": true, "无": false, "√": true, "×": false,
+	"✓": true, "✗": false,
+}
+
+func normalizeBool(value string) (string, error) {
+	b, ok := boolValues[strings.ToLower(strings.TrimSpace(value))]
+	if !ok {
+		return value, fmt.Errorf("不是有效的布尔值")
+	}
+	if b {
+		return "TRUE", nil
+	}
+	return "FALSE", nil
+}
@@ -0,0 +1,93 @@
+package excelutil
+
+import (
+	"reflect"
+	"testing"
+
+	"go_file/common"
+)
+
+func TestNormalizers(t *testing.T) {
+	tests := []struct {
+		name    string
+		fn      Normalizer
+		value   string
+		want    string
+		wantErr bool
+	}{
+		{"全角", normalizeWidth, "ＡＢ１２３　－", "AB123 -", false},
+		{"空白", normalizeSpace, "  螺丝​\n M3\t ", "螺丝 M3", false},
+		{"货币和千分位", normalizeNumber, "¥1,234.50", "1234.50", false},
+		{"货币单位在后", normalizeNumber, "1,234元", "1234", false},
+		{"负号在货币符号之后", normalizeNumber, "¥-12.00", "-12.00", false},
+		{"会计格式负数", normalizeNumber, "(1,234.00)", "-1234.00", false},
+		{"百分比", normalizeNumber, "33.3%", "0.333", false},
+		{"小于一的百分比", normalizeNumber, "0.5%", "0.005", false},
+		{"正号", normalizeNumber, "+5", "5", false},
+		{"负零", normalizeNumber, "-0.00", "0.00", false},
+		{"千分位位置不对", normalizeNumber, "1,23", "1,23", true},
+		{"不是数字", normalizeNumber, "约100", "约100", true},
+		{"是", normalizeBool, " 是 ", "TRUE", false},
+		{"N", normalizeBool, "N", "FALSE", false},
+		{"无法识别的布尔值", normalizeBool, "也许", "也许", true},
+	}
+	for _, tt := range tests {
+		got, err := tt.fn(tt.value)
+		if got != tt.want || (err != nil) != tt.wantErr {
+			t.Errorf("%s: normalize(%q) = %q, %v, want %q, error %v", tt.name, tt.value, got, err, tt.want, tt.wantErr)
+		}
+	}
+}
+
+func TestNormalizeValue(t *testing.T) {
+	fns, err := lookupNormalizers([]string{NormalizeWidth, NormalizeSpace, NormalizeUpper})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, err := normalizeValue("  ｍ３ 螺丝 ", fns); got != "M3 螺丝" || err != nil {
+		t.Errorf("normalizeValue() = %q, %v, want %q", got, err, "M3 螺丝")
+	}
+	if _, err := lookupNormalizers([]string{"unknown"}); err == nil {
+		t.Errorf("lookupNormalizers() unknown name error = nil, want error")
+	}
+}
+
+// 重复行展开的多行共用同一个切片, 规范化不能修改读取器中的原始数据;
+// 重复次数超过查找表头时扫描的行数, 后面的行直接从读取器读取
+func TestNormalizeRepeatedODSRows(t *testing.T) {
+	if err := RegisterNormalizer("test-exclaim", func(value string) (string, error) { return value + "!", nil }); err != nil {
+		t.Fatal(err)
+	}
+	r := newTestODS(t, `<?xml version="1.0" encoding="UTF-8"?>
+<office:document-content xmlns:office="urn:oasis:names:tc:opendocument:xmlns:office:1.0"
+	xmlns:table="urn:oasis:names:tc:opendocument:xmlns:table:1.0"
+	xmlns:text="urn:oasis:names:tc:opendocument:xmlns:text:1.0">
+<office:body><office:spreadsheet><table:table table:name="Sheet1">
+	<table:table-row><table:table-cell><text:p>name</text:p></table:table-cell></table:table-row>
+	<table:table-row table:number-rows-repeated="15"><table:table-cell><text:p>m</text:p></table:table-cell></table:table-row>
+</table:table></office:spreadsheet></office:body>
+</office:document-content>`)
+	options := newReadOptions(WithNormalizers("name", "test-exclaim"))
+	src, err := openSheetSource(r, r.Size(), "test.ods", common.FileTypeOds, nil, options)
+	if err != nil {
+		t.Fatal(err)
+	}
+	reader := newRowReader("test.ods", src, nil, map[string]string{"name": "Name"}, options)
+	defer reader.Close()
+	var rows [][]string
+	for reader.NextSheet() {
+		for reader.Next() {
+			rows = append(rows, reader.Row())
+		}
+	}
+	if err = reader.Err(); err != nil {
+		t.Fatal(err)
+	}
+	want := make([][]string, 15)
+	for i := range want {
+		want[i] = []string{"m!"}
+	}
+	if !reflect.DeepEqual(rows, want) {
+		t.Errorf("rows = %q, want %q", rows, want)
+	}
+}
@@ -0,0 +1,77 @@
+package excelutil
+
+import (
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/xuri/excelize/v2"
+)
+
+// maxExactDigits Excel 数字的有效位数, 超过的部分保存为 0
+const maxExactDigits = 15
+
+// scientificPattern 科学计数法表示的数字, 如 6.90123E+12
+var scientificPattern = regexp.MustCompile(`^[+-]?\d+(\.\d+)?[eE][+-]?\d+$`)
+
+// isScientific 是否为科学计数法表示的数字
+func isScientific(value string) bool {
+	return scientificPattern.MatchString(value)
+}
+
+// plainNumber 把科学计数法表示的数字转换为完整的整数或小数, 如 1.10101199001011E+17 转换为 110101199001011000;
+// 其他值原样返回
+func plainNumber(value string) string {
+	if !isScientific(value) {
+		return value
+	}
+	f, err := strconv.ParseFloat(value, 64)
+	if err != nil {
+		return value
+	}
+	return strconv.FormatFloat(f, 'f', -1, 64)
+}
+
+// precisionLost 判断数字是否已经丢失精度: 仍为科学计数法, 或超过 15 位的整数在第 15 位之后全为 0.
+// 身份证号、条码等长数字以数字格式保存在 Excel 中时会出现这种情况
+func precisionLost(value string) bool {
+	if isScientific(value) {
+		return true
+	}
+	digits := strings.TrimLeft(strings.TrimPrefix(value, "-"), "0")
+	if len(digits) <= maxExactDigits || !isDigits(digits) {
+		return false
+	}
+	return strings.Trim(digits[maxExactDigits:], "0") == ""
+}
+
+// expandScientific 把 .xlsx 表单中以科学计数法显示的数字替换为原始值的完整数字, rows 为 GetRows 的结果;
+// 文本单元格即使形如科学计数法(如零件编码 1E5)也保持原样
+func expandScientific(f *excelize.File, sheet string, rows [][]string) error {
+	var raw [][]string
+	for i, row := range rows {
+		for j, value := range row {
+			if !isScientific(value) {
+				continue
+			}
+			cell, err := excelize.CoordinatesToCellName(j+1, i+1)
+			if err != nil {
+				return err
+			}
+			if cellType, err := f.GetCellType(sheet, cell); err != nil {
+				return err
+			} else if !(xlsxCellAttr{cellType: cellType}).numeric() {
+				continue
+			}
+			if raw == nil {
+				if raw, err = f.GetRows(sheet, excelize.Options{RawCellValue: true}); err != nil {
+					return err
+				}
+			}
+			if i < len(raw) && j < len(raw[i]) {
+				row[j] = plainNumber(raw[i][j])
+			}
+		}
+	}
+	return nil
+}
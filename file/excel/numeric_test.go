@@ -0,0 +1,110 @@
+package excelutil
+
+import (
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"github.com/xuri/excelize/v2"
+)
+
+func TestPrecisionLost(t *testing.T) {
+	tests := []struct {
+		value string
+		want  bool
+	}{
+		{"6.90123E+12", true},
+		{"110101199001011000", true},
+		{"110101199001011234", false},
+		{"123456789012345", false},
+		{"1E5", true},
+		{"编码", false},
+	}
+	for _, tt := range tests {
+		if got := precisionLost(tt.value); got != tt.want {
+			t.Errorf("precisionLost(%q) = %v, want %v", tt.value, got, tt.want)
+		}
+	}
+}
+
+// writeNumericXLSX 生成表头为 编码、数量 的 .xlsx, 第 2 行编码为形如科学计数法的文本,
+// 第 3 行编码为以常规格式保存、显示为科学计数法的长数字
+func writeNumericXLSX(t *testing.T) string {
+	t.Helper()
+	f := excelize.NewFile()
+	defer f.Close()
+	rows := [][]any{{"编码", "数量"}, {"1E5", 1}, {1.10101199001011e17, 2}}
+	for i, row := range rows {
+		cell, _ := excelize.CoordinatesToCellName(1, i+1)
+		if err := f.SetSheetRow("Sheet1", cell, &row); err != nil {
+			t.Fatal(err)
+		}
+	}
+	path := filepath.Join(t.TempDir(), "numeric.xlsx")
+	if err := f.SaveAs(path); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func TestScientificCells(t *testing.T) {
+	titleMap := map[string]string{"编码": "code", "数量": "qty"}
+	xlsxPath := writeNumericXLSX(t)
+	csvPath := writeTestFile(t, "numeric.csv", "编码,数量\n1E5,1\n6.90123E+12,2\n")
+	tests := []struct {
+		name     string
+		read     func() (*ExcelFile, error)
+		want     [][]string
+		wantLost []string
+	}{
+		{
+			"xlsx 文本单元格保持原样",
+			func() (*ExcelFile, error) { return ReadExcelFile(xlsxPath, nil, titleMap) },
+			[][]string{{"1E5", "1"}, {"110101199001011000", "2"}},
+			[]string{"编码"},
+		},
+		{
+			"xlsx 文本列",
+			func() (*ExcelFile, error) { return ReadExcelFile(xlsxPath, nil, titleMap, WithTextColumns("编码")) },
+			[][]string{{"1E5", "1"}, {"110101199001011000", "2"}},
+			[]string{"编码"},
+		},
+		{
+			"csv",
+			func() (*ExcelFile, error) { return ReadExcelFile(csvPath, nil, titleMap) },
+			[][]string{{"1E5", "1"}, {"6.90123E+12", "2"}},
+			[]string{"编码"},
+		},
+		{
+			"csv 文本列保持文件中的文本",
+			func() (*ExcelFile, error) { return ReadExcelFile(csvPath, nil, titleMap, WithTextColumns("编码")) },
+			[][]string{{"1E5", "1"}, {"6.90123E+12", "2"}},
+			[]string{"编码"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			file, err := tt.read()
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got := file.Sheets[0].Rows; !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("rows = %q, want %q", got, tt.want)
+			}
+			if got := file.Report.Sheets[0].PrecisionLostColumns; !reflect.DeepEqual(got, tt.wantLost) {
+				t.Errorf("PrecisionLostColumns = %q, want %q", got, tt.wantLost)
+			}
+		})
+	}
+}
+
+func TestOpenExFileScientificCells(t *testing.T) {
+	file, err := OpenExFile(writeNumericXLSX(t))
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := [][]string{{"编码", "数量"}, {"1E5", "1"}, {"110101199001011000", "2"}}
+	if got := file.Sheets[0].Rows; !reflect.DeepEqual(got, want) {
+		t.Errorf("rows = %q, want %q", got, want)
+	}
+}
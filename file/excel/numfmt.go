@@ -0,0 +1,121 @@
+package excelutil
+
+import (
+	"strconv"
+	"strings"
+	"time"
+
+	"go_file/utils/timeutil"
+
+	"github.com/xuri/excelize/v2"
+)
+
+// TimeOnlyLayout 只有时间的输出格式
+const TimeOnlyLayout = "15:04:05"
+
+// customNumFmtID 自定义数字格式的起始编号, 小于该值的为内置格式
+const customNumFmtID = 164
+
+// builtInDateLayouts 内置的日期时间数字格式对应的输出格式
+var builtInDateLayouts = map[int]string{
+	14: DateOnlyLayout, 15: DateOnlyLayout, 16: DateOnlyLayout, 17: DateOnlyLayout,
+	18: TimeOnlyLayout, 19: TimeOnlyLayout, 20: TimeOnlyLayout, 21: TimeOnlyLayout,
+	22: timeutil.DefaultTimeLayout,
+	27: DateOnlyLayout, 28: DateOnlyLayout, 29: DateOnlyLayout, 30: DateOnlyLayout, 31: DateOnlyLayout,
+	32: TimeOnlyLayout, 33: TimeOnlyLayout, 34: TimeOnlyLayout, 35: TimeOnlyLayout,
+	36: DateOnlyLayout,
+	45: TimeOnlyLayout, 46: TimeOnlyLayout, 47: TimeOnlyLayout,
+	50: DateOnlyLayout, 51: DateOnlyLayout, 52: DateOnlyLayout, 53: DateOnlyLayout, 54: DateOnlyLayout,
+	55: DateOnlyLayout, 56: DateOnlyLayout, 57: DateOnlyLayout, 58: DateOnlyLayout,
+}
+
+// builtInNumFmtCodes 常用内置数字格式的格式代码, 其余内置格式和区域设置有关
+var builtInNumFmtCodes = map[int]string{
+	1: "0", 2: "0.00", 3: "#,##0", 4: "#,##0.00",
+	9: "0%", 10: "0.00%", 11: "0.00E+00", 12: "# ?/?", 13: "# ??/??",
+	14: "mm-dd-yy", 15: "d-mmm-yy", 16: "d-mmm", 17: "mmm-yy",
+	18: "h:mm AM/PM", 19: "h:mm:ss AM/PM", 20: "h:mm", 21: "h:mm:ss", 22: "m/d/yy h:mm",
+	37: "#,##0 ;(#,##0)", 38: "#,##0 ;[Red](#,##0)", 39: "#,##0.00 ;(#,##0.00)", 40: "#,##0.00 ;[Red](#,##0.00)",
+	45: "mm:ss", 46: "[h]:mm:ss", 47: "mm:ss.0", 48: "##0.0E+0", 49: "@",
+}
+
+// xlsxDateOptions 读取 .xlsx 时内置日期格式统一输出为 "yyyy-mm-dd hh:mm:ss" 形式, 便于解析
+var xlsxDateOptions = excelize.Options{
+	ShortDatePattern: "yyyy-mm-dd",
+	LongDatePattern:  "yyyy-mm-dd",
+	LongTimePattern:  "hh:mm:ss",
+}
+
+// dateFormatLayout 返回数字格式对应的日期输出格式, 不是日期时间格式时返回空字符串
+func dateFormatLayout(id int, code string) string {
+	if id < customNumFmtID {
+		return builtInDateLayouts[id]
+	}
+	section := strings.ToLower(stripFormatLiterals(code))
+	// 只看第一段, 其余段用于负数和零
+	if i := strings.IndexByte(section, ';'); i >= 0 {
+		section = section[:i]
+	}
+	// 含有数字或文本占位符的不是日期格式
+	if strings.ContainsAny(section, "0#?@") {
+		return ""
+	}
+	// h:mm 中的 m 是分钟, 有 y 或 d 时才有日期部分
+	hasDate := strings.ContainsAny(section, "yd")
+	hasTime := strings.ContainsAny(section, "hs")
+	switch {
+	case hasDate && hasTime:
+		return timeutil.DefaultTimeLayout
+	case hasTime:
+		return TimeOnlyLayout
+	case hasDate || strings.ContainsRune(section, 'm'):
+		return DateOnlyLayout
+	}
+	return ""
+}
+
+// stripFormatLiterals 去掉数字格式中的引号文本、转义字符和方括号中的颜色、区域设置,
+// 保留 [h]、[mm]、[ss] 这样的经过时间
+func stripFormatLiterals(code string) string {
+	var b strings.Builder
+	for i := 0; i < len(code); i++ {
+		switch c := code[i]; c {
+		case '"':
+			end := strings.IndexByte(code[i+1:], '"')
+			if end < 0 {
+				return b.String()
+			}
+			i += end + 1
+		case '\\', '_', '*':
+			// 跳过下一个字符
+			i++
+		case '[':
+			end := strings.IndexByte(code[i+1:], ']')
+			if end < 0 {
+				return b.String()
+			}
+			if inner := strings.ToLower(code[i+1 : i+1+end]); strings.Trim(inner, "hms") == "" {
+				b.WriteString(inner)
+			}
+			i += end + 1
+		default:
+			b.WriteByte(c)
+		}
+	}
+	return b.String()
+}
+
+// serialToTime 把 Excel 日期序列号转换为时间, date1904 表示使用 1904 日期系统
+func serialToTime(serial float64, date1904 bool) (time.Time, error) {
+	return excelize.ExcelDateToTime(serial, date1904)
+}
+
+// formatNumber 按数字格式输出数值, 日期时间格式转换为对应的日期字符串, 其他格式输出原始数值
+func formatNumber(value float64, date1904 bool, id int, code string) string {
+	if layout := dateFormatLayout(id, code); layout != "" {
+		if t, err := serialToTime(value, date1904); err == nil {
+			return t.Format(layout)
+		}
+	}
+	return strconv.FormatFloat(value, 'f', -1, 64)
+}
@@ -0,0 +1,65 @@
+package excelutil
+
+import (
+	"testing"
+
+	"go_file/utils/timeutil"
+)
+
+func TestDateFormatLayout(t *testing.T) {
+	tests := []struct {
+		id   int
+		code string
+		want string
+	}{
+		{0, "General", ""},
+		{2, "0.00", ""},
+		{14, "", DateOnlyLayout},
+		{20, "", TimeOnlyLayout},
+		{22, "", timeutil.DefaultTimeLayout},
+		{49, "@", ""},
+		{164, "yyyy/mm/dd", DateOnlyLayout},
+		{164, "yyyy\"年\"m\"月\"d\"日\"", DateOnlyLayout},
+		{164, "[$-804]yyyy年m月d日", DateOnlyLayout},
+		{164, "yyyy-mm-dd hh:mm:ss", timeutil.DefaultTimeLayout},
+		{164, "h:mm", TimeOnlyLayout},
+		{164, "[h]:mm:ss", TimeOnlyLayout},
+		{164, "mmm-yy", DateOnlyLayout},
+		{164, "[Red]yyyy/m/d;@", DateOnlyLayout},
+		{164, "0.00_);[Red](0.00)", ""},
+		{164, "#,##0\"天\"", ""},
+		{164, "\"d\"0", ""},
+		{164, "\\d0", ""},
+		{164, "@", ""},
+		{164, "", ""},
+	}
+	for _, tt := range tests {
+		if got := dateFormatLayout(tt.id, tt.code); got != tt.want {
+			t.Errorf("dateFormatLayout(%d, %q) = %q, want %q", tt.id, tt.code, got, tt.want)
+		}
+	}
+}
+
+func TestFormatNumber(t *testing.T) {
+	tests := []struct {
+		value    float64
+		date1904 bool
+		id       int
+		code     string
+		want     string
+	}{
+		{45123, false, 14, "", "2023-07-16"},
+		{43661, true, 14, "", "2023-07-16"},
+		{45123.5, false, 22, "", "2023-07-16 12:00:00"},
+		{0.75, false, 20, "", "18:00:00"},
+		{45123, false, 164, "yyyy/mm/dd", "2023-07-16"},
+		{1234.5, false, 4, "", "1234.5"},
+		{0.125, false, 10, "", "0.125"},
+		{-1, false, 14, "", "-1"},
+	}
+	for _, tt := range tests {
+		if got := formatNumber(tt.value, tt.date1904, tt.id, tt.code); got != tt.want {
+			t.Errorf("formatNumber(%v, %v, %d, %q) = %q, want %q", tt.value, tt.date1904, tt.id, tt.code, got, tt.want)
+		}
+	}
+}
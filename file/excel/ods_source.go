@@ -0,0 +1,439 @@
+package excelutil
+
+import (
+	"archive/zip"
+	"encoding/xml"
+	"errors"
+	"io"
+	"strconv"
+	"strings"
+	"time"
+
+	"go_file/utils/timeutil"
+)
+
+// OpenDocument 命名空间
+const (
+	odsTableNS  = "urn:oasis:names:tc:opendocument:xmlns:table:1.0"
+	odsOfficeNS = "urn:oasis:names:tc:opendocument:xmlns:office:1.0"
+	odsTextNS   = "urn:oasis:names:tc:opendocument:xmlns:text:1.0"
+	odsStyleNS  = "urn:oasis:names:tc:opendocument:xmlns:style:1.0"
+)
+
+// odsMaxColumns 一行最多读取的列数, 和 Excel 一致, 防止重复列属性展开过多的单元格
+const odsMaxColumns = 16384
+
+// odsSource 流式读取 .ods 文件中的 content.xml, 每个 <table:table> 作为一个表单.
+// 重复的空行和空列只在后面还有数据时才展开, 表单末尾填充用的大量空行不会输出
+type odsSource struct {
+	closer    io.Closer
+	file      *zip.File // content.xml, 读取合并单元格时重新打开
+	content   io.ReadCloser
+	decoder   *xml.Decoder
+	sheetName string
+	table     int // 当前表单的下标
+	hidden    bool
+	inTable   bool
+	formula   bool // 公式单元格返回公式文本
+
+	hiddenStyles map[string]bool // table:display 为 false 的表单样式
+
+	emptyRows int      // 尚未输出的空行数
+	row       []string // 尚未输出的非空行
+	rowRepeat int      // row 还需要输出的次数
+	cells     []string
+	err       error
+}
+
+func newODSSource(r io.ReaderAt, size int64, closer io.Closer, formula bool) (*odsSource, error) {
+	zr, err := zip.NewReader(r, size)
+	if err != nil {
+		return nil, err
+	}
+	for _, f := range zr.File {
+		if f.Name != "content.xml" {
+			continue
+		}
+		content, err := f.Open()
+		if err != nil {
+			return nil, err
+		}
+		return &odsSource{closer: closer, file: f, content: content, decoder: xml.NewDecoder(content), table: -1,
+			formula: formula}, nil
+	}
+	return nil, errors.New("ods文件中没有content.xml")
+}
+
+// NextSheet 跳到下一个 <table:table>
+func (s *odsSource) NextSheet() bool {
+	for s.inTable && s.NextRow() {
+	}
+	for s.err == nil {
+		token, err := s.decoder.Token()
+		if err != nil {
+			s.setErr(err)
+			return false
+		}
+		start, ok := token.(xml.StartElement)
+		switch {
+		case !ok:
+		case isODSElement(start.Name, odsStyleNS, "style") && odsAttr(start, odsStyleNS, "family") == "table":
+			s.readTableStyle(start)
+		case isODSElement(start.Name, odsTableNS, "table"):
+			s.sheetName = odsAttr(start, odsTableNS, "name")
+			s.table++
+			s.hidden = s.hiddenStyles[odsAttr(start, odsTableNS, "style-name")]
+			s.inTable, s.emptyRows, s.row, s.rowRepeat = true, 0, nil, 0
+			return true
+		}
+	}
+	return false
+}
+
+// readTableStyle 读取表单样式, 记录隐藏表单使用的样式; 样式在 content.xml 中位于表单之前
+func (s *odsSource) readTableStyle(start xml.StartElement) {
+	var style struct {
+		Properties struct {
+			Display string `xml:"urn:oasis:names:tc:opendocument:xmlns:table:1.0 display,attr"`
+		} `xml:"urn:oasis:names:tc:opendocument:xmlns:style:1.0 table-properties"`
+	}
+	if err := s.decoder.DecodeElement(&style, &start); err != nil {
+		s.setErr(err)
+		return
+	}
+	if style.Properties.Display == "false" {
+		if s.hiddenStyles == nil {
+			s.hiddenStyles = make(map[string]bool)
+		}
+		s.hiddenStyles[odsAttr(start, odsStyleNS, "name")] = true
+	}
+}
+
+func (s *odsSource) SheetName() string {
+	return s.sheetName
+}
+
+func (s *odsSource) SheetIndex() int {
+	return s.table
+}
+
+func (s *odsSource) Hidden() bool {
+	return s.hidden
+}
+
+// MergeRanges 重新流式读取 content.xml 中当前表单的合并单元格, 不影响当前的读取位置
+func (s *odsSource) MergeRanges() []MergeRange {
+	if s.err != nil || s.table < 0 {
+		return nil
+	}
+	rc, err := s.file.Open()
+	if err != nil {
+		s.setErr(err)
+		return nil
+	}
+	defer rc.Close()
+	ranges, err := odsMergeRanges(xml.NewDecoder(rc), s.table)
+	if err != nil {
+		s.setErr(err)
+		return nil
+	}
+	return ranges
+}
+
+// odsMergeRanges 读取第 index 个表单中 table:number-columns-spanned 和 table:number-rows-spanned 表示的合并区域,
+// 被合并的单元格为 <table:covered-table-cell>, 和普通单元格一样占用位置
+func odsMergeRanges(decoder *xml.Decoder, index int) ([]MergeRange, error) {
+	var ranges []MergeRange
+	table, rowNum, colNum, rowRepeat := -1, 1, 1, 1
+	var spans []MergeRange // 当前行中的合并区域, 行号为 0
+	for {
+		token, err := decoder.Token()
+		if err == io.EOF {
+			return ranges, nil
+		}
+		if err != nil {
+			return nil, err
+		}
+		switch t := token.(type) {
+		case xml.StartElement:
+			switch {
+			case isODSElement(t.Name, odsTableNS, "table"):
+				if table++; table > index {
+					return ranges, nil
+				}
+				if table < index {
+					if err = decoder.Skip(); err != nil {
+						return nil, err
+					}
+				}
+			case isODSElement(t.Name, odsTableNS, "table-row"):
+				colNum, rowRepeat, spans = 1, odsRepeat(t, odsTableNS, "number-rows-repeated"), spans[:0]
+			case isODSElement(t.Name, odsTableNS, "table-cell"), isODSElement(t.Name, odsTableNS, "covered-table-cell"):
+				cols := odsRepeat(t, odsTableNS, "number-columns-spanned")
+				rows := odsRepeat(t, odsTableNS, "number-rows-spanned")
+				if t.Name.Local == "table-cell" && (cols > 1 || rows > 1) {
+					spans = append(spans, MergeRange{StartCol: colNum, EndCol: colNum + cols - 1, EndRow: rows - 1})
+				}
+				colNum += odsRepeat(t, odsTableNS, "number-columns-repeated")
+				// 跳过单元格内容, 其中可能有嵌套的表格
+				if err = decoder.Skip(); err != nil {
+					return nil, err
+				}
+			}
+		case xml.EndElement:
+			if !isODSElement(t.Name, odsTableNS, "table-row") {
+				continue
+			}
+			for i := 0; i < rowRepeat && len(spans) > 0; i++ {
+				for _, span := range spans {
+					ranges = append(ranges, newMergeRange(rowNum+i, span.StartCol, rowNum+i+span.EndRow, span.EndCol))
+				}
+			}
+			rowNum += rowRepeat
+		}
+	}
+}
+
+func (s *odsSource) NextRow() bool {
+	for s.err == nil {
+		switch {
+		case s.row != nil && s.emptyRows > 0:
+			s.emptyRows--
+			s.cells = s.cells[:0]
+			return true
+		case s.row != nil:
+			s.cells = s.row
+			if s.rowRepeat--; s.rowRepeat <= 0 {
+				s.row = nil
+			}
+			return true
+		case !s.inTable:
+			return false
+		}
+		row, repeat, ok := s.readRow()
+		switch {
+		case !ok:
+			// 表单末尾的空行不输出
+			s.inTable, s.emptyRows = false, 0
+		case len(row) == 0:
+			s.emptyRows += repeat
+		default:
+			s.row, s.rowRepeat = row, repeat
+		}
+	}
+	return false
+}
+
+// readRow 读取下一个 <table:table-row>, 读到表单结束时 ok 为 false
+func (s *odsSource) readRow() (row []string, repeat int, ok bool) {
+	for {
+		token, err := s.decoder.Token()
+		if err != nil {
+			s.setErr(err)
+			return nil, 0, false
+		}
+		switch t := token.(type) {
+		case xml.StartElement:
+			if isODSElement(t.Name, odsTableNS, "table-row") {
+				repeat = odsRepeat(t, odsTableNS, "number-rows-repeated")
+				row, err = s.readCells()
+				if err != nil {
+					s.setErr(err)
+					return nil, 0, false
+				}
+				return row, repeat, true
+			}
+			// 跳过列定义、图形等
+			if !isODSElement(t.Name, odsTableNS, "table-header-rows") &&
+				!isODSElement(t.Name, odsTableNS, "table-row-group") &&
+				!isODSElement(t.Name, odsTableNS, "table-rows") {
+				if err = s.decoder.Skip(); err != nil {
+					s.setErr(err)
+					return nil, 0, false
+				}
+			}
+		case xml.EndElement:
+			if isODSElement(t.Name, odsTableNS, "table") {
+				return nil, 0, false
+			}
+		}
+	}
+}
+
+// readCells 读取一行中的单元格, 合并单元格中被覆盖的单元格读取为空
+func (s *odsSource) readCells() ([]string, error) {
+	var (
+		row        []string
+		emptyCells int // 尚未展开的空单元格数, 行末的空单元格不展开
+	)
+	for {
+		token, err := s.decoder.Token()
+		if err != nil {
+			return nil, err
+		}
+		switch t := token.(type) {
+		case xml.StartElement:
+			if !isODSElement(t.Name, odsTableNS, "table-cell") && !isODSElement(t.Name, odsTableNS, "covered-table-cell") {
+				if err = s.decoder.Skip(); err != nil {
+					return nil, err
+				}
+				continue
+			}
+			repeat := odsRepeat(t, odsTableNS, "number-columns-repeated")
+			value, err := s.readCellValue(t)
+			if err != nil {
+				return nil, err
+			}
+			if value == "" {
+				emptyCells += repeat
+				continue
+			}
+			for ; emptyCells > 0 && len(row) < odsMaxColumns; emptyCells-- {
+				row = append(row, "")
+			}
+			emptyCells = 0
+			for i := 0; i < repeat && len(row) < odsMaxColumns; i++ {
+				row = append(row, value)
+			}
+		case xml.EndElement:
+			if isODSElement(t.Name, odsTableNS, "table-row") {
+				return row, nil
+			}
+		}
+	}
+}
+
+// readCellValue 读取单元格的值, 数字、日期和时间使用属性中的值, 日期和时间统一格式, 其他类型使用显示的文本
+func (s *odsSource) readCellValue(start xml.StartElement) (string, error) {
+	var (
+		text       strings.Builder
+		paragraphs int
+	)
+	for depth := 1; depth > 0; {
+		token, err := s.decoder.Token()
+		if err != nil {
+			return "", err
+		}
+		switch t := token.(type) {
+		case xml.StartElement:
+			depth++
+			switch {
+			case isODSElement(t.Name, odsOfficeNS, "annotation"):
+				// 批注不是单元格的值
+				if err = s.decoder.Skip(); err != nil {
+					return "", err
+				}
+				depth--
+			case isODSElement(t.Name, odsTextNS, "p"):
+				if paragraphs++; paragraphs > 1 {
+					text.WriteByte('\n')
+				}
+			case isODSElement(t.Name, odsTextNS, "s"):
+				text.WriteString(strings.Repeat(" ", odsRepeat(t, odsTextNS, "c")))
+			case isODSElement(t.Name, odsTextNS, "tab"):
+				text.WriteByte('\t')
+			case isODSElement(t.Name, odsTextNS, "line-break"):
+				text.WriteByte('\n')
+			}
+		case xml.EndElement:
+			depth--
+		case xml.CharData:
+			text.Write(t)
+		}
+	}
+	if formula := odsAttr(start, odsTableNS, "formula"); s.formula && formula != "" {
+		// 去掉命名空间前缀, 如 of:=SUM([.A1:.A3])
+		if i := strings.Index(formula, ":="); i >= 0 {
+			formula = formula[i+1:]
+		}
+		return formula, nil
+	}
+	switch odsAttr(start, odsOfficeNS, "value-type") {
+	case "float":
+		// 显示的文本可能是科学计数法或只显示部分小数位, 长数字会丢失位数
+		if value := odsAttr(start, odsOfficeNS, "value"); value != "" {
+			return plainNumber(value), nil
+		}
+	case "date":
+		return formatODSDate(odsAttr(start, odsOfficeNS, "date-value")), nil
+	case "time":
+		return formatODSTime(odsAttr(start, odsOfficeNS, "time-value")), nil
+	}
+	return text.String(), nil
+}
+
+// setErr 记录读取错误, 读到文件末尾不是错误
+func (s *odsSource) setErr(err error) {
+	if err != io.EOF {
+		s.err = err
+	}
+	s.inTable = false
+}
+
+func (s *odsSource) Cells() []string {
+	return s.cells
+}
+
+func (s *odsSource) RawCells() []string {
+	return s.cells
+}
+
+func (s *odsSource) Date1904() bool {
+	return false
+}
+
+func (s *odsSource) Err() error {
+	return s.err
+}
+
+func (s *odsSource) Close() error {
+	err := s.content.Close()
+	if s.closer != nil {
+		_ = s.closer.Close()
+	}
+	return err
+}
+
+// isODSElement 判断元素的命名空间和名称
+func isODSElement(name xml.Name, space, local string) bool {
+	return name.Space == space && name.Local == local
+}
+
+// odsAttr 读取属性值
+func odsAttr(start xml.StartElement, space, local string) string {
+	for _, attr := range start.Attr {
+		if attr.Name.Space == space && attr.Name.Local == local {
+			return attr.Value
+		}
+	}
+	return ""
+}
+
+// odsRepeat 读取重复次数属性, 默认为 1
+func odsRepeat(start xml.StartElement, space, local string) int {
+	if n, err := strconv.Atoi(odsAttr(start, space, local)); err == nil && n > 1 {
+		return n
+	}
+	return 1
+}
+
+// formatODSDate 把 office:date-value 转换为 "2006-01-02" 或 "2006-01-02 15:04:05"
+func formatODSDate(value string) string {
+	if len(value) <= len(DateOnlyLayout) {
+		return value
+	}
+	t, err := time.Parse("2006-01-02T15:04:05", value[:min(len(value), 19)])
+	if err != nil {
+		return value
+	}
+	return t.Format(timeutil.DefaultTimeLayout)
+}
+
+// formatODSTime 把 office:time-value 的 ISO 8601 时长(如 PT12H30M00S)转换为 "15:04:05"
+func formatODSTime(value string) string {
+	d, err := time.ParseDuration(strings.ToLower(strings.TrimPrefix(value, "PT")))
+	if err != nil {
+		return value
+	}
+	return time.Date(0, 1, 1, 0, 0, 0, 0, time.UTC).Add(d).Format(TimeOnlyLayout)
+}
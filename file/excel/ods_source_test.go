@@ -0,0 +1,170 @@
+package excelutil
+
+import (
+	"archive/zip"
+	"bytes"
+	"reflect"
+	"testing"
+)
+
+// odsContent 测试用的 content.xml, 第一个表单包含重复行列、合并单元格、日期、时间和公式, 第二个表单隐藏
+const odsContent = `<?xml version="1.0" encoding="UTF-8"?>
+<office:document-content xmlns:office="urn:oasis:names:tc:opendocument:xmlns:office:1.0"
+	xmlns:table="urn:oasis:names:tc:opendocument:xmlns:table:1.0"
+	xmlns:text="urn:oasis:names:tc:opendocument:xmlns:text:1.0"
+	xmlns:style="urn:oasis:names:tc:opendocument:xmlns:style:1.0">
+<office:automatic-styles>
+	<style:style style:name="ta1" style:family="table"><style:table-properties table:display="true"/></style:style>
+	<style:style style:name="ta2" style:family="table"><style:table-properties table:display="false"/></style:style>
+</office:automatic-styles>
+<office:body><office:spreadsheet>
+<table:table table:name="数据" table:style-name="ta1">
+	<table:table-column table:number-columns-repeated="4"/>
+	<table:table-row>
+		<table:table-cell table:number-columns-spanned="2" office:value-type="string"><text:p>规格</text:p></table:table-cell>
+		<table:covered-table-cell/>
+		<table:table-cell office:value-type="string"><text:p>日期</text:p></table:table-cell>
+		<table:table-cell office:value-type="string"><text:p>合计</text:p></table:table-cell>
+	</table:table-row>
+	<table:table-row>
+		<table:table-cell table:number-rows-spanned="3" office:value-type="string"><text:p>A<text:s text:c="2"/>B</text:p><office:annotation><text:p>批注</text:p></office:annotation></table:table-cell>
+		<table:table-cell office:value-type="float" office:value="1"><text:p>1</text:p></table:table-cell>
+		<table:table-cell office:value-type="date" office:date-value="2023-07-16T08:30:00"><text:p>2023/7/16</text:p></table:table-cell>
+		<table:table-cell table:formula="of:=SUM([.B2:.B4])" office:value-type="float" office:value="3"><text:p>3</text:p></table:table-cell>
+	</table:table-row>
+	<table:table-row table:number-rows-repeated="2">
+		<table:covered-table-cell/>
+		<table:table-cell table:number-columns-repeated="2" office:value-type="string"><text:p>x</text:p></table:table-cell>
+		<table:table-cell table:number-columns-repeated="1020"/>
+	</table:table-row>
+	<table:table-row table:number-rows-repeated="2"><table:table-cell table:number-columns-repeated="1024"/></table:table-row>
+	<table:table-row>
+		<table:table-cell table:number-columns-repeated="3"/>
+		<table:table-cell office:value-type="time" office:time-value="PT12H30M00S"><text:p>12:30</text:p></table:table-cell>
+	</table:table-row>
+	<table:table-row table:number-rows-repeated="1048570"><table:table-cell table:number-columns-repeated="1024"/></table:table-row>
+</table:table>
+<table:table table:name="隐藏" table:style-name="ta2">
+	<table:table-row><table:table-cell office:value-type="string"><text:p>h</text:p></table:table-cell></table:table-row>
+</table:table>
+</office:spreadsheet></office:body>
+</office:document-content>`
+
+// newTestODS 生成只包含 content.xml 的 .ods 文件
+func newTestODS(t *testing.T, content string) *bytes.Reader {
+	t.Helper()
+	var buf bytes.Buffer
+	zw := zip.NewWriter(&buf)
+	w, err := zw.Create("content.xml")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err = w.Write([]byte(content)); err != nil {
+		t.Fatal(err)
+	}
+	if err = zw.Close(); err != nil {
+		t.Fatal(err)
+	}
+	return bytes.NewReader(buf.Bytes())
+}
+
+// readODSSheets 读取所有表单的名称、是否隐藏、合并区域和数据
+func readODSSheets(t *testing.T, formula bool) (names []string, hidden []bool, merges [][]MergeRange, rows [][][]string) {
+	t.Helper()
+	r := newTestODS(t, odsContent)
+	src, err := newODSSource(r, r.Size(), nil, formula)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer src.Close()
+	for src.NextSheet() {
+		names = append(names, src.SheetName())
+		hidden = append(hidden, src.Hidden())
+		var sheetRows [][]string
+		for src.NextRow() {
+			// 合并区域在读取过程中获取, 不影响读取位置
+			if len(sheetRows) == 1 {
+				merges = append(merges, src.MergeRanges())
+			}
+			sheetRows = append(sheetRows, append([]string(nil), src.Cells()...))
+		}
+		if len(sheetRows) < 2 {
+			merges = append(merges, src.MergeRanges())
+		}
+		rows = append(rows, sheetRows)
+	}
+	if err = src.Err(); err != nil {
+		t.Fatal(err)
+	}
+	return names, hidden, merges, rows
+}
+
+func TestODSSource(t *testing.T) {
+	names, hidden, merges, rows := readODSSheets(t, false)
+	if want := []string{"数据", "隐藏"}; !reflect.DeepEqual(names, want) {
+		t.Errorf("sheet names = %v, want %v", names, want)
+	}
+	if want := []bool{false, true}; !reflect.DeepEqual(hidden, want) {
+		t.Errorf("hidden = %v, want %v", hidden, want)
+	}
+	wantRows := [][][]string{
+		{
+			{"规格", "", "日期", "合计"},
+			{"A  B", "1", "2023-07-16 08:30:00", "3"},
+			{"", "x", "x"},
+			{"", "x", "x"},
+			nil,
+			nil,
+			{"", "", "", "12:30:00"},
+		},
+		{{"h"}},
+	}
+	if !reflect.DeepEqual(rows, wantRows) {
+		t.Errorf("rows = %q, want %q", rows, wantRows)
+	}
+	wantMerges := [][]MergeRange{{newMergeRange(1, 1, 1, 2), newMergeRange(2, 1, 4, 1)}, nil}
+	if !reflect.DeepEqual(merges, wantMerges) {
+		t.Errorf("merges = %v, want %v", merges, wantMerges)
+	}
+}
+
+func TestODSSourceFormula(t *testing.T) {
+	_, _, _, rows := readODSSheets(t, true)
+	if got, want := rows[0][1][3], "=SUM([.B2:.B4])"; got != want {
+		t.Errorf("formula = %q, want %q", got, want)
+	}
+	if got, want := rows[0][1][1], "1"; got != want {
+		t.Errorf("value = %q, want %q", got, want)
+	}
+}
+
+// 数字单元格使用 office:value, 不受显示格式影响
+func TestODSSourceFloatValue(t *testing.T) {
+	r := newTestODS(t, `<?xml version="1.0" encoding="UTF-8"?>
+<office:document-content xmlns:office="urn:oasis:names:tc:opendocument:xmlns:office:1.0"
+	xmlns:table="urn:oasis:names:tc:opendocument:xmlns:table:1.0"
+	xmlns:text="urn:oasis:names:tc:opendocument:xmlns:text:1.0">
+<office:body><office:spreadsheet><table:table table:name="Sheet1">
+	<table:table-row>
+		<table:table-cell office:value-type="float" office:value="123456789012345"><text:p>1.23457E+14</text:p></table:table-cell>
+		<table:table-cell office:value-type="float" office:value="3.14159"><text:p>3.14</text:p></table:table-cell>
+		<table:table-cell office:value-type="float" office:value="1234.5"><text:p>1,234.50</text:p></table:table-cell>
+		<table:table-cell office:value-type="float" office:value="1.10101199001011E+17"><text:p>1.10101E+17</text:p></table:table-cell>
+		<table:table-cell office:value-type="float"><text:p>42</text:p></table:table-cell>
+		<table:table-cell office:value-type="percentage" office:value="0.125"><text:p>12.5%</text:p></table:table-cell>
+	</table:table-row>
+</table:table></office:spreadsheet></office:body>
+</office:document-content>`)
+	src, err := newODSSource(r, r.Size(), nil, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer src.Close()
+	if !src.NextSheet() || !src.NextRow() {
+		t.Fatalf("no rows, error = %v", src.Err())
+	}
+	want := []string{"123456789012345", "3.14159", "1234.5", "110101199001011000", "42", "12.5%"}
+	if got := src.Cells(); !reflect.DeepEqual(got, want) {
+		t.Errorf("cells = %q, want %q", got, want)
+	}
+}
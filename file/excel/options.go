@@ -0,0 +1,255 @@
+package excelutil
+
+// ReadOption 读取Excel文件的选项
+type ReadOption func(*readOptions)
+
+// readOptions 读取Excel文件的配置
+type readOptions struct {
+	headerScanRows int                 // 查找表头时扫描的行数
+	titleAliases   map[string][]string // 标准表头 -> 别名
+	rules          []*ColumnRule       // 列校验规则
+	columns        map[string]*columnConfig
+	hasDateColumns bool        // 是否通过 WithDateColumns 设置了日期列, 未设置时按目标表头是否包含 "时间" 判断
+	hasTextColumns bool        // 是否通过 WithTextColumns 设置了文本列
+	sheetNames     []string    // 只读取这些名称的表单, 和 sheetIndexes、sheetPatterns 都为空时读取全部表单
+	sheetIndexes   []int       // 只读取这些下标的表单, 从 0 开始
+	sheetPatterns  []string    // 只读取名称匹配这些正则表达式的表单
+	skipHidden     bool        // 跳过隐藏的表单
+	skipMismatched bool        // 跳过表头不符合要求的表单, 不返回错误
+	fillMerged     bool        // 用合并单元格的值填充区域内的其他单元格
+	delimiter      rune        // 文本文件的分隔符, 为 0 时自动检测
+	charset        string      // 文本文件的编码, 为空时自动检测
+	password       string      // 加密 .xlsx 的打开密码
+	formula        FormulaMode // 公式单元格的读取方式
+	typedCells     bool        // 同时返回带类型的数据
+	normalizers    []string    // 所有映射列的规范化方式, 在列自己的规范化方式之前执行
+	uniqueKey      []string    // 主键列(源表头)
+	duplicate      DuplicatePolicy
+	batch          bool // 批量读取, 主键在全部文件读取后统一检查
+}
+
+// columnConfig 按源表头设置的列读取配置
+type columnConfig struct {
+	hasDefault   bool
+	defaultValue string      // 空单元格的默认值
+	date         *DateColumn // 日期列配置, 为空时不是日期列
+	text         bool        // 文本列, 使用单元格保存的原始值
+	normalizers  []string    // 规范化方式, 按顺序执行
+}
+
+func newReadOptions(opts ...ReadOption) *readOptions {
+	options := &readOptions{
+		headerScanRows: defaultHeaderScanRows,
+		columns:        make(map[string]*columnConfig),
+	}
+	for _, opt := range opts {
+		opt(options)
+	}
+	return options
+}
+
+// WithHeaderScanRows 设置查找表头时扫描的行数, 默认扫描前10行;
+// 设置为1时表头固定为第一行
+func WithHeaderScanRows(n int) ReadOption {
+	return func(o *readOptions) {
+		if n > 0 {
+			o.headerScanRows = n
+		}
+	}
+}
+
+// WithTitleAliases 设置表头别名, key 为标准表头, value 为别名列表,
+// 校验 checkTitles 和映射 dstTitleMap 时别名视为标准表头, 多次调用会合并
+func WithTitleAliases(aliases map[string][]string) ReadOption {
+	return func(o *readOptions) {
+		if o.titleAliases == nil {
+			o.titleAliases = make(map[string][]string, len(aliases))
+		}
+		for title, list := range aliases {
+			o.titleAliases[title] = append(o.titleAliases[title], list...)
+		}
+	}
+}
+
+// WithRules 设置列校验规则, 读取时逐行校验, 不符合规则的单元格记录在 ExcelFile.Violations 中
+func WithRules(rules ...ColumnRule) ReadOption {
+	return func(o *readOptions) {
+		for i := range rules {
+			rule := rules[i]
+			o.rules = append(o.rules, &rule)
+		}
+	}
+}
+
+// column 返回源表头对应的列配置, 不存在时创建
+func (o *readOptions) column(title string) *columnConfig {
+	config, ok := o.columns[title]
+	if !ok {
+		config = &columnConfig{}
+		o.columns[title] = config
+	}
+	return config
+}
+
+// WithDefaults 设置空单元格的默认值, key 为源表头
+func WithDefaults(defaults map[string]string) ReadOption {
+	return func(o *readOptions) {
+		for title, value := range defaults {
+			config := o.column(title)
+			config.hasDefault = true
+			config.defaultValue = value
+		}
+	}
+}
+
+// WithTextColumns 设置文本列, title 为源表头. 文本列使用单元格保存的原始值, 不应用数字格式和日期转换,
+// 数字单元格按完整的整数或小数输出, 适用于身份证号、条码、订单号等以数字格式保存的编号;
+// csv 等文本文件保持文件中的文本, 已丢失的精度无法恢复, 仍记录在 PrecisionLostColumns 中
+func WithTextColumns(titles ...string) ReadOption {
+	return func(o *readOptions) {
+		for _, title := range titles {
+			o.column(title).text = true
+		}
+		o.hasTextColumns = o.hasTextColumns || len(titles) > 0
+	}
+}
+
+// WithNormalizers 设置列的规范化方式, title 为源表头, names 为 NormalizeWidth 等内置方式或 RegisterNormalizer
+// 注册的名称, 按顺序执行, 如 WithNormalizers("单价", NormalizeWidth, NormalizeSpace, NormalizeNumber).
+// 规范化在校验和日期转换之前执行, 无法规范化的单元格保留原值并记录到 ExcelFile.Violations; 名称不存在时读取返回错误
+func WithNormalizers(title string, names ...string) ReadOption {
+	return func(o *readOptions) {
+		config := o.column(title)
+		config.normalizers = append(config.normalizers, names...)
+	}
+}
+
+// WithNormalizeAll 设置所有映射列的规范化方式, 在 WithNormalizers 设置的方式之前执行,
+// 如 WithNormalizeAll(NormalizeWidth, NormalizeSpace)
+func WithNormalizeAll(names ...string) ReadOption {
+	return func(o *readOptions) {
+		o.normalizers = append(o.normalizers, names...)
+	}
+}
+
+// WithUniqueKey 设置主键列, titles 为源表头, 支持别名, 多个表头为联合主键; 主键列必须在 dstTitleMap 中.
+// 读取后检查所有表单中主键重复的行, 按 WithDuplicatePolicy 处理, 重复的主键记录在 ImportReport.Duplicates 中;
+// 主键列有空值的行不检查, 比较时去掉两端空白, 需要忽略大小写等时配合 WithNormalizers 使用
+func WithUniqueKey(titles ...string) ReadOption {
+	return func(o *readOptions) {
+		o.uniqueKey = append(o.uniqueKey, titles...)
+	}
+}
+
+// WithDuplicatePolicy 设置主键重复的数据的处理方式, 默认为 DuplicateError
+func WithDuplicatePolicy(policy DuplicatePolicy) ReadOption {
+	return func(o *readOptions) {
+		o.duplicate = policy
+	}
+}
+
+// WithDateLayouts 设置日期列及其输出格式, key 为源表头;
+// 未设置的列仍按目标表头是否包含 "时间" 判断是否为日期列
+func WithDateLayouts(layouts map[string]string) ReadOption {
+	return func(o *readOptions) {
+		for title, layout := range layouts {
+			o.column(title).date = &DateColumn{Title: title, OutputLayout: layout}
+		}
+	}
+}
+
+// WithDateColumns 设置日期列的输入格式、输出格式、时区等;
+// 设置了日期列后不再按目标表头是否包含 "时间" 判断日期列
+func WithDateColumns(columns ...DateColumn) ReadOption {
+	return func(o *readOptions) {
+		for i := range columns {
+			column := columns[i]
+			o.column(column.Title).date = &column
+			o.hasDateColumns = true
+		}
+	}
+}
+
+// WithDelimiter 设置 csv 等文本文件的分隔符, 不设置时自动检测逗号、分号、制表符和竖线
+func WithDelimiter(delimiter rune) ReadOption {
+	return func(o *readOptions) {
+		o.delimiter = delimiter
+	}
+}
+
+// WithCharset 指定 csv、html 等文本文件的编码, 如 common.CharsetGBK, 用于内容较少时自动检测不准确的情况;
+// 文件开头有 BOM 时以 BOM 为准
+func WithCharset(charset string) ReadOption {
+	return func(o *readOptions) {
+		o.charset = charset
+	}
+}
+
+// WithPassword 设置加密 .xlsx 文件的打开密码, 没有密码或密码不正确时返回 *PasswordError
+func WithPassword(password string) ReadOption {
+	return func(o *readOptions) {
+		o.password = password
+	}
+}
+
+// WithTypedCells 同时返回带类型的数据 ExcelSheet.Cells 和推断的列类型 ExcelSheet.ColumnTypes.
+// .xlsx 和 .xls 按单元格类型和数字格式确定类型, 文本文件按文本推断
+func WithTypedCells() ReadOption {
+	return func(o *readOptions) {
+		o.typedCells = true
+	}
+}
+
+// WithFormulaMode 设置公式单元格的读取方式, 默认为 FormulaCached.
+// .xlsx 支持全部方式; .ods 支持 FormulaText, 公式为 OpenFormula 格式; .xls 只保存了计算结果, 总是使用保存的结果.
+// .xlsx 使用 FormulaEvaluate 或 FormulaText 时, excelize 读取公式和计算需要把整个表单加载到内存,
+// 只有包含公式的表单会加载, 没有公式的表单仍然流式读取
+func WithFormulaMode(mode FormulaMode) ReadOption {
+	return func(o *readOptions) {
+		o.formula = mode
+	}
+}
+
+// WithFillMergedCells 用合并单元格左上角的值填充区域内的其他单元格, 如纵向合并的分类会填充到每一行;
+// 支持 .xlsx、.xls 和 .ods, 表头中的合并单元格不填充
+func WithFillMergedCells() ReadOption {
+	return func(o *readOptions) {
+		o.fillMerged = true
+	}
+}
+
+// WithSheets 只读取指定名称的表单, 和 WithSheetIndexes、WithSheetPattern 同时使用时读取满足任一条件的表单
+func WithSheets(names ...string) ReadOption {
+	return func(o *readOptions) {
+		o.sheetNames = append(o.sheetNames, names...)
+	}
+}
+
+// WithSheetIndexes 只读取指定下标的表单, 下标从 0 开始, 按工作簿中的顺序计算, 包括隐藏的表单
+func WithSheetIndexes(indexes ...int) ReadOption {
+	return func(o *readOptions) {
+		o.sheetIndexes = append(o.sheetIndexes, indexes...)
+	}
+}
+
+// WithSheetPattern 只读取名称匹配正则表达式的表单, 如 "^2024年"
+func WithSheetPattern(pattern string) ReadOption {
+	return func(o *readOptions) {
+		o.sheetPatterns = append(o.sheetPatterns, pattern)
+	}
+}
+
+// WithSkipHiddenSheets 跳过隐藏和深度隐藏的表单, 即使表单被 WithSheets 等选中
+func WithSkipHiddenSheets() ReadOption {
+	return func(o *readOptions) {
+		o.skipHidden = true
+	}
+}
+
+// WithSkipMismatchedSheets 跳过没有数据或表头不符合要求的表单(如 "说明" 表单), 不再返回错误,
+// 跳过的表单记录在 ImportReport.SkippedSheets 中; 所有表单都不符合要求时仍返回第一个表单的错误
+func WithSkipMismatchedSheets() ReadOption {
+	return func(o *readOptions) {
+		o.skipMismatched = true
+	}
+}
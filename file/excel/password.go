@@ -0,0 +1,69 @@
+package excelutil
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"os"
+
+	"github.com/xuri/excelize/v2"
+)
+
+// PasswordError 加密的工作簿没有提供打开密码或密码不正确, 可以据此提示用户输入密码
+type PasswordError struct {
+	FileName string // 文件名
+	Missing  bool   // true 表示没有提供密码, false 表示密码不正确
+}
+
+func (e *PasswordError) Error() string {
+	msg := "文件需要打开密码"
+	if !e.Missing {
+		msg = "文件打开密码不正确"
+	}
+	if e.FileName != "" {
+		msg += ":" + e.FileName
+	}
+	return msg
+}
+
+// isEncrypted 判断 .xlsx 是否加密, 加密后的文件是 OLE2 复合文件
+func isEncrypted(r io.ReaderAt) bool {
+	head := make([]byte, len(ole2Magic))
+	if _, err := r.ReadAt(head, 0); err != nil {
+		return false
+	}
+	return bytes.Equal(head, ole2Magic)
+}
+
+// openXLSX 打开 .xlsx 文件, 加密的文件(ECMA-376 标准加密和敏捷加密)使用 password 解密,
+// 没有密码或密码不正确时返回 *PasswordError
+func openXLSX(r io.ReaderAt, size int64, fileName, password string, opts excelize.Options) (*excelize.File, error) {
+	encrypted := isEncrypted(r)
+	if encrypted && password == "" {
+		return nil, &PasswordError{FileName: fileName, Missing: true}
+	}
+	opts.Password = password
+	f, err := excelize.OpenReader(io.NewSectionReader(r, 0, size), opts)
+	if err != nil {
+		// 密码错误时解密失败或解密出的数据不是 zip 文件
+		if encrypted && (errors.Is(err, excelize.ErrWorkbookPassword) || errors.Is(err, excelize.ErrWorkbookFileFormat)) {
+			return nil, &PasswordError{FileName: fileName}
+		}
+		return nil, err
+	}
+	return f, nil
+}
+
+// openXLSXFile 打开 .xlsx 文件用于修改, 加密的文件使用 password 解密, 保存时使用相同的密码加密
+func openXLSXFile(fileName, password string) (*excelize.File, error) {
+	file, err := os.Open(fileName)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+	info, err := file.Stat()
+	if err != nil {
+		return nil, err
+	}
+	return openXLSX(file, info.Size(), fileName, password, excelize.Options{})
+}
@@ -0,0 +1,68 @@
+package excelutil
+
+import (
+	"errors"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"github.com/xuri/excelize/v2"
+)
+
+// writeEncryptedXLSX 生成使用 password 加密的 .xlsx 文件
+func writeEncryptedXLSX(t *testing.T, password string) string {
+	t.Helper()
+	f := excelize.NewFile()
+	defer f.Close()
+	if err := f.SetSheetRow("Sheet1", "A1", &[]any{"编码", "名称"}); err != nil {
+		t.Fatal(err)
+	}
+	if err := f.SetSheetRow("Sheet1", "A2", &[]any{"001", "螺丝"}); err != nil {
+		t.Fatal(err)
+	}
+	fileName := filepath.Join(t.TempDir(), "secret.xlsx")
+	if err := f.SaveAs(fileName, excelize.Options{Password: password}); err != nil {
+		t.Fatal(err)
+	}
+	return fileName
+}
+
+func TestReadEncryptedXLSX(t *testing.T) {
+	fileName := writeEncryptedXLSX(t, "123456")
+	dstTitleMap := map[string]string{"编码": "code", "名称": "name"}
+	tests := []struct {
+		name     string
+		opts     []ReadOption
+		wantErr  bool
+		wantMiss bool
+	}{
+		{name: "没有密码", wantErr: true, wantMiss: true},
+		{name: "密码不正确", opts: []ReadOption{WithPassword("654321")}, wantErr: true},
+		{name: "密码正确", opts: []ReadOption{WithPassword("123456")}},
+	}
+	for _, tt := range tests {
+		file, err := ReadExcelFile(fileName, nil, dstTitleMap, tt.opts...)
+		var passwordErr *PasswordError
+		if tt.wantErr {
+			if !errors.As(err, &passwordErr) || passwordErr.Missing != tt.wantMiss || passwordErr.FileName != fileName {
+				t.Errorf("%s: ReadExcelFile() error = %v, want *PasswordError{Missing: %v}", tt.name, err, tt.wantMiss)
+			}
+			continue
+		}
+		if err != nil {
+			t.Fatalf("%s: ReadExcelFile() error = %v", tt.name, err)
+		}
+		if want := [][]string{{"001", "螺丝"}}; !reflect.DeepEqual(file.Sheets[0].Rows, want) {
+			t.Errorf("%s: rows = %q, want %q", tt.name, file.Sheets[0].Rows, want)
+		}
+	}
+}
+
+func TestPasswordError(t *testing.T) {
+	if got := (&PasswordError{FileName: "a.xlsx", Missing: true}).Error(); got != "文件需要打开密码:a.xlsx" {
+		t.Errorf("Error() = %q", got)
+	}
+	if got := (&PasswordError{}).Error(); got != "文件打开密码不正确" {
+		t.Errorf("Error() = %q", got)
+	}
+}
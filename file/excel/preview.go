@@ -0,0 +1,360 @@
+package excelutil
+
+import (
+	"archive/zip"
+	"encoding/csv"
+	"encoding/xml"
+	"io"
+	"os"
+	"path"
+	"strings"
+
+	"go_file/common"
+
+	"github.com/xuri/excelize/v2"
+)
+
+// PreviewFile 文件预览, 用于导入前展示数据和设置列映射
+type PreviewFile struct {
+	FileName  string          // 文件名
+	FileType  string          // 检测到的文件格式, 如 common.FileTypeXlsx
+	Charset   string          // 文本文件检测到的编码
+	Delimiter string          // csv 文件检测到的分隔符
+	Sheets    []*PreviewSheet // 每个表单的预览
+}
+
+// PreviewSheet 表单预览
+type PreviewSheet struct {
+	SheetName string     // 表单名称
+	Hidden    bool       // 是否隐藏
+	HeaderRow int        // 检测到的表头所在行号, 从 1 开始, 没有数据时为 0
+	Header    []string   // 检测到的表头
+	Rows      [][]string // 开头的原始数据, 包括表头及之前的行
+	TotalRows int        // 总行数, 无法确定时为 -1; .ods 和 html 文件不读完无法确定, 读到的行数少于 n 时才有值
+	RowsExact bool       // TotalRows 是否为准确值, 否则为根据文件信息估计的值
+}
+
+// Preview 读取文件每个表单开头的 n 行, 检测表头并估计总行数, 只读取预览需要的数据.
+// 在 WithHeaderScanRows 设置的行数中检测表头, 还支持 WithPassword、WithCharset、WithDelimiter
+func Preview(fileName string, n int, opts ...ReadOption) (*PreviewFile, error) {
+	file, err := os.Open(fileName)
+	if err != nil {
+		return nil, err
+	}
+	info, err := file.Stat()
+	if err != nil {
+		file.Close()
+		return nil, err
+	}
+	if info.Size() == 0 {
+		file.Close()
+		return nil, &EmptyFileError{FileName: fileName}
+	}
+	return preview(file, info.Size(), fileName, "", file, n, newReadOptions(opts...))
+}
+
+// PreviewReader 从 io.Reader 读取预览, fileType 为格式提示, 为空时根据内容检测
+func PreviewReader(r io.Reader, fileType string, n int, opts ...ReadOption) (*PreviewFile, error) {
+	ra, size, err := toReaderAt(r)
+	if err != nil {
+		return nil, err
+	}
+	return preview(ra, size, "", fileType, nil, n, newReadOptions(opts...))
+}
+
+func preview(r io.ReaderAt, size int64, fileName, fileType string, closer io.Closer, n int,
+	options *readOptions) (*PreviewFile, error) {
+	if fileType == "" {
+		var err error
+		if fileType, err = detectFileType(r, size); err != nil {
+			if closer != nil {
+				closer.Close()
+			}
+			return nil, err
+		}
+	}
+	src, err := openPreviewSource(r, size, fileName, fileType, closer, options)
+	if err != nil {
+		if closer != nil {
+			closer.Close()
+		}
+		return nil, err
+	}
+	defer src.Close()
+	result := &PreviewFile{FileName: fileName, FileType: fileType}
+	if s, ok := src.(*csvSource); ok {
+		result.Charset, result.Delimiter = s.charset, string(s.delimiter)
+	}
+	var dimensions map[string]int
+	if fileType == common.FileTypeXlsx {
+		// 加密的文件不是 zip 文件, 无法读取时不估计总行数
+		dimensions, _ = xlsxDimensions(r, size)
+	}
+	limit := max(n, options.headerScanRows)
+	for src.NextSheet() {
+		sheet := &PreviewSheet{SheetName: src.SheetName(), Hidden: src.Hidden(), TotalRows: -1}
+		var rows [][]string
+		for len(rows) < limit && src.NextRow() {
+			rows = append(rows, append([]string(nil), src.Cells()...))
+		}
+		ended := len(rows) < limit || !src.NextRow()
+		if err = src.Err(); err != nil {
+			return nil, err
+		}
+		if index, header := previewHeader(rows); index >= 0 {
+			sheet.HeaderRow, sheet.Header = index+1, header
+		}
+		if ended {
+			sheet.TotalRows, sheet.RowsExact = len(rows), true
+		} else {
+			sheet.TotalRows, sheet.RowsExact = estimateRows(src, size, dimensions)
+			if sheet.TotalRows >= 0 && sheet.TotalRows <= len(rows) {
+				// 估计值不可信, 至少还有一行
+				sheet.TotalRows = -1
+			}
+		}
+		sheet.Rows = rows[:min(n, len(rows))]
+		result.Sheets = append(result.Sheets, sheet)
+	}
+	if err = src.Err(); err != nil {
+		return nil, err
+	}
+	return result, nil
+}
+
+// openPreviewSource 打开预览使用的读取器, .xlsx 直接从 zip 中流式读取, 只读取预览需要的行和共享字符串;
+// 加密的文件和 FormulaCached 以外的公式方式需要 excelize, 仍使用 xlsxSource
+func openPreviewSource(r io.ReaderAt, size int64, fileName, fileType string, closer io.Closer,
+	options *readOptions) (sheetSource, error) {
+	if fileType == common.FileTypeXlsx && options.formula == FormulaCached && !isEncrypted(r) {
+		return newXLSXStreamSource(r, size, closer)
+	}
+	return openSheetSource(r, size, fileName, fileType, closer, options)
+}
+
+// previewHeader 还没有列映射时, 取第一个非空单元格数达到最多的行作为表头, 跳过表头上方的标题行;
+// 和上方的行拼接为多级表头后更完整时使用拼接的表头, 和 ReadExcelFile 一致. 没有数据时返回 -1
+func previewHeader(rows [][]string) (int, []string) {
+	index, most := -1, 0
+	var best []string
+	for i, row := range rows {
+		header := trimHeader(row)
+		if isEmptyRow(header) {
+			continue
+		}
+		for _, merged := range stackedHeaders(rows, i) {
+			if filledCount(merged) > filledCount(header) {
+				header = merged
+			}
+		}
+		if count := filledCount(header); count > most {
+			index, most, best = i, count, header
+		}
+	}
+	return index, best
+}
+
+// filledCount 非空单元格数
+func filledCount(row []string) int {
+	count := 0
+	for _, cell := range row {
+		if cell != "" {
+			count++
+		}
+	}
+	return count
+}
+
+// estimateRows 根据文件信息估计当前表单的总行数, 无法估计时返回 -1; exact 表示是否为准确值
+func estimateRows(src sheetSource, size int64, dimensions map[string]int) (rows int, exact bool) {
+	switch s := src.(type) {
+	case *xlsxSource, *xlsxStreamSource:
+		if n, ok := dimensions[s.SheetName()]; ok {
+			return n, false
+		}
+	case *xlsSource:
+		if s.sheet != nil {
+			return int(s.sheet.MaxRow) + 1, true
+		}
+	case *csvSource:
+		// 文件不超过采样大小时开头数据就是全部数据
+		return s.estimateRows(size), size <= csvSampleSize
+	}
+	return -1, false
+}
+
+// estimateRows 按开头数据的平均行长度估计总行数, 无法估计时返回 -1
+func (s *csvSource) estimateRows(size int64) int {
+	sample := s.sample
+	if i := strings.LastIndexByte(sample, '\n'); i >= 0 && size > csvSampleSize {
+		// 去掉末尾不完整的行
+		sample = sample[:i+1]
+	}
+	reader := csv.NewReader(strings.NewReader(sample))
+	reader.Comma = s.delimiter
+	reader.FieldsPerRecord = -1
+	reader.LazyQuotes = true
+	records := 0
+	for {
+		if _, err := reader.Read(); err != nil {
+			break
+		}
+		records++
+	}
+	if len(sample) == 0 || records == 0 {
+		return -1
+	}
+	return int(int64(records) * size / int64(min(size, csvSampleSize)))
+}
+
+// xlsxDimensions 读取 .xlsx 每个表单 <dimension> 记录的最大行号, 只读取表单 XML 开头的部分;
+// 部分工具生成的文件没有 <dimension>, 这些表单不返回
+func xlsxDimensions(r io.ReaderAt, size int64) (map[string]int, error) {
+	zr, err := zip.NewReader(r, size)
+	if err != nil {
+		return nil, err
+	}
+	sheets, err := xlsxSheetFiles(zr)
+	if err != nil {
+		return nil, err
+	}
+	dimensions := make(map[string]int, len(sheets))
+	for name, f := range sheets {
+		if rows, err := sheetDimension(f); err == nil && rows > 0 {
+			dimensions[name] = rows
+		}
+	}
+	return dimensions, nil
+}
+
+// xlsxZipWorkbook 直接从 zip 中读取的 .xlsx 工作簿信息
+type xlsxZipWorkbook struct {
+	sheets        []*xlsxZipSheet // 按工作簿中的顺序
+	sharedStrings *zip.File       // 共享字符串, 没有时为 nil
+	styles        *zip.File       // 样式, 没有时为 nil
+	date1904      bool
+}
+
+// xlsxZipSheet .xlsx 中的表单
+type xlsxZipSheet struct {
+	name   string
+	hidden bool // 隐藏或深度隐藏
+	file   *zip.File
+}
+
+// readXLSXWorkbook 根据 workbook.xml 和关系文件找到每个表单的 XML 文件、共享字符串和样式, 不读取表单数据
+func readXLSXWorkbook(zr *zip.Reader) (*xlsxZipWorkbook, error) {
+	files := make(map[string]*zip.File, len(zr.File))
+	for _, f := range zr.File {
+		files[f.Name] = f
+	}
+	var workbook struct {
+		WorkbookPr struct {
+			Date1904 string `xml:"date1904,attr"`
+		} `xml:"workbookPr"`
+		Sheets []struct {
+			Name  string `xml:"name,attr"`
+			State string `xml:"state,attr"`
+			ID    string `xml:"http://schemas.openxmlformats.org/officeDocument/2006/relationships id,attr"`
+		} `xml:"sheets>sheet"`
+	}
+	var rels struct {
+		Relationships []struct {
+			ID     string `xml:"Id,attr"`
+			Type   string `xml:"Type,attr"`
+			Target string `xml:"Target,attr"`
+		} `xml:"Relationship"`
+	}
+	if err := decodeZipXML(files["xl/workbook.xml"], &workbook); err != nil {
+		return nil, err
+	}
+	if err := decodeZipXML(files["xl/_rels/workbook.xml.rels"], &rels); err != nil {
+		return nil, err
+	}
+	date1904 := workbook.WorkbookPr.Date1904
+	result := &xlsxZipWorkbook{date1904: date1904 == "1" || date1904 == "true"}
+	targets := make(map[string]string, len(rels.Relationships))
+	for _, rel := range rels.Relationships {
+		target := strings.TrimPrefix(rel.Target, "/")
+		if !strings.HasPrefix(rel.Target, "/") {
+			target = path.Join("xl", target)
+		}
+		targets[rel.ID] = target
+		switch path.Base(rel.Type) {
+		case "sharedStrings":
+			result.sharedStrings = files[target]
+		case "styles":
+			result.styles = files[target]
+		}
+	}
+	for _, sheet := range workbook.Sheets {
+		if f := files[targets[sheet.ID]]; f != nil {
+			result.sheets = append(result.sheets, &xlsxZipSheet{
+				name:   sheet.Name,
+				hidden: sheet.State == "hidden" || sheet.State == "veryHidden",
+				file:   f,
+			})
+		}
+	}
+	return result, nil
+}
+
+// xlsxSheetFiles 每个表单的 XML 文件, key 为表单名称
+func xlsxSheetFiles(zr *zip.Reader) (map[string]*zip.File, error) {
+	workbook, err := readXLSXWorkbook(zr)
+	if err != nil {
+		return nil, err
+	}
+	sheets := make(map[string]*zip.File, len(workbook.sheets))
+	for _, sheet := range workbook.sheets {
+		sheets[sheet.name] = sheet.file
+	}
+	return sheets, nil
+}
+
+// decodeZipXML 解析 zip 中的 XML 文件
+func decodeZipXML(f *zip.File, v any) error {
+	if f == nil {
+		return io.ErrUnexpectedEOF
+	}
+	rc, err := f.Open()
+	if err != nil {
+		return err
+	}
+	defer rc.Close()
+	return xml.NewDecoder(rc).Decode(v)
+}
+
+// sheetDimension 读取表单 XML 中 <dimension ref="A1:G100"> 的最大行号, 读到 <sheetData> 时停止, 没有时返回 0
+func sheetDimension(f *zip.File) (int, error) {
+	rc, err := f.Open()
+	if err != nil {
+		return 0, err
+	}
+	defer rc.Close()
+	decoder := xml.NewDecoder(rc)
+	for {
+		token, err := decoder.Token()
+		if err != nil {
+			return 0, err
+		}
+		start, ok := token.(xml.StartElement)
+		if !ok {
+			continue
+		}
+		switch start.Name.Local {
+		case "dimension":
+			for _, attr := range start.Attr {
+				if attr.Name.Local != "ref" {
+					continue
+				}
+				ref := attr.Value[strings.LastIndexByte(attr.Value, ':')+1:]
+				_, row, err := excelize.CellNameToCoordinates(ref)
+				return row, err
+			}
+			return 0, nil
+		case "sheetData":
+			return 0, nil
+		}
+	}
+}
@@ -0,0 +1,133 @@
+package excelutil
+
+import (
+	"fmt"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/xuri/excelize/v2"
+)
+
+func TestPreviewHeader(t *testing.T) {
+	tests := []struct {
+		name       string
+		rows       [][]string
+		wantIndex  int
+		wantHeader []string
+	}{
+		{"没有数据", nil, -1, nil},
+		{"第一行为表头", [][]string{{"编码", "名称"}, {"001", "螺丝"}}, 0, []string{"编码", "名称"}},
+		{"跳过标题行", [][]string{{"物料清单"}, {}, {" 编码 ", "名称", "数量"}, {"001", "螺丝", "100"}}, 2, []string{"编码", "名称", "数量"}},
+		{
+			"两级表头",
+			[][]string{
+				{"物料清单"},
+				{"编码", "规格", "", "数量"},
+				{"", "长", "宽", ""},
+				{"001", "10", "20", "100"},
+			},
+			2, []string{"编码", "规格/长", "规格/宽", "数量"},
+		},
+		{
+			"三级表头",
+			[][]string{
+				{"编码", "库存", "", "", ""},
+				{"", "北京", "", "上海", ""},
+				{"", "数量", "金额", "数量", "金额"},
+				{"001", "1", "2", "3", "4"},
+			},
+			2, []string{"编码", "库存/北京/数量", "库存/北京/金额", "库存/上海/数量", "库存/上海/金额"},
+		},
+	}
+	for _, tt := range tests {
+		index, header := previewHeader(tt.rows)
+		if index != tt.wantIndex || !reflect.DeepEqual(header, tt.wantHeader) {
+			t.Errorf("%s: previewHeader() = %d, %q, want %d, %q", tt.name, index, header, tt.wantIndex, tt.wantHeader)
+		}
+	}
+}
+
+// writePreviewXLSX 生成带标题行、数字、日期和空行的物料表单及一个隐藏表单, 共 rows 行数据
+func writePreviewXLSX(t *testing.T, rows int) string {
+	t.Helper()
+	f := excelize.NewFile()
+	defer f.Close()
+	dateStyle, err := f.NewStyle(&excelize.Style{NumFmt: 14})
+	if err != nil {
+		t.Fatal(err)
+	}
+	data := [][]any{{"物料清单"}, {"编码", "名称", "数量", "日期"}}
+	for i := 1; i <= rows; i++ {
+		data = append(data, []any{fmt.Sprintf("%03d", i), fmt.Sprintf("螺丝%d", i), i * 10, 45123 + i})
+	}
+	for i, row := range data {
+		if i == 3 {
+			// 第 4 行为空行
+			continue
+		}
+		cell, _ := excelize.CoordinatesToCellName(1, i+1)
+		if err = f.SetSheetRow("Sheet1", cell, &row); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err = f.SetCellStyle("Sheet1", "D3", fmt.Sprintf("D%d", len(data)), dateStyle); err != nil {
+		t.Fatal(err)
+	}
+	if err = f.SetSheetName("Sheet1", "物料"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err = f.NewSheet("隐藏"); err != nil {
+		t.Fatal(err)
+	}
+	if err = f.SetSheetVisible("隐藏", false); err != nil {
+		t.Fatal(err)
+	}
+	fileName := filepath.Join(t.TempDir(), "preview.xlsx")
+	if err = f.SaveAs(fileName); err != nil {
+		t.Fatal(err)
+	}
+	return fileName
+}
+
+func TestPreviewXLSX(t *testing.T) {
+	fileName := writePreviewXLSX(t, 200)
+	want, err := OpenExFile(fileName)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// 预览只读取开头的行和用到的共享字符串, 后面的数据损坏不影响预览
+	rewriteZipFile(t, fileName, "xl/worksheets/sheet1.xml", func(content string) string {
+		return strings.Replace(content, `<row r="100"`, `<row r="100"><<`, 1)
+	})
+	rewriteZipFile(t, fileName, "xl/sharedStrings.xml", func(content string) string {
+		return content[:len(content)*3/4]
+	})
+	if _, err = ReadExcelFile(fileName, nil, map[string]string{"编码": "code"}); err == nil {
+		t.Fatal("ReadExcelFile() of the damaged file error = nil, want error")
+	}
+
+	preview, err := Preview(fileName, 5)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(preview.Sheets) != 2 {
+		t.Fatalf("len(Sheets) = %d, want 2", len(preview.Sheets))
+	}
+	sheet := preview.Sheets[0]
+	if sheet.SheetName != "物料" || sheet.Hidden || !preview.Sheets[1].Hidden {
+		t.Errorf("sheets = %q hidden %v, %q hidden %v", sheet.SheetName, sheet.Hidden,
+			preview.Sheets[1].SheetName, preview.Sheets[1].Hidden)
+	}
+	wantRows := want.Sheets[0].Rows[:5]
+	if !reflect.DeepEqual(sheet.Rows, wantRows) {
+		t.Errorf("Rows = %q, want %q", sheet.Rows, wantRows)
+	}
+	if sheet.HeaderRow != 2 || !reflect.DeepEqual(sheet.Header, []string{"编码", "名称", "数量", "日期"}) {
+		t.Errorf("HeaderRow = %d, Header = %q", sheet.HeaderRow, sheet.Header)
+	}
+	if sheet.RowsExact {
+		t.Errorf("RowsExact = true, want false")
+	}
+}
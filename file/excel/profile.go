@@ -0,0 +1,263 @@
+package excelutil
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/zeromicro/go-zero/core/conf"
+)
+
+// ImportProfile 导入配置, 描述一种导入模板, 新的供应商模板只需要新增配置文件.
+// 支持 .yaml, .yml, .json 格式, 例如:
+//
+//	name: 供应商物料
+//	checkTitles: [物料编码, 物料名称]
+//	normalize: [width, space]
+//	uniqueKey: [物料编码]
+//	onDuplicate: keepFirst
+//	sheets: [物料清单]
+//	columns:
+//	  - source: 物料编码
+//	    target: code
+//	    aliases: [料号, 物料编号]
+//	    required: true
+//	    rule: {required: true, pattern: "^M\\d+$"}
+//	  - source: 入库时间
+//	    target: in_time
+//	    type: date
+//	    dateLayout: "2006-01-02"
+//	    inputLayouts: ["2006.01.02", "01/02/2006"]
+//	    timezone: Asia/Shanghai
+//	    excelSerial: true
+//	    strict: true
+//	  - source: 数量
+//	    target: qty
+//	    type: int
+//	    default: "0"
+//	    normalize: [number]
+//	  - source: 身份证号
+//	    target: id_card
+//	    type: text
+type ImportProfile struct {
+	Name           string          `json:"name,optional"`           // 配置名称
+	CheckTitles    []string        `json:"checkTitles,optional"`    // 必须存在的表头
+	Sheets         []string        `json:"sheets,optional"`         // 只读取这些表单, 为空时读取全部表单
+	SheetPattern   string          `json:"sheetPattern,optional"`   // 只读取名称匹配正则表达式的表单
+	SkipHidden     bool            `json:"skipHidden,optional"`     // 跳过隐藏的表单
+	SkipMismatched bool            `json:"skipMismatched,optional"` // 跳过表头不符合要求的表单
+	HeaderScanRows int             `json:"headerScanRows,optional"` // 查找表头时扫描的行数
+	Charset        string          `json:"charset,optional"`        // 文本文件的编码, 为空时自动检测
+	Normalize      []string        `json:"normalize,optional"`      // 所有列的规范化方式, 如 [width, space]
+	UniqueKey      []string        `json:"uniqueKey,optional"`      // 主键列(源表头), 多个表头为联合主键
+	OnDuplicate    string          `json:"onDuplicate,optional"`    // 主键重复的处理方式: error, keepFirst, keepLast, merge, 默认为 error
+	Columns        []ProfileColumn `json:"columns"`                 // 列配置
+}
+
+// ProfileColumn 导入配置中的列
+type ProfileColumn struct {
+	Source     string      `json:"source"`              // 源表头
+	Target     string      `json:"target,optional"`     // 目标表头, 为空时和源表头相同
+	Aliases    []string    `json:"aliases,optional"`    // 源表头的别名
+	Required   bool        `json:"required,optional"`   // 表头是否必须存在
+	Type       string      `json:"type,optional"`       // 数据类型: string, text, int, number, date
+	DateLayout string      `json:"dateLayout,optional"` // 日期列的输出格式, 默认为 timeutil.DefaultTimeLayout
+	Default    *string     `json:"default,optional"`    // 空单元格的默认值
+	Rule       *ColumnRule `json:"rule,optional"`       // 校验规则
+	Normalize  []string    `json:"normalize,optional"`  // 规范化方式, 如 [number], 在所有列的规范化方式之后执行
+
+	InputLayouts []string `json:"inputLayouts,optional"` // 日期列的输入格式
+	Timezone     string   `json:"timezone,optional"`     // 日期列的时区, 如 Asia/Shanghai
+	DateOnly     bool     `json:"dateOnly,optional"`     // 日期列只保留日期
+	ExcelSerial  bool     `json:"excelSerial,optional"`  // 日期列支持 Excel 日期序列号
+	Strict       bool     `json:"strict,optional"`       // 日期列无法解析时记录到 Violations
+}
+
+// LoadImportProfile 加载导入配置文件
+func LoadImportProfile(fileName string) (*ImportProfile, error) {
+	var profile ImportProfile
+	if err := conf.Load(fileName, &profile); err != nil {
+		return nil, fmt.Errorf("加载导入配置%s失败:%v", fileName, err)
+	}
+	if err := profile.Validate(); err != nil {
+		return nil, err
+	}
+	return &profile, nil
+}
+
+// Validate 检查导入配置是否正确
+func (p *ImportProfile) Validate() error {
+	if len(p.Columns) == 0 {
+		return fmt.Errorf("导入配置%s没有列配置", p.Name)
+	}
+	if _, err := regexp.Compile(p.SheetPattern); err != nil {
+		return fmt.Errorf("导入配置%s的表单名称正则表达式%s错误:%v", p.Name, p.SheetPattern, err)
+	}
+	if _, err := lookupNormalizers(p.Normalize); err != nil {
+		return fmt.Errorf("导入配置%s的%v", p.Name, err)
+	}
+	if _, ok := duplicatePolicyNames[p.OnDuplicate]; !ok {
+		return fmt.Errorf("导入配置%s的主键重复处理方式%s不支持", p.Name, p.OnDuplicate)
+	}
+	sources := make(map[string]bool, len(p.Columns))
+	for _, column := range p.Columns {
+		source := strings.TrimSpace(column.Source)
+		if source == "" {
+			return fmt.Errorf("导入配置%s的列缺少源表头", p.Name)
+		}
+		if sources[source] {
+			return fmt.Errorf("导入配置%s的源表头%s重复", p.Name, source)
+		}
+		sources[source] = true
+		switch column.Type {
+		case "", "string", ColumnTypeText, ColumnTypeInt, ColumnTypeNumber, ColumnTypeDate:
+		default:
+			return fmt.Errorf("导入配置%s的列%s类型%s不支持", p.Name, source, column.Type)
+		}
+		if column.Type != ColumnTypeDate && column.hasDateConfig() {
+			return fmt.Errorf("导入配置%s的列%s不是日期列, 不能设置日期格式", p.Name, source)
+		}
+		if _, err := lookupNormalizers(column.Normalize); err != nil {
+			return fmt.Errorf("导入配置%s的列%s的%v", p.Name, source, err)
+		}
+		if _, err := column.location(); err != nil {
+			return fmt.Errorf("导入配置%s的列%s时区%s不正确:%v", p.Name, source, column.Timezone, err)
+		}
+	}
+	return nil
+}
+
+// DstTitleMap 返回源表头到目标表头的映射
+func (p *ImportProfile) DstTitleMap() map[string]string {
+	dstTitleMap := make(map[string]string, len(p.Columns))
+	for _, column := range p.Columns {
+		target := column.Target
+		if target == "" {
+			target = column.Source
+		}
+		dstTitleMap[column.Source] = target
+	}
+	return dstTitleMap
+}
+
+// RequiredTitles 返回必须存在的表头
+func (p *ImportProfile) RequiredTitles() []string {
+	titles := append([]string(nil), p.CheckTitles...)
+	for _, column := range p.Columns {
+		if column.Required {
+			titles = append(titles, column.Source)
+		}
+	}
+	return titles
+}
+
+// hasDateConfig 是否设置了日期列的配置
+func (c *ProfileColumn) hasDateConfig() bool {
+	return c.DateLayout != "" || len(c.InputLayouts) > 0 || c.Timezone != "" || c.DateOnly || c.ExcelSerial || c.Strict
+}
+
+// location 返回日期列的时区, 未设置时返回 nil
+func (c *ProfileColumn) location() (*time.Location, error) {
+	if c.Timezone == "" {
+		return nil, nil
+	}
+	return time.LoadLocation(c.Timezone)
+}
+
+// Options 把导入配置转换为读取选项
+func (p *ImportProfile) Options() []ReadOption {
+	var (
+		opts     []ReadOption
+		rules    []ColumnRule
+		aliases  = make(map[string][]string)
+		defaults = make(map[string]string)
+		texts    []string
+		dates    []DateColumn
+	)
+	if p.HeaderScanRows > 0 {
+		opts = append(opts, WithHeaderScanRows(p.HeaderScanRows))
+	}
+	if len(p.Sheets) > 0 {
+		opts = append(opts, WithSheets(p.Sheets...))
+	}
+	if p.SheetPattern != "" {
+		opts = append(opts, WithSheetPattern(p.SheetPattern))
+	}
+	if p.SkipHidden {
+		opts = append(opts, WithSkipHiddenSheets())
+	}
+	if p.SkipMismatched {
+		opts = append(opts, WithSkipMismatchedSheets())
+	}
+	if p.Charset != "" {
+		opts = append(opts, WithCharset(p.Charset))
+	}
+	if len(p.Normalize) > 0 {
+		opts = append(opts, WithNormalizeAll(p.Normalize...))
+	}
+	if len(p.UniqueKey) > 0 {
+		opts = append(opts, WithUniqueKey(p.UniqueKey...), WithDuplicatePolicy(duplicatePolicyNames[p.OnDuplicate]))
+	}
+	for _, column := range p.Columns {
+		if len(column.Aliases) > 0 {
+			aliases[column.Source] = column.Aliases
+		}
+		if column.Default != nil {
+			defaults[column.Source] = *column.Default
+		}
+		if len(column.Normalize) > 0 {
+			opts = append(opts, WithNormalizers(column.Source, column.Normalize...))
+		}
+		if column.Type == ColumnTypeDate {
+			// Validate 已检查过时区
+			loc, _ := column.location()
+			dates = append(dates, DateColumn{
+				Title:        column.Source,
+				InputLayouts: column.InputLayouts,
+				OutputLayout: column.DateLayout,
+				Location:     loc,
+				DateOnly:     column.DateOnly,
+				ExcelSerial:  column.ExcelSerial,
+				Strict:       column.Strict,
+			})
+		}
+		var rule ColumnRule
+		if column.Rule != nil {
+			rule = *column.Rule
+		}
+		// 严格模式的日期列在转换日期时已经记录无法解析的单元格, 不再添加类型规则, 避免同一个单元格报两次错
+		if column.Type == ColumnTypeText {
+			texts = append(texts, column.Source)
+		} else if rule.Type == "" && column.Type != "string" && !(column.Type == ColumnTypeDate && column.Strict) {
+			rule.Type = column.Type
+		}
+		if column.Rule != nil || rule.Type != "" {
+			rule.Title = column.Source
+			rules = append(rules, rule)
+		}
+	}
+	opts = append(opts, WithTitleAliases(aliases), WithDefaults(defaults))
+	if len(dates) > 0 {
+		opts = append(opts, WithDateColumns(dates...))
+	}
+	if len(texts) > 0 {
+		opts = append(opts, WithTextColumns(texts...))
+	}
+	if len(rules) > 0 {
+		opts = append(opts, WithRules(rules...))
+	}
+	return opts
+}
+
+// ReadExcelFileWithProfile 按导入配置读取Excel文件, opts 在配置生成的选项之后应用:
+// 单个值的选项(如 WithCharset、WithHeaderScanRows、WithDuplicatePolicy、WithDefaults、WithDateColumns)覆盖配置中的值;
+// 列表选项(WithSheets、WithSheetPattern、WithRules、WithTitleAliases、WithNormalizers、WithNormalizeAll、
+// WithUniqueKey、WithTextColumns)追加到配置之后, 不会替换配置中的列表
+func ReadExcelFileWithProfile(fileName string, profile *ImportProfile, opts ...ReadOption) (*ExcelFile, error) {
+	if err := profile.Validate(); err != nil {
+		return nil, err
+	}
+	return ReadExcelFile(fileName, profile.RequiredTitles(), profile.DstTitleMap(),
+		append(profile.Options(), opts...)...)
+}
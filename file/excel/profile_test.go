@@ -0,0 +1,84 @@
+package excelutil
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+const testProfile = `name: 供应商物料
+checkTitles: [物料编码]
+uniqueKey: [物料编码]
+onDuplicate: keepFirst
+columns:
+  - source: 物料编码
+    target: code
+    aliases: [料号]
+    required: true
+    rule: {pattern: "^M\\d+$"}
+  - source: 入库时间
+    target: in_time
+    type: date
+    dateOnly: true
+    inputLayouts: ["2006.01.02"]
+    strict: true
+  - source: 数量
+    target: qty
+    type: int
+    default: "0"
+`
+
+func TestLoadImportProfile(t *testing.T) {
+	profile, err := LoadImportProfile(writeTestFile(t, "profile.yaml", testProfile))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := map[string]string{"物料编码": "code", "入库时间": "in_time", "数量": "qty"}; !reflect.DeepEqual(profile.DstTitleMap(), want) {
+		t.Errorf("DstTitleMap() = %v, want %v", profile.DstTitleMap(), want)
+	}
+	if want := []string{"物料编码", "物料编码"}; !reflect.DeepEqual(profile.RequiredTitles(), want) {
+		t.Errorf("RequiredTitles() = %v, want %v", profile.RequiredTitles(), want)
+	}
+
+	fileName := writeTestFile(t, "material.csv",
+		"料号,入库时间,数量\nM001,2023.07.16,\nX002,昨天,abc\nM001,2023.07.17,5\n")
+	file, err := ReadExcelFileWithProfile(fileName, profile)
+	if err != nil {
+		t.Fatal(err)
+	}
+	wantRows := [][]string{{"M001", "2023-07-16", "0"}, {"X002", "昨天", "abc"}}
+	if !reflect.DeepEqual(file.Sheets[0].Rows, wantRows) {
+		t.Errorf("rows = %q, want %q", file.Sheets[0].Rows, wantRows)
+	}
+	// 严格模式的日期列只报一次日期错误, 数量列按类型规则校验
+	var violations []string
+	for _, v := range file.Violations {
+		violations = append(violations, v.Column+":"+v.Rule)
+	}
+	if want := []string{"A:" + RulePattern, "C:" + RuleType, "B:" + RuleDate}; !reflect.DeepEqual(violations, want) {
+		t.Errorf("violations = %v, want %v", violations, want)
+	}
+	if got := file.Report.Sheets[0].DuplicateRows; !reflect.DeepEqual(got, []int{4}) {
+		t.Errorf("DuplicateRows = %v, want [4]", got)
+	}
+}
+
+func TestLoadImportProfileInvalid(t *testing.T) {
+	tests := []struct {
+		name    string
+		content string
+		want    string
+	}{
+		{"没有列", "name: 空\n", "加载导入配置"},
+		{"类型不支持", "name: a\ncolumns:\n  - source: 数量\n    type: float\n", "类型float不支持"},
+		{"非日期列设置日期格式", "name: a\ncolumns:\n  - source: 数量\n    strict: true\n", "不是日期列"},
+		{"源表头重复", "name: a\ncolumns:\n  - source: 数量\n  - source: 数量\n", "源表头数量重复"},
+		{"主键重复处理方式", "name: a\nonDuplicate: skip\ncolumns:\n  - source: 数量\n", "主键重复处理方式skip不支持"},
+	}
+	for _, tt := range tests {
+		_, err := LoadImportProfile(writeTestFile(t, "profile.yaml", tt.content))
+		if err == nil || !strings.Contains(err.Error(), tt.want) {
+			t.Errorf("%s: LoadImportProfile() error = %v, want containing %q", tt.name, err, tt.want)
+		}
+	}
+}
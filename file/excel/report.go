@@ -0,0 +1,37 @@
+package excelutil
+
+import (
+	"time"
+)
+
+// ImportReport 导入报告, 记录读取过程中被调整或忽略的数据
+type ImportReport struct {
+	StartTime time.Time      // 开始读取的时间
+	Duration  time.Duration  // 总耗时
+	TotalRow  int            // 读取的数据总行数
+	Sheets    []*SheetReport // 每个表单的导入情况
+
+	SkippedSheets []*SkippedSheet // 隐藏或表头不符合要求被跳过的表单
+	Duplicates    []*DuplicateKey // 重复的主键, 批量读取时包括和其他文件重复的主键
+}
+
+// SkippedSheet 被跳过的表单
+type SkippedSheet struct {
+	SheetName string // 表单名称
+	Reason    string // 跳过的原因
+}
+
+// SheetReport 表单导入报告
+type SheetReport struct {
+	SheetName       string        // 表单名称
+	HeaderRow       int           // 表头所在行号
+	RowCount        int           // 读取的数据行数
+	SkippedRows     []int         // 映射列全为空被跳过的行号
+	DroppedColumns  []string      // 数据全为空被去掉的列(源表头)
+	UnmappedColumns []string      // 没有映射的列(源表头)
+	Duration        time.Duration // 耗时
+
+	PrecisionLostColumns []string        // 数字精度已丢失的列(源表头), 需要设置为文本格式后重新导出
+	FormulaErrors        []*FormulaError // 公式计算失败的单元格
+	DuplicateRows        []int           // 主键重复按处理方式去掉的行号
+}
@@ -0,0 +1,1223 @@
+package excelutil
+
+import (
+	"archive/zip"
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+
+	"go_file/common"
+
+	"github.com/extrame/xls"
+	"github.com/xuri/excelize/v2"
+	"github.com/zeromicro/go-zero/core/logx"
+)
+
+// csvSampleSize 检测文本文件编码和分隔符时读取的字节数
+const csvSampleSize = 64 * 1024
+
+// defaultDateColumn 未设置日期列时, 目标表头包含 "时间" 的列使用的日期配置
+var defaultDateColumn = &DateColumn{}
+
+// sheetSource 按表单逐行提供原始单元格数据
+type sheetSource interface {
+	// NextSheet 切换到下一个表单, 没有更多表单时返回 false
+	NextSheet() bool
+	// SheetName 当前表单名称
+	SheetName() string
+	// SheetIndex 当前表单在工作簿中的位置, 从 0 开始, 包括读取器跳过的表单
+	SheetIndex() int
+	// Hidden 当前表单是否隐藏
+	Hidden() bool
+	// MergeRanges 当前表单的合并单元格区域, 不支持合并单元格的格式返回 nil
+	MergeRanges() []MergeRange
+	// NextRow 读取当前表单的下一行, 表单读完时返回 false
+	NextRow() bool
+	// Cells 当前行的单元格数据
+	Cells() []string
+	// RawCells 当前行单元格保存的原始值, 不应用数字格式; 文本文件和 Cells 相同
+	RawCells() []string
+	// Date1904 是否使用 1904 日期系统
+	Date1904() bool
+	Err() error
+	Close() error
+}
+
+// RowReader 逐行读取Excel文件, 按 checkTitles 校验表头, 按 dstTitleMap 映射列,
+// 不会把整个文件的数据加载到内存中. 用法:
+//
+//	r, err := NewRowReader(fileName, checkTitles, dstTitleMap)
+//	if err != nil {
+//		return err
+//	}
+//	defer r.Close()
+//	for r.NextSheet() {
+//		for r.Next() {
+//			fmt.Println(r.SheetName(), r.Header(), r.Row())
+//		}
+//	}
+//	if err := r.Err(); err != nil {
+//		return err
+//	}
+type RowReader struct {
+	fileName    string
+	checkTitles []string
+	src         sheetSource
+	options     *readOptions
+	matcher     *titleMatcher
+
+	header       []string        // 映射后的表头
+	srcHeader    []string        // 原始表头
+	colIndexes   []int           // 映射后的每一列在原始数据中的列下标
+	colConfigs   []*columnConfig // 原始数据每一列的读取配置
+	normalizer   [][]Normalizer  // 原始数据每一列的规范化函数
+	headerRow    int             // 表头所在行号
+	pending      []sourceRow     // 查找表头时多读取的数据行
+	merges       []MergeRange    // 当前表单的合并单元格区域, 需要时才读取
+	mergesLoaded bool
+	filler       *mergeFiller // 设置 WithFillMergedCells 时填充合并单元格
+	skipped      []int        // 当前表单跳过的空行行号
+	rules        []*boundRule
+	row          []string
+	cells        []Cell // 当前行带类型的数据, 设置 WithTypedCells 时才有
+	violations   []*Violation
+	rowNum       int
+	lostTitles   []string // 当前表单中数字精度已丢失的列(源表头)
+	err          error
+
+	sheetPatterns []*regexp.Regexp
+	sheetCount    int             // 已读取的表单数
+	skippedSheets []*SkippedSheet // 被跳过的表单
+	mismatchErr   error           // 第一个表头不符合要求的表单的错误
+}
+
+// NewRowReader 根据文件内容检测格式, 创建逐行读取器
+func NewRowReader(fileName string, checkTitles []string, dstTitleMap map[string]string,
+	opts ...ReadOption) (*RowReader, error) {
+	options := newReadOptions(opts...)
+	src, err := newSheetSource(fileName, options)
+	if err != nil {
+		return nil, err
+	}
+	return newRowReader(fileName, src, checkTitles, dstTitleMap, options), nil
+}
+
+// newSheetSource 根据文件内容检测的格式打开原始数据读取器
+func newSheetSource(fileName string, options *readOptions) (sheetSource, error) {
+	fileType, err := DetectFileType(fileName)
+	if err != nil {
+		return nil, err
+	}
+	return openFileSource(fileName, fileType, options)
+}
+
+// openFileSource 按指定格式打开文件, 文件在读取器关闭时关闭
+func openFileSource(fileName, fileType string, options *readOptions) (sheetSource, error) {
+	file, err := os.Open(fileName)
+	if err != nil {
+		logx.Errorf("openFileSource:%s, failed to open file: %v", fileName, err)
+		return nil, err
+	}
+	info, err := file.Stat()
+	if err != nil {
+		file.Close()
+		return nil, err
+	}
+	src, err := openSheetSource(file, info.Size(), fileName, fileType, file, options)
+	if err != nil {
+		file.Close()
+		return nil, err
+	}
+	return src, nil
+}
+
+// openSheetSource 按指定格式打开原始数据读取器, closer 在读取器关闭时关闭, 可以为 nil
+func openSheetSource(r io.ReaderAt, size int64, fileName, fileType string, closer io.Closer,
+	options *readOptions) (sheetSource, error) {
+	switch fileType {
+	case common.FileTypeXlsx:
+		return newXLSXSource(r, size, fileName, closer, options.password, options.formula)
+	case common.FileTypeXls:
+		return newXLSSource(r, size, closer)
+	case common.FileTypeCsv, common.FileTypeTsv, common.FileTypeTxt:
+		return newCSVSource(io.NewSectionReader(r, 0, size), closer, options.charset, options.delimiter,
+			defaultDelimiter(fileName, fileType))
+	case common.FileTypeHtml:
+		return newHTMLSource(io.NewSectionReader(r, 0, size), closer, options.charset)
+	case common.FileTypeOds:
+		return newODSSource(r, size, closer, options.formula == FormulaText)
+	}
+	return nil, &UnsupportedFormatError{FileName: fileName, Format: fileType}
+}
+
+func newRowReader(fileName string, src sheetSource, checkTitles []string, dstTitleMap map[string]string,
+	options *readOptions) *RowReader {
+	// 复制一份, 避免调用方修改映射影响读取
+	titleMap := make(map[string]string, len(dstTitleMap))
+	for src, dst := range dstTitleMap {
+		titleMap[src] = dst
+	}
+	r := &RowReader{
+		fileName:    fileName,
+		checkTitles: checkTitles,
+		src:         src,
+		options:     options,
+		matcher:     newTitleMatcher(titleMap, options.titleAliases),
+		err:         compileRules(options.rules),
+	}
+	for _, pattern := range options.sheetPatterns {
+		re, err := regexp.Compile(pattern)
+		if err != nil {
+			r.err = fmt.Errorf("表单名称正则表达式%s错误:%v", pattern, err)
+			break
+		}
+		r.sheetPatterns = append(r.sheetPatterns, re)
+	}
+	return r
+}
+
+// NextSheet 切换到下一个表单, 查找并校验表头, 没有更多表单或出错时返回 false
+func (r *RowReader) NextSheet() bool {
+	for r.err == nil {
+		r.header, r.srcHeader, r.colIndexes, r.colConfigs, r.normalizer, r.pending = nil, nil, nil, nil, nil, nil
+		r.rules, r.row, r.cells, r.violations, r.skipped = nil, nil, nil, nil, nil
+		r.merges, r.mergesLoaded, r.filler, r.lostTitles = nil, false, nil, nil
+		r.headerRow, r.rowNum = 0, 0
+		if !r.nextSelectedSheet() {
+			if r.err = r.src.Err(); r.err == nil && r.sheetCount == 0 {
+				r.err = r.mismatchErr
+			}
+			return false
+		}
+		mismatch := r.readHeader()
+		if r.err != nil {
+			return false
+		}
+		if mismatch == nil {
+			r.sheetCount++
+			return true
+		}
+		if !r.options.skipMismatched {
+			r.err = mismatch
+			return false
+		}
+		r.skipSheet(mismatch.Error())
+		if r.mismatchErr == nil {
+			r.mismatchErr = mismatch
+		}
+	}
+	return false
+}
+
+// readHeader 查找并校验当前表单的表头, 表单没有数据或表头不符合要求时返回错误, 读取数据出错时设置 r.err
+func (r *RowReader) readHeader() error {
+	// 读取前几行用于查找表头, 单元格切片可能被复用, 需要复制
+	scanned := make([]sourceRow, 0, r.options.headerScanRows)
+	rows := make([][]string, 0, r.options.headerScanRows)
+	for len(scanned) < r.options.headerScanRows && r.src.NextRow() {
+		row := r.currentRow(true)
+		scanned = append(scanned, row)
+		rows = append(rows, row.cells)
+	}
+	if r.err = r.src.Err(); r.err != nil {
+		return nil
+	}
+	index, header := detectHeader(rows, r.checkTitles, r.matcher)
+	if index < 0 {
+		return fmt.Errorf("no rows found in sheet")
+	}
+	if err := r.matcher.checkExcelTitle(header, r.checkTitles); err != nil {
+		return err
+	}
+	if r.options.fillMerged {
+		if r.loadMerges(); r.err != nil {
+			return nil
+		}
+		// 表头不填充, 只记录从表头及以上开始的区域的值
+		r.filler = newMergeFiller(r.merges)
+		for i := 0; i <= index; i++ {
+			r.filler.observe(i+1, rows[i])
+		}
+	}
+	r.srcHeader = header
+	r.headerRow = index + 1
+	r.rowNum = r.headerRow
+	r.pending = scanned[index+1:]
+	for i, title := range r.srcHeader {
+		if dst, ok := r.matcher.dstTitle(title); ok {
+			r.header = append(r.header, dst)
+			r.colIndexes = append(r.colIndexes, i)
+		}
+	}
+	r.colConfigs = r.bindColumnConfigs()
+	if r.normalizer, r.err = r.bindNormalizers(); r.err != nil {
+		return nil
+	}
+	r.rules = bindRules(r.srcHeader, r.options.rules, r.matcher)
+	for _, rule := range r.rules {
+		if r.colConfigs != nil && r.colConfigs[rule.col] != nil {
+			rule.date, rule.date1904 = r.colConfigs[rule.col].date, r.src.Date1904()
+		}
+	}
+	return nil
+}
+
+// nextSelectedSheet 切换到下一个需要读取的表单
+func (r *RowReader) nextSelectedSheet() bool {
+	for r.src.NextSheet() {
+		if !r.sheetSelected() {
+			continue
+		}
+		if r.options.skipHidden && r.src.Hidden() {
+			r.skipSheet("隐藏的表单")
+			continue
+		}
+		return true
+	}
+	return false
+}
+
+// sheetSelected 当前表单是否满足 WithSheets、WithSheetIndexes、WithSheetPattern 中的任一条件, 都没有设置时全部选中
+func (r *RowReader) sheetSelected() bool {
+	o := r.options
+	if len(o.sheetNames) == 0 && len(o.sheetIndexes) == 0 && len(r.sheetPatterns) == 0 {
+		return true
+	}
+	name := strings.TrimSpace(r.src.SheetName())
+	for _, selected := range o.sheetNames {
+		if strings.TrimSpace(selected) == name {
+			return true
+		}
+	}
+	for _, index := range o.sheetIndexes {
+		if index == r.src.SheetIndex() {
+			return true
+		}
+	}
+	for _, re := range r.sheetPatterns {
+		if re.MatchString(name) {
+			return true
+		}
+	}
+	return false
+}
+
+// skipSheet 记录被跳过的表单
+func (r *RowReader) skipSheet(reason string) {
+	r.skippedSheets = append(r.skippedSheets, &SkippedSheet{SheetName: r.src.SheetName(), Reason: reason})
+}
+
+// bindColumnConfigs 根据表头找到每一列的读取配置
+func (r *RowReader) bindColumnConfigs() []*columnConfig {
+	if len(r.options.columns) == 0 {
+		return nil
+	}
+	configs := make(map[string]*columnConfig, len(r.options.columns))
+	for title, config := range r.options.columns {
+		configs[r.matcher.canonical(title)] = config
+	}
+	colConfigs := make([]*columnConfig, len(r.srcHeader))
+	for i, title := range r.srcHeader {
+		if title != "" {
+			colConfigs[i] = configs[r.matcher.canonical(title)]
+		}
+	}
+	return colConfigs
+}
+
+// bindNormalizers 找到每个映射列的规范化函数, 没有设置规范化时返回 nil
+func (r *RowReader) bindNormalizers() ([][]Normalizer, error) {
+	var normalizer [][]Normalizer
+	for _, idx := range r.colIndexes {
+		names := r.options.normalizers
+		if r.colConfigs != nil && r.colConfigs[idx] != nil {
+			names = append(names[:len(names):len(names)], r.colConfigs[idx].normalizers...)
+		}
+		if len(names) == 0 {
+			continue
+		}
+		fns, err := lookupNormalizers(names)
+		if err != nil {
+			return nil, fmt.Errorf("列[%s]的%v", r.srcHeader[idx], err)
+		}
+		if normalizer == nil {
+			normalizer = make([][]Normalizer, len(r.srcHeader))
+		}
+		normalizer[idx] = fns
+	}
+	return normalizer, nil
+}
+
+// Next 读取当前表单的下一行数据, 跳过映射列全为空的行
+func (r *RowReader) Next() bool {
+	if r.err != nil || r.srcHeader == nil {
+		return false
+	}
+	for {
+		row, ok := r.nextSourceRow()
+		if !ok {
+			break
+		}
+		cells := r.applyTextColumns(&row)
+		r.rowNum++
+		r.checkPrecision(cells)
+		normalizeViolations := r.normalizeRow(cells, &row)
+		// 只有合并单元格的值的行仍然视为空行
+		r.filler.observe(r.rowNum, cells)
+		if r.isEmptyRow(cells) {
+			r.skipped = append(r.skipped, r.rowNum)
+			continue
+		}
+		cells = r.filler.fill(cells)
+		cells = r.applyDefaults(cells)
+		r.violations = append(normalizeViolations, validateRow(r.SheetName(), r.rowNum, cells, r.rules)...)
+		var dateViolations []*Violation
+		r.row, dateViolations = r.mapRow(cells)
+		r.violations = append(r.violations, dateViolations...)
+		if r.options.typedCells {
+			r.cells = r.typedRow(cells, row.hints)
+		}
+		return true
+	}
+	r.row, r.cells, r.violations = nil, nil, nil
+	r.err = r.src.Err()
+	return false
+}
+
+// sourceRow 原始数据的一行
+type sourceRow struct {
+	cells []string
+	raw   []string   // 单元格保存的原始值, 设置了文本列时才读取
+	hints []cellHint // 单元格类型, 设置 WithTypedCells 时才读取
+}
+
+// currentRow 读取原始数据的当前行, copied 为 true 时复制单元格, 读取器可能复用切片
+func (r *RowReader) currentRow(copied bool) sourceRow {
+	row := sourceRow{cells: r.src.Cells()}
+	if r.options.hasTextColumns {
+		row.raw = r.src.RawCells()
+	}
+	if src, ok := r.src.(typedSource); ok && r.options.typedCells {
+		row.hints = src.CellHints()
+	}
+	if copied {
+		row.cells = append([]string(nil), row.cells...)
+		row.raw = append([]string(nil), row.raw...)
+	}
+	return row
+}
+
+// nextSourceRow 读取下一行数据, 先返回查找表头时多读取的行
+func (r *RowReader) nextSourceRow() (sourceRow, bool) {
+	if len(r.pending) > 0 {
+		row := r.pending[0]
+		r.pending = r.pending[1:]
+		return row, true
+	}
+	if !r.src.NextRow() {
+		return sourceRow{}, false
+	}
+	return r.currentRow(r.writesCells()), true
+}
+
+// writesCells 文本列、规范化和填充合并单元格会直接修改单元格切片, 读取器可能在多行之间共享切片
+// (如 .ods 的重复行), 这时需要先复制
+func (r *RowReader) writesCells() bool {
+	return r.options.hasTextColumns || r.normalizer != nil || r.filler != nil
+}
+
+// applyTextColumns 文本列使用单元格保存的原始值; 文本文件的原始值就是文件中的文本, 保持原样
+func (r *RowReader) applyTextColumns(row *sourceRow) []string {
+	cells := row.cells
+	if row.raw == nil {
+		return cells
+	}
+	for i, config := range r.colConfigs {
+		if config == nil || !config.text {
+			continue
+		}
+		for len(cells) <= i {
+			cells = append(cells, "")
+		}
+		value := ""
+		if i < len(row.raw) {
+			value = row.raw[i]
+		}
+		cells[i] = value
+	}
+	return cells
+}
+
+// normalizeRow 按列的规范化方式处理当前行, 返回无法规范化的单元格; 值改变的单元格按文本推断类型
+func (r *RowReader) normalizeRow(cells []string, row *sourceRow) []*Violation {
+	var violations []*Violation
+	copied := false
+	for idx, fns := range r.normalizer {
+		if len(fns) == 0 || idx >= len(cells) || cells[idx] == "" {
+			continue
+		}
+		value, err := normalizeValue(cells[idx], fns)
+		if err != nil {
+			violations = append(violations, r.newViolation(idx, RuleNormalize, cells[idx], err.Error()))
+			continue
+		}
+		if value == cells[idx] {
+			continue
+		}
+		cells[idx] = value
+		if idx < len(row.hints) {
+			if !copied {
+				row.hints, copied = append([]cellHint(nil), row.hints...), true
+			}
+			row.hints[idx] = cellHint{}
+		}
+	}
+	return violations
+}
+
+// typedRow 创建映射后的带类型的一行, 日期转换等改变了值的单元格按文本推断类型
+func (r *RowReader) typedRow(cells []string, hints []cellHint) []Cell {
+	typed := make([]Cell, len(r.row))
+	for i, idx := range r.colIndexes {
+		var hint cellHint
+		if idx < len(cells) && idx < len(hints) && r.row[i] == cells[idx] {
+			hint = hints[idx]
+		}
+		if r.colConfigs != nil && r.colConfigs[idx] != nil && r.colConfigs[idx].text {
+			hint = cellHint{kind: CellKindString}
+		}
+		typed[i] = newCell(r.row[i], hint, r.src.Date1904())
+	}
+	return typed
+}
+
+// checkPrecision 在规范化和日期转换之前检查当前行的原始数据, 记录数字精度已丢失的映射列
+func (r *RowReader) checkPrecision(cells []string) {
+	for _, idx := range r.colIndexes {
+		if idx >= len(cells) || !precisionLost(cells[idx]) {
+			continue
+		}
+		title := r.srcHeader[idx]
+		if !slices.Contains(r.lostTitles, title) {
+			r.lostTitles = append(r.lostTitles, title)
+		}
+	}
+}
+
+// isEmptyRow 判断映射的列是否全为空
+func (r *RowReader) isEmptyRow(cells []string) bool {
+	for _, idx := range r.colIndexes {
+		if idx < len(cells) && cells[idx] != "" {
+			return false
+		}
+	}
+	return true
+}
+
+// applyDefaults 空单元格使用默认值, 有默认值时返回新的切片
+func (r *RowReader) applyDefaults(cells []string) []string {
+	var filled []string
+	for i, config := range r.colConfigs {
+		if config == nil || !config.hasDefault || i < len(cells) && cells[i] != "" {
+			continue
+		}
+		if filled == nil {
+			filled = make([]string, len(r.colConfigs))
+			copy(filled, cells)
+		}
+		filled[i] = config.defaultValue
+	}
+	if filled == nil {
+		return cells
+	}
+	return filled
+}
+
+// mapRow 根据映射关系提取列数据, 并统一时间格式, 严格模式的日期列无法解析时返回校验错误
+func (r *RowReader) mapRow(cells []string) ([]string, []*Violation) {
+	var violations []*Violation
+	row := make([]string, len(r.colIndexes))
+	for i, idx := range r.colIndexes {
+		if idx >= len(cells) {
+			continue
+		}
+		value := cells[idx]
+		if column := r.dateColumn(i, idx); column != nil && value != "" {
+			formatted, err := column.format(value, r.src.Date1904())
+			switch {
+			case err == nil:
+				value = formatted
+			case column.Strict:
+				violations = append(violations, r.newViolation(idx, RuleDate, value, "不是有效的日期"))
+			}
+		}
+		row[i] = value
+	}
+	return row, violations
+}
+
+// dateColumn 返回映射后第 i 列(原始数据第 idx 列)的日期配置, 不是日期列或是文本列时返回 nil
+func (r *RowReader) dateColumn(i, idx int) *DateColumn {
+	if r.colConfigs != nil && r.colConfigs[idx] != nil {
+		if config := r.colConfigs[idx]; config.text {
+			return nil
+		} else if config.date != nil {
+			return config.date
+		}
+	}
+	if !r.options.hasDateColumns && strings.Contains(r.header[i], "时间") {
+		return defaultDateColumn
+	}
+	return nil
+}
+
+// newViolation 创建当前行原始数据第 idx 列的校验错误
+func (r *RowReader) newViolation(idx int, rule, value, message string) *Violation {
+	column, _ := excelize.ColumnNumberToName(idx + 1)
+	return &Violation{
+		Sheet:   r.SheetName(),
+		Row:     r.rowNum,
+		Column:  column,
+		Title:   r.srcHeader[idx],
+		Rule:    rule,
+		Value:   value,
+		Message: message,
+	}
+}
+
+// SheetName 当前表单名称
+func (r *RowReader) SheetName() string {
+	return r.src.SheetName()
+}
+
+// Header 当前表单映射后的表头
+func (r *RowReader) Header() []string {
+	return r.header
+}
+
+// SourceHeader 当前表单的原始表头
+func (r *RowReader) SourceHeader() []string {
+	return r.srcHeader
+}
+
+// UnmappedColumns 当前表单中没有映射的列(源表头)
+func (r *RowReader) UnmappedColumns() []string {
+	var unmapped []string
+	for _, title := range r.srcHeader {
+		if _, ok := r.matcher.lookup(title); title != "" && !ok {
+			unmapped = append(unmapped, title)
+		}
+	}
+	return unmapped
+}
+
+// Cells 当前行带类型的数据, 和 Row 对应, 只在设置 WithTypedCells 时返回
+func (r *RowReader) Cells() []Cell {
+	return r.cells
+}
+
+// MergeRanges 当前表单的合并单元格区域, 位置为原始表单中的行列号; 第一次调用时读取
+func (r *RowReader) MergeRanges() []MergeRange {
+	if r.srcHeader != nil && r.err == nil {
+		r.loadMerges()
+	}
+	return r.merges
+}
+
+// loadMerges 读取当前表单的合并单元格区域, 只读取一次
+func (r *RowReader) loadMerges() {
+	if r.mergesLoaded {
+		return
+	}
+	r.merges, r.mergesLoaded = r.src.MergeRanges(), true
+	r.err = r.src.Err()
+}
+
+// PrecisionLostColumns 当前表单中已读取的行里, 数字精度已丢失的列(源表头),
+// 如以数字格式保存的身份证号, 需要把这些列设置为文本格式后重新导出
+func (r *RowReader) PrecisionLostColumns() []string {
+	return r.lostTitles
+}
+
+// FormulaErrors 当前表单中已读取的行里, 公式计算失败的单元格, 只在 FormulaEvaluate 方式下记录
+func (r *RowReader) FormulaErrors() []*FormulaError {
+	if src, ok := r.src.(formulaSource); ok {
+		return src.FormulaErrors()
+	}
+	return nil
+}
+
+// SkippedSheets 到目前为止因隐藏或表头不符合要求被跳过的表单
+func (r *RowReader) SkippedSheets() []*SkippedSheet {
+	return r.skippedSheets
+}
+
+// SkippedRows 当前表单中已读取的行里, 映射列全为空被跳过的行号
+func (r *RowReader) SkippedRows() []int {
+	return r.skipped
+}
+
+// Row 当前行映射后的数据, 与 Header 一一对应
+func (r *RowReader) Row() []string {
+	return r.row
+}
+
+// Violations 当前行不符合校验规则的单元格
+func (r *RowReader) Violations() []*Violation {
+	return r.violations
+}
+
+// RowNum 当前行在表单中的行号, 从1开始
+func (r *RowReader) RowNum() int {
+	return r.rowNum
+}
+
+// HeaderRow 当前表单表头所在的行号, 从1开始, 多级表头为最后一级所在的行号
+func (r *RowReader) HeaderRow() int {
+	return r.headerRow
+}
+
+// Err 返回读取过程中遇到的错误
+func (r *RowReader) Err() error {
+	return r.err
+}
+
+// Close 关闭文件
+func (r *RowReader) Close() error {
+	return r.src.Close()
+}
+
+// xlsxSource 基于 excelize 的流式读取 .xlsx 文件, 单元格按数字格式输出
+type xlsxSource struct {
+	file     *excelize.File
+	zipFiles map[string]*zip.File // 表单名称 -> 表单 XML, 用于流式读取合并单元格; 加密的文件为 nil
+	closer   io.Closer
+	sheets   []string
+	date1904 bool
+	index    int
+	rows     *excelize.Rows
+	rowNum   int // 当前行号
+	cells    []string
+	rawRows  *excelize.Rows // 读取原始值的迭代器, 需要时才打开
+	rawNum   int            // rawRows 的当前行号
+	rawCells []string
+	formula  FormulaMode
+	failures []*FormulaError  // 当前表单中公式计算失败的单元格
+	hinter   *xlsxHinter      // 读取单元格类型, 需要时才创建
+	scanner  *xlsxCellScanner // 流式读取当前表单的单元格属性, 需要时才打开
+	err      error
+}
+
+func newXLSXSource(r io.ReaderAt, size int64, fileName string, closer io.Closer, password string,
+	formula FormulaMode) (*xlsxSource, error) {
+	f, err := openXLSX(r, size, fileName, password, xlsxDateOptions)
+	if err != nil {
+		return nil, err
+	}
+	props, err := f.GetWorkbookProps()
+	if err != nil {
+		f.Close()
+		return nil, err
+	}
+	s := &xlsxSource{file: f, closer: closer, sheets: f.GetSheetList(), index: -1, formula: formula}
+	if zr, err := zip.NewReader(r, size); err == nil {
+		// 加密的文件不是 zip 文件, 读取失败时使用 excelize 读取合并单元格
+		s.zipFiles, _ = xlsxSheetFiles(zr)
+	}
+	if props.Date1904 != nil {
+		s.date1904 = *props.Date1904
+	}
+	return s, nil
+}
+
+func (s *xlsxSource) NextSheet() bool {
+	if s.err != nil {
+		return false
+	}
+	if s.rows != nil {
+		if s.err = s.rows.Close(); s.err != nil {
+			return false
+		}
+		s.rows = nil
+	}
+	if s.rawRows != nil {
+		if s.err = s.rawRows.Close(); s.err != nil {
+			return false
+		}
+		s.rawRows = nil
+	}
+	if s.scanner != nil {
+		if s.err = s.scanner.Close(); s.err != nil {
+			return false
+		}
+		s.scanner = nil
+	}
+	s.rowNum, s.rawNum, s.rawCells, s.failures = 0, 0, nil, nil
+	s.index++
+	if s.index >= len(s.sheets) {
+		return false
+	}
+	s.rows, s.err = s.file.Rows(s.sheets[s.index])
+	return s.err == nil
+}
+
+func (s *xlsxSource) SheetName() string {
+	if s.index < 0 || s.index >= len(s.sheets) {
+		return ""
+	}
+	return s.sheets[s.index]
+}
+
+func (s *xlsxSource) SheetIndex() int {
+	return s.index
+}
+
+// Hidden 隐藏和深度隐藏的表单都返回 true
+func (s *xlsxSource) Hidden() bool {
+	visible, err := s.file.GetSheetVisible(s.SheetName())
+	return err == nil && !visible
+}
+
+func (s *xlsxSource) MergeRanges() []MergeRange {
+	if s.err != nil {
+		return nil
+	}
+	var ranges []MergeRange
+	if f := s.zipFiles[s.SheetName()]; f != nil {
+		ranges, s.err = zipMergeRanges(f)
+		return ranges
+	}
+	// excelize 的 GetMergeCells 会加载整个表单, 只用于加密的文件, 这类文件已经全部解密到内存中
+	ranges, s.err = xlsxMergeRanges(s.file, s.SheetName())
+	return ranges
+}
+
+// NextRow 以科学计数法显示的数字(常规格式的长数字或科学计数格式)使用原始值输出完整的数字,
+// 形如科学计数法的文本保持原样; 公式单元格按设置的方式输出
+func (s *xlsxSource) NextRow() bool {
+	if s.err != nil || s.rows == nil || !s.rows.Next() {
+		return false
+	}
+	s.rowNum++
+	if s.cells, s.err = s.rows.Columns(); s.err != nil {
+		return false
+	}
+	for i, value := range s.cells {
+		if !isScientific(value) {
+			continue
+		}
+		raw := s.RawCells()
+		if s.err != nil {
+			return false
+		}
+		if i < len(raw) {
+			s.cells[i] = raw[i]
+		}
+	}
+	if s.formula == FormulaCached {
+		return true
+	}
+	attrs, err := s.cellAttrs()
+	if err != nil {
+		s.err = err
+		return false
+	}
+	failures, err := applyFormulas(s.file, s.SheetName(), s.rowNum, s.cells, s.formula, attrs)
+	if err != nil {
+		s.err = err
+		return false
+	}
+	s.failures = append(s.failures, failures...)
+	return true
+}
+
+func (s *xlsxSource) FormulaErrors() []*FormulaError {
+	return s.failures
+}
+
+func (s *xlsxSource) CellHints() []cellHint {
+	if s.hinter == nil {
+		s.hinter = newXLSXHinter(s.file)
+	}
+	raw := s.RawCells()
+	if s.err != nil {
+		return nil
+	}
+	attrs, err := s.cellAttrs()
+	if err != nil {
+		s.err = err
+		return nil
+	}
+	var hints []cellHint
+	hints, s.err = s.hinter.row(s.SheetName(), s.rowNum, s.cells, raw, attrs)
+	return hints
+}
+
+// cellAttrs 流式读取当前行的单元格属性; 加密的文件返回 nil, 由 excelize 读取, 这类文件已经全部解密到内存中
+func (s *xlsxSource) cellAttrs() ([]xlsxCellAttr, error) {
+	f := s.zipFiles[s.SheetName()]
+	if f == nil {
+		return nil, nil
+	}
+	if s.scanner == nil {
+		var err error
+		if s.scanner, err = newXLSXCellScanner(f); err != nil {
+			return nil, err
+		}
+	}
+	return s.scanner.row(s.rowNum)
+}
+
+func (s *xlsxSource) Cells() []string {
+	return s.cells
+}
+
+// RawCells 第一次使用时打开另一个迭代器, 跳到当前行读取原始值;
+// 数字单元格中以科学计数法保存的值转换为完整的数字, 文本单元格保持原样
+func (s *xlsxSource) RawCells() []string {
+	if s.rawNum == s.rowNum && s.rawCells != nil || s.err != nil {
+		return s.rawCells
+	}
+	if s.rawRows == nil {
+		if s.rawRows, s.err = s.file.Rows(s.SheetName()); s.err != nil {
+			return nil
+		}
+	}
+	for s.rawNum < s.rowNum {
+		if !s.rawRows.Next() {
+			s.err = s.rawRows.Error()
+			return nil
+		}
+		s.rawNum++
+	}
+	if s.rawCells, s.err = s.rawRows.Columns(excelize.Options{RawCellValue: true}); s.err != nil {
+		return nil
+	}
+	if s.rawCells == nil {
+		s.rawCells = []string{}
+	}
+	for i, value := range s.rawCells {
+		if !isScientific(value) {
+			continue
+		}
+		attrs, err := s.cellAttrs()
+		if err != nil {
+			s.err = err
+			return nil
+		}
+		attr, err := xlsxCellAttrAt(s.file, s.SheetName(), s.rowNum, i, attrs)
+		if err != nil {
+			s.err = err
+			return nil
+		}
+		if attr.numeric() {
+			s.rawCells[i] = plainNumber(value)
+		}
+	}
+	return s.rawCells
+}
+
+func (s *xlsxSource) Date1904() bool {
+	return s.date1904
+}
+
+func (s *xlsxSource) Err() error {
+	return s.err
+}
+
+func (s *xlsxSource) Close() error {
+	if s.rows != nil {
+		_ = s.rows.Close()
+	}
+	if s.rawRows != nil {
+		_ = s.rawRows.Close()
+	}
+	if s.scanner != nil {
+		_ = s.scanner.Close()
+	}
+	err := s.file.Close()
+	if s.closer != nil {
+		_ = s.closer.Close()
+	}
+	return err
+}
+
+// xlsSource 读取 .xls 文件, extrame/xls 会在打开时解析整个文件;
+// 数值单元格按 readXLSWorkbookInfo 读取的数字格式输出
+type xlsSource struct {
+	workbook *xls.WorkBook
+	closer   io.Closer
+	info     *xlsWorkbookInfo
+	index    int
+	sheet    *xls.WorkSheet
+	rowIndex int
+	cells    []string
+	rawCells []string
+}
+
+// newXLSSource extrame/xls 读取表单时会再次读取数据, r 需要在读取器关闭前保持可用
+func newXLSSource(r io.ReaderAt, size int64, closer io.Closer) (*xlsSource, error) {
+	workbook, err := xls.OpenReader(io.NewSectionReader(r, 0, size), "utf-8")
+	if err != nil {
+		logx.Errorf("newXLSSource failed to open workbook: %v", err)
+		return nil, err
+	}
+	if workbook == nil {
+		return nil, errors.New("无法解析xls文件")
+	}
+	// 读取数字格式失败时仍使用 extrame/xls 的结果
+	info, err := readXLSWorkbookInfo(io.NewSectionReader(r, 0, size))
+	if err != nil {
+		logx.Errorf("newXLSSource failed to read number formats: %v", err)
+	}
+	return &xlsSource{workbook: workbook, closer: closer, info: info, index: -1}, nil
+}
+
+func (s *xlsSource) NextSheet() bool {
+	for {
+		s.index++
+		if s.index >= s.workbook.NumSheets() {
+			s.sheet = nil
+			return false
+		}
+		if s.sheet = s.workbook.GetSheet(s.index); s.sheet != nil {
+			s.rowIndex = -1
+			return true
+		}
+	}
+}
+
+func (s *xlsSource) SheetName() string {
+	if s.sheet == nil {
+		return ""
+	}
+	return s.sheet.Name
+}
+
+// SheetIndex extrame/xls 无法读取的表单会被跳过, 下标仍按 BOUNDSHEET 的顺序
+func (s *xlsSource) SheetIndex() int {
+	return s.index
+}
+
+// Hidden 根据 BOUNDSHEET 记录判断, 读取工作簿信息失败时认为表单可见
+func (s *xlsSource) Hidden() bool {
+	return s.info != nil && s.index < len(s.info.sheets) && s.info.sheets[s.index].hidden
+}
+
+// MergeRanges 根据 MERGEDCELLS 记录读取, 读取工作簿信息失败时返回 nil
+func (s *xlsSource) MergeRanges() []MergeRange {
+	if s.info == nil || s.index >= len(s.info.sheets) {
+		return nil
+	}
+	return s.info.sheets[s.index].merges
+}
+
+func (s *xlsSource) NextRow() bool {
+	if s.sheet == nil {
+		return false
+	}
+	s.rowIndex++
+	if s.rowIndex > int(s.sheet.MaxRow) {
+		return false
+	}
+	s.cells = s.cells[:0]
+	row := xlsRow(s.sheet, s.rowIndex)
+	if row != nil {
+		for i := 0; i < row.LastCol(); i++ {
+			s.cells = append(s.cells, row.Col(i))
+		}
+	}
+	s.formatNumbers()
+	return true
+}
+
+// formatNumbers 按数字格式重新输出当前行的数值单元格, extrame/xls 会把日期输出为序列号或只保留年月;
+// 同时记录不应用数字格式的原始值
+func (s *xlsSource) formatNumbers() {
+	s.rawCells = append(s.rawCells[:0], s.cells...)
+	if s.info == nil || s.index >= len(s.info.sheets) {
+		return
+	}
+	for _, n := range s.info.sheets[s.index].numbers[s.rowIndex] {
+		for len(s.cells) <= n.col {
+			s.cells = append(s.cells, "")
+			s.rawCells = append(s.rawCells, "")
+		}
+		s.cells[n.col] = s.info.format(n)
+		s.rawCells[n.col] = strconv.FormatFloat(n.value, 'f', -1, 64)
+	}
+}
+
+func (s *xlsSource) Cells() []string {
+	return s.cells
+}
+
+func (s *xlsSource) RawCells() []string {
+	return s.rawCells
+}
+
+// CellHints 数值单元格按数字格式返回类型, 其他单元格按文本推断
+func (s *xlsSource) CellHints() []cellHint {
+	hints := make([]cellHint, len(s.cells))
+	if s.info == nil || s.index >= len(s.info.sheets) {
+		return hints
+	}
+	for _, n := range s.info.sheets[s.index].numbers[s.rowIndex] {
+		var id uint16
+		if int(n.xf) < len(s.info.xfFormats) {
+			id = s.info.xfFormats[n.xf]
+		}
+		if n.col < len(hints) {
+			hints[n.col] = numFmtHint(int(id), s.info.formats[id], s.rawCells[n.col])
+		}
+	}
+	return hints
+}
+
+func (s *xlsSource) Date1904() bool {
+	return s.info != nil && s.info.date1904
+}
+
+func (s *xlsSource) Err() error {
+	return nil
+}
+
+func (s *xlsSource) Close() error {
+	if s.closer != nil {
+		return s.closer.Close()
+	}
+	return nil
+}
+
+// xlsRow 获取 .xls 表单中的行, extrame/xls 在行不存在时会 panic, 此时返回 nil
+func xlsRow(sheet *xls.WorkSheet, i int) (row *xls.Row) {
+	defer func() {
+		if recover() != nil {
+			row = nil
+		}
+	}()
+	return sheet.Row(i)
+}
+
+// csvSource 读取 .csv 文件, 只读取一遍数据, 编码检测只使用开头的部分数据
+type csvSource struct {
+	closer    io.Closer
+	reader    *csv.Reader
+	charset   string // 文件编码
+	delimiter rune   // 分隔符
+	sample    string // 开头解码后的部分数据
+	done      bool
+	cells     []string
+	err       error
+
+	// encoding/csv 会跳过空行, 按记录所在的行号补出空行, 使行号和文件中的行对应
+	end     int      // 上一条记录结束的行号
+	blank   int      // pending 前面还没有输出的空行数
+	pending []string // 已读取还没有输出的记录
+}
+
+// newCSVSource charset 为空时检测编码; delimiter 为 0 时根据开头的数据检测分隔符, 无法检测时使用 fallback
+func newCSVSource(r io.Reader, closer io.Closer, charset string, delimiter, fallback rune) (*csvSource, error) {
+	text, sample, enc, err := newTextReader(r, charset)
+	if err != nil {
+		return nil, err
+	}
+	reader := csv.NewReader(text)
+	if delimiter == 0 {
+		delimiter = sniffDelimiter(sample, fallback)
+	}
+	reader.Comma = delimiter
+	reader.FieldsPerRecord = -1 // 允许可变数量的字段
+	reader.LazyQuotes = true
+	reader.ReuseRecord = true
+	return &csvSource{
+		closer:    closer,
+		reader:    reader,
+		charset:   encodingName(enc),
+		delimiter: delimiter,
+		sample:    sample,
+	}, nil
+}
+
+// NextSheet csv 文件只有一个表单
+func (s *csvSource) NextSheet() bool {
+	if s.done {
+		return false
+	}
+	s.done = true
+	return true
+}
+
+func (s *csvSource) SheetName() string {
+	return "csv"
+}
+
+func (s *csvSource) SheetIndex() int {
+	return 0
+}
+
+func (s *csvSource) Hidden() bool {
+	return false
+}
+
+func (s *csvSource) MergeRanges() []MergeRange {
+	return nil
+}
+
+// NextRow 空行输出为没有单元格的行, 行号和文件中的行一致; 引号中包含换行的记录和在 Excel 中打开时一样算作一行
+func (s *csvSource) NextRow() bool {
+	if s.err != nil {
+		return false
+	}
+	if s.pending == nil {
+		record, err := s.reader.Read()
+		if err != nil {
+			if err != io.EOF {
+				s.err = err
+			}
+			return false
+		}
+		start, _ := s.reader.FieldPos(0)
+		s.blank = start - s.end - 1
+		s.end, _ = s.reader.FieldPos(len(record) - 1)
+		s.end += strings.Count(record[len(record)-1], "\n")
+		s.pending = record
+	}
+	if s.blank > 0 {
+		s.blank--
+		s.cells = nil
+		return true
+	}
+	s.cells, s.pending = s.pending, nil
+	return true
+}
+
+func (s *csvSource) Cells() []string {
+	return s.cells
+}
+
+func (s *csvSource) RawCells() []string {
+	return s.cells
+}
+
+// Date1904 csv 中的日期序列号按 1900 日期系统处理
+func (s *csvSource) Date1904() bool {
+	return false
+}
+
+func (s *csvSource) Err() error {
+	return s.err
+}
+
+func (s *csvSource) Close() error {
+	if s.closer != nil {
+		return s.closer.Close()
+	}
+	return nil
+}
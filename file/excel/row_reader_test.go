@@ -0,0 +1,171 @@
+package excelutil
+
+import (
+	"archive/zip"
+	"bytes"
+	"io"
+	"os"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// streamedRow RowReader 读取的一行
+type streamedRow struct {
+	sheet  string
+	rowNum int
+	row    []string
+}
+
+// readAllRows 用 RowReader 逐个表单逐行读取文件, 返回读取的行和 Err
+func readAllRows(t *testing.T, fileName string, opts ...ReadOption) ([]streamedRow, error) {
+	t.Helper()
+	r, err := NewRowReader(fileName, []string{"编码"}, map[string]string{"编码": "code", "名称": "name"}, opts...)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer r.Close()
+	var rows []streamedRow
+	for r.NextSheet() {
+		for r.Next() {
+			rows = append(rows, streamedRow{r.SheetName(), r.RowNum(), r.Row()})
+		}
+	}
+	return rows, r.Err()
+}
+
+// writeTestXLS 生成只包含文本单元格的 .xls 文件
+func writeTestXLS(t *testing.T, name string, sheets ...testSheet) string {
+	t.Helper()
+	var biff []biffSheet
+	for _, sheet := range sheets {
+		biff = append(biff, biffSheet{name: sheet.name, records: biffLabels(sheet.rows)})
+	}
+	return writeTestFile(t, name, string(newOLE2File("Workbook", biffSheets(biff...))))
+}
+
+func TestRowReaderSheets(t *testing.T) {
+	sheets := []testSheet{
+		{name: "物料1", rows: [][]string{{"编码", "名称", "备注"}, {"001", "螺丝", "x"}, {"002", "螺帽", ""}}},
+		{name: "物料2", rows: [][]string{{"物料清单"}, {"名称", "编码"}, {"垫片", "003"}}},
+	}
+	want := []streamedRow{
+		{"物料1", 2, []string{"001", "螺丝"}},
+		{"物料1", 3, []string{"002", "螺帽"}},
+		{"物料2", 3, []string{"垫片", "003"}},
+	}
+	tests := []struct {
+		name     string
+		fileName string
+	}{
+		{"xlsx", writeTestXLSX(t, "sheets.xlsx", sheets...)},
+		{"xls", writeTestXLS(t, "sheets.xls", sheets...)},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rows, err := readAllRows(t, tt.fileName)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !reflect.DeepEqual(rows, want) {
+				t.Errorf("rows = %v, want %v", rows, want)
+			}
+		})
+	}
+}
+
+// 读到损坏的行时停止读取, Err 返回错误, 之前的行正常返回
+func TestRowReaderCorruptRow(t *testing.T) {
+	fileName := writeTestXLSX(t, "corrupt.xlsx", testSheet{
+		name: "物料",
+		rows: [][]string{{"编码", "名称"}, {"001", "螺丝"}, {"002", "螺帽"}, {"003", "垫片"}},
+	})
+	rewriteZipFile(t, fileName, "xl/worksheets/sheet1.xml", func(content string) string {
+		return strings.Replace(content, `r="A3"`, `r="A?"`, 1)
+	})
+	rows, err := readAllRows(t, fileName, WithHeaderScanRows(1))
+	if err == nil {
+		t.Fatal("Err() = nil, want error")
+	}
+	if want := []streamedRow{{"物料", 2, []string{"001", "螺丝"}}}; !reflect.DeepEqual(rows, want) {
+		t.Errorf("rows = %v, want %v", rows, want)
+	}
+}
+
+// rewriteZipFile 修改 zip 文件中的一个文件
+func rewriteZipFile(t *testing.T, fileName, name string, rewrite func(string) string) {
+	t.Helper()
+	zr, err := zip.OpenReader(fileName)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer zr.Close()
+	var buf bytes.Buffer
+	zw := zip.NewWriter(&buf)
+	for _, f := range zr.File {
+		rc, err := f.Open()
+		if err != nil {
+			t.Fatal(err)
+		}
+		content, err := io.ReadAll(rc)
+		rc.Close()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if f.Name == name {
+			content = []byte(rewrite(string(content)))
+		}
+		w, err := zw.Create(f.Name)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if _, err = w.Write(content); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err = zw.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if err = os.WriteFile(fileName, buf.Bytes(), 0o644); err != nil {
+		t.Fatal(err)
+	}
+}
+
+// encoding/csv 跳过的空行仍然计入行号, 引号中的换行不增加行数
+func TestCSVSourceLineNumbers(t *testing.T) {
+	src, err := newCSVSource(strings.NewReader("\n编码,名称\n001,\"螺丝\n长款\"\n\n\n002,螺帽\n"), nil, "", ',', ',')
+	if err != nil {
+		t.Fatal(err)
+	}
+	var rows [][]string
+	for src.NextSheet() {
+		for src.NextRow() {
+			rows = append(rows, append([]string(nil), src.Cells()...))
+		}
+	}
+	if err = src.Err(); err != nil {
+		t.Fatal(err)
+	}
+	want := [][]string{nil, {"编码", "名称"}, {"001", "螺丝\n长款"}, nil, nil, {"002", "螺帽"}}
+	if !reflect.DeepEqual(rows, want) {
+		t.Errorf("rows = %q, want %q", rows, want)
+	}
+}
+
+func TestCSVRowNumbers(t *testing.T) {
+	fileName := writeTestFile(t, "material.csv", "物料清单\n\n编码,名称\n001,螺丝\n\n,螺帽\n")
+	file, err := ReadExcelFile(fileName, nil, map[string]string{"编码": "code", "名称": "name"},
+		WithRules(ColumnRule{Title: "编码", Required: true}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := file.Sheets[0].HeaderRow; got != 3 {
+		t.Errorf("HeaderRow = %d, want 3", got)
+	}
+	if len(file.Violations) != 1 || file.Violations[0].Row != 6 {
+		t.Errorf("Violations = %v, want row 6", file.Violations)
+	}
+	if got := file.Report.Sheets[0].SkippedRows; !reflect.DeepEqual(got, []int{5}) {
+		t.Errorf("SkippedRows = %v, want [5]", got)
+	}
+}
@@ -0,0 +1,164 @@
+package excelutil
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/xuri/excelize/v2"
+)
+
+// writeSelectXLSX 生成包含两个物料表单、一个没有表头的说明表单和一个隐藏表单的 .xlsx
+func writeSelectXLSX(t *testing.T) string {
+	t.Helper()
+	rows := [][]string{{"编码", "名称"}, {"001", "螺丝"}}
+	fileName := writeTestXLSX(t, "sheets.xlsx",
+		testSheet{name: "物料1", rows: rows},
+		testSheet{name: "物料2", rows: rows},
+		testSheet{name: "说明", rows: [][]string{{"导入说明"}}},
+		testSheet{name: "隐藏", rows: rows},
+	)
+	f, err := excelize.OpenFile(fileName)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	if err = f.SetSheetVisible("隐藏", false); err != nil {
+		t.Fatal(err)
+	}
+	if err = f.Save(); err != nil {
+		t.Fatal(err)
+	}
+	return fileName
+}
+
+func TestSheetSelection(t *testing.T) {
+	fileName := writeSelectXLSX(t)
+	tests := []struct {
+		name    string
+		opts    []ReadOption
+		sheets  []string
+		skipped []SkippedSheet
+		wantErr bool
+	}{
+		{name: "表头不符合要求时返回错误", wantErr: true},
+		{name: "名称", opts: []ReadOption{WithSheets("物料2")}, sheets: []string{"物料2"}},
+		{name: "下标", opts: []ReadOption{WithSheetIndexes(0, 3)}, sheets: []string{"物料1", "隐藏"}},
+		{name: "正则", opts: []ReadOption{WithSheetPattern(`^物料\d$`)}, sheets: []string{"物料1", "物料2"}},
+		{
+			name:   "满足任一条件",
+			opts:   []ReadOption{WithSheets("物料2"), WithSheetIndexes(0)},
+			sheets: []string{"物料1", "物料2"},
+		},
+		{
+			name:   "跳过隐藏和表头不符合要求的表单",
+			opts:   []ReadOption{WithSkipHiddenSheets(), WithSkipMismatchedSheets()},
+			sheets: []string{"物料1", "物料2"},
+			skipped: []SkippedSheet{
+				{SheetName: "说明", Reason: "表头不符合要求,表头应包含列:编码,缺少列:编码(最接近的表头:导入说明)"},
+				{SheetName: "隐藏", Reason: "隐藏的表单"},
+			},
+		},
+		{
+			name:    "选中的隐藏表单也跳过",
+			opts:    []ReadOption{WithSheets("隐藏", "物料1"), WithSkipHiddenSheets()},
+			sheets:  []string{"物料1"},
+			skipped: []SkippedSheet{{SheetName: "隐藏", Reason: "隐藏的表单"}},
+		},
+		{
+			name:    "全部表单都不符合要求",
+			opts:    []ReadOption{WithSheets("说明"), WithSkipMismatchedSheets()},
+			wantErr: true,
+		},
+		{name: "正则错误", opts: []ReadOption{WithSheetPattern("(")}, wantErr: true},
+	}
+	for _, tt := range tests {
+		file, err := ReadExcelFile(fileName, []string{"编码"}, map[string]string{"编码": "code", "名称": "name"}, tt.opts...)
+		if tt.wantErr {
+			if err == nil {
+				t.Errorf("%s: ReadExcelFile() error = nil, want error", tt.name)
+			}
+			continue
+		}
+		if err != nil {
+			t.Fatalf("%s: ReadExcelFile() error = %v", tt.name, err)
+		}
+		var sheets []string
+		for _, sheet := range file.Sheets {
+			sheets = append(sheets, sheet.SheetName)
+		}
+		if !reflect.DeepEqual(sheets, tt.sheets) {
+			t.Errorf("%s: sheets = %q, want %q", tt.name, sheets, tt.sheets)
+		}
+		var skipped []SkippedSheet
+		for _, sheet := range file.Report.SkippedSheets {
+			skipped = append(skipped, *sheet)
+		}
+		if !reflect.DeepEqual(skipped, tt.skipped) {
+			t.Errorf("%s: SkippedSheets = %+v, want %+v", tt.name, skipped, tt.skipped)
+		}
+	}
+}
+
+// stubSheet 测试用的表单, index 为在工作簿中的位置
+type stubSheet struct {
+	name  string
+	index int
+	rows  [][]string
+}
+
+// stubSource 只返回部分表单的读取器, 和 extrame/xls 无法读取某些表单时一样
+type stubSource struct {
+	sheets []stubSheet
+	sheet  int
+	row    int
+}
+
+func (s *stubSource) NextSheet() bool {
+	s.sheet++
+	s.row = -1
+	return s.sheet < len(s.sheets)
+}
+
+func (s *stubSource) SheetName() string         { return s.sheets[s.sheet].name }
+func (s *stubSource) SheetIndex() int           { return s.sheets[s.sheet].index }
+func (s *stubSource) Hidden() bool              { return false }
+func (s *stubSource) MergeRanges() []MergeRange { return nil }
+func (s *stubSource) Cells() []string           { return s.sheets[s.sheet].rows[s.row] }
+func (s *stubSource) RawCells() []string        { return s.Cells() }
+func (s *stubSource) Date1904() bool            { return false }
+func (s *stubSource) Err() error                { return nil }
+func (s *stubSource) Close() error              { return nil }
+
+func (s *stubSource) NextRow() bool {
+	s.row++
+	return s.row < len(s.sheets[s.sheet].rows)
+}
+
+// 读取器跳过的表单仍然占用下标, WithSheetIndexes 按表单在工作簿中的位置选择
+func TestSheetIndexesWithSkippedSheets(t *testing.T) {
+	rows := [][]string{{"编码"}, {"001"}}
+	src := &stubSource{sheet: -1, sheets: []stubSheet{{"物料1", 0, rows}, {"物料3", 2, rows}}}
+	r := newRowReader("", src, []string{"编码"}, map[string]string{"编码": "code"},
+		newReadOptions(WithSheetIndexes(2)))
+	var sheets []string
+	for r.NextSheet() {
+		sheets = append(sheets, r.SheetName())
+	}
+	if err := r.Err(); err != nil {
+		t.Fatal(err)
+	}
+	if want := []string{"物料3"}; !reflect.DeepEqual(sheets, want) {
+		t.Errorf("sheets = %q, want %q", sheets, want)
+	}
+
+	fileName := writeTestXLS(t, "sheets.xls",
+		testSheet{name: "物料1", rows: rows}, testSheet{name: "说明", rows: [][]string{{"导入说明"}}},
+		testSheet{name: "物料3", rows: rows})
+	file, err := ReadExcelFile(fileName, []string{"编码"}, map[string]string{"编码": "code"}, WithSheetIndexes(2))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(file.Sheets) != 1 || file.Sheets[0].SheetName != "物料3" {
+		t.Errorf("xls sheets = %v, want [物料3]", sheetRows(file))
+	}
+}
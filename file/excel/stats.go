@@ -0,0 +1,276 @@
+package excelutil
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/xuri/excelize/v2"
+)
+
+const (
+	statsTopValues = 5   // 每列统计的常见值个数
+	statsMaxIssues = 100 // 每列最多记录的可疑值个数
+)
+
+// 可疑值的原因
+const (
+	IssueMixedType      = "类型不一致"
+	IssueErrorValue     = "错误值"
+	IssueSpaces         = "首尾有空白"
+	IssueFullWidthDigit = "包含全角数字"
+)
+
+// FileStats 文件的数据质量统计
+type FileStats struct {
+	FileName string        // 文件名
+	Sheets   []*SheetStats // 每个表单的统计
+}
+
+// SheetStats 表单的数据质量统计
+type SheetStats struct {
+	SheetName string         // 表单名称
+	RowCount  int            // 数据行数
+	Columns   []*ColumnStats // 每一列的统计
+}
+
+// ColumnStats 列的数据质量统计
+type ColumnStats struct {
+	Title         string        // 表头
+	Type          CellKind      // 推断的类型
+	NullCount     int           // 空单元格数
+	DistinctCount int           // 不同的非空值个数
+	Min           string        // 最小值, 数字和日期按值比较, 其他按文本比较, 只比较和列中多数值类型一致的值
+	Max           string        // 最大值
+	TopValues     []ValueCount  // 出现次数最多的值, 按次数从多到少排列
+	IssueCount    int           // 可疑值总数
+	Issues        []*ValueIssue // 可疑值, 最多记录 100 个
+}
+
+// ValueCount 值及其出现次数
+type ValueCount struct {
+	Value string
+	Count int
+}
+
+// ValueIssue 可疑值
+type ValueIssue struct {
+	Row    int    // 数据行序号, 从 1 开始, 不含表头
+	Value  string // 单元格的值
+	Reason string // 原因, 如 IssueSpaces
+}
+
+// Stats 统计文件中每个表单的数据质量
+func (f *ExcelFile) Stats() *FileStats {
+	stats := &FileStats{FileName: f.FileName}
+	for _, sheet := range f.Sheets {
+		stats.Sheets = append(stats.Sheets, sheet.Stats())
+	}
+	return stats
+}
+
+// Stats 统计表单每一列的数据质量: 空值数、不同值个数、最小最大值、常见值、推断的类型和可疑值.
+// 没有 Header 时(如 OpenExFile 的结果)第一行作为表头; 设置 WithTypedCells 时使用读取的单元格类型
+func (s *ExcelSheet) Stats() *SheetStats {
+	header, rows, cells := s.Header, s.Rows, s.Cells
+	if header == nil && len(rows) > 0 {
+		header, rows = rows[0], rows[1:]
+		if len(cells) > 0 {
+			cells = cells[1:]
+		}
+	}
+	if len(cells) != len(rows) {
+		cells = make([][]Cell, len(rows))
+		for i, row := range rows {
+			cells[i] = newCells(row, nil, false)
+		}
+	}
+	types := inferColumnTypes(cells, len(header))
+	stats := &SheetStats{SheetName: s.SheetName, RowCount: len(rows)}
+	for i, title := range header {
+		stats.Columns = append(stats.Columns, columnStats(title, types[i], cells, i))
+	}
+	return stats
+}
+
+// columnStats 统计第 col 列
+func columnStats(title string, kind CellKind, rows [][]Cell, col int) *ColumnStats {
+	stats := &ColumnStats{Title: title, Type: kind}
+	// 多种类型混合的列按占多数的类型检查, 如数量列中个别的文本
+	expected := kind
+	if kind == CellKindString {
+		expected = dominantKind(rows, col)
+	}
+	counts := make(map[string]int)
+	var minCell, maxCell *Cell
+	for i, row := range rows {
+		if col >= len(row) || row[col].Kind == CellKindEmpty {
+			stats.NullCount++
+			continue
+		}
+		c := &row[col]
+		counts[c.Text]++
+		for _, reason := range cellIssues(c, expected) {
+			stats.IssueCount++
+			if len(stats.Issues) < statsMaxIssues {
+				stats.Issues = append(stats.Issues, &ValueIssue{Row: i + 1, Value: c.Text, Reason: reason})
+			}
+		}
+		if !sameKind(c.Kind, expected) {
+			continue
+		}
+		if minCell == nil || compareCells(c, minCell) < 0 {
+			minCell = c
+		}
+		if maxCell == nil || compareCells(c, maxCell) > 0 {
+			maxCell = c
+		}
+	}
+	if minCell != nil {
+		stats.Min, stats.Max = minCell.Text, maxCell.Text
+	}
+	stats.DistinctCount = len(counts)
+	for value, count := range counts {
+		stats.TopValues = append(stats.TopValues, ValueCount{Value: value, Count: count})
+	}
+	sort.Slice(stats.TopValues, func(i, j int) bool {
+		a, b := stats.TopValues[i], stats.TopValues[j]
+		return a.Count > b.Count || a.Count == b.Count && a.Value < b.Value
+	})
+	if len(stats.TopValues) > statsTopValues {
+		stats.TopValues = stats.TopValues[:statsTopValues]
+	}
+	return stats
+}
+
+// dominantKind 返回第 col 列中超过一半的非空单元格的类型, 整数和小数合并计算, 没有时返回 CellKindString
+func dominantKind(rows [][]Cell, col int) CellKind {
+	counts := make(map[CellKind]int)
+	total := 0
+	for _, row := range rows {
+		if col >= len(row) || row[col].Kind == CellKindEmpty || row[col].Kind == CellKindError {
+			continue
+		}
+		kind := row[col].Kind
+		if kind == CellKindInt {
+			kind = CellKindFloat
+		}
+		counts[kind]++
+		total++
+	}
+	for kind, count := range counts {
+		if count*2 > total {
+			return kind
+		}
+	}
+	return CellKindString
+}
+
+// cellIssues 检查单元格的值是否可疑, kind 为列类型
+func cellIssues(c *Cell, kind CellKind) []string {
+	var reasons []string
+	switch {
+	case c.Kind == CellKindError:
+		reasons = append(reasons, IssueErrorValue)
+	case !sameKind(c.Kind, kind):
+		reasons = append(reasons, IssueMixedType)
+	}
+	if strings.TrimSpace(c.Text) != c.Text {
+		reasons = append(reasons, IssueSpaces)
+	}
+	if strings.ContainsFunc(c.Text, func(r rune) bool { return r >= '０' && r <= '９' }) {
+		reasons = append(reasons, IssueFullWidthDigit)
+	}
+	return reasons
+}
+
+// sameKind 单元格类型是否和列类型一致, 整数列和小数列兼容, 文本列兼容所有类型
+func sameKind(cell, column CellKind) bool {
+	switch {
+	case cell == column, column == CellKindString:
+		return true
+	case column == CellKindFloat:
+		return cell == CellKindInt
+	}
+	return false
+}
+
+// compareCells 比较两个同类型单元格的值
+func compareCells(a, b *Cell) int {
+	switch a.Kind {
+	case CellKindInt, CellKindFloat:
+		if b.Kind == CellKindInt || b.Kind == CellKindFloat {
+			return compareFloat(a.Float, b.Float)
+		}
+	case CellKindDate:
+		if b.Kind == CellKindDate {
+			return a.Time.Compare(b.Time)
+		}
+	}
+	return strings.Compare(a.Text, b.Text)
+}
+
+func compareFloat(a, b float64) int {
+	switch {
+	case a < b:
+		return -1
+	case a > b:
+		return 1
+	}
+	return 0
+}
+
+// statsTitles 统计结果工作表的表头
+var statsTitles = []string{"表单", "列", "类型", "行数", "空值数", "不同值数", "最小值", "最大值", "常见值", "可疑值数", "可疑值"}
+
+// WriteSheet 把统计结果写入工作簿的 sheet 表单, 表单不存在时新建, 每一列一行
+func (s *FileStats) WriteSheet(f *excelize.File, sheet string) error {
+	index, err := f.GetSheetIndex(sheet)
+	if err != nil {
+		return err
+	}
+	if index < 0 {
+		if _, err = f.NewSheet(sheet); err != nil {
+			return err
+		}
+	}
+	if err = f.SetSheetRow(sheet, "A1", &statsTitles); err != nil {
+		return err
+	}
+	rowNum := 2
+	for _, sheetStats := range s.Sheets {
+		for _, column := range sheetStats.Columns {
+			top := make([]string, 0, len(column.TopValues))
+			for _, v := range column.TopValues {
+				top = append(top, fmt.Sprintf("%s(%d)", v.Value, v.Count))
+			}
+			issues := make([]string, 0, len(column.Issues))
+			for _, issue := range column.Issues {
+				issues = append(issues, fmt.Sprintf("第%d行[%s]%s", issue.Row, issue.Value, issue.Reason))
+			}
+			cell, _ := excelize.CoordinatesToCellName(1, rowNum)
+			if err = f.SetSheetRow(sheet, cell, &[]any{
+				sheetStats.SheetName, column.Title, column.Type.String(), sheetStats.RowCount, column.NullCount,
+				column.DistinctCount, column.Min, column.Max, strings.Join(top, ", "), column.IssueCount,
+				strings.Join(issues, "\n"),
+			}); err != nil {
+				return err
+			}
+			rowNum++
+		}
+	}
+	return nil
+}
+
+// SaveAs 把统计结果保存为 .xlsx 文件
+func (s *FileStats) SaveAs(fileName string) error {
+	f := excelize.NewFile()
+	defer f.Close()
+	if err := f.SetSheetName("Sheet1", "数据统计"); err != nil {
+		return err
+	}
+	if err := s.WriteSheet(f, "数据统计"); err != nil {
+		return err
+	}
+	return f.SaveAs(fileName)
+}
@@ -0,0 +1,106 @@
+package excelutil
+
+import (
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"github.com/xuri/excelize/v2"
+)
+
+func newStatsSheet() *ExcelSheet {
+	return &ExcelSheet{
+		SheetName: "库存",
+		Header:    []string{"编码", "数量", "日期", "备注"},
+		Rows: [][]string{
+			{"001", "10", "2023-07-16", "a"},
+			{"002", "2.5", "2023-01-02", " b"},
+			{"003", "", "2023-12-31", "a"},
+			{"004", "abc", "", "a"},
+			{"005", "#DIV/0!", "2022-05-01", "１２"},
+		},
+	}
+}
+
+func TestSheetStats(t *testing.T) {
+	stats := newStatsSheet().Stats()
+	if stats.SheetName != "库存" || stats.RowCount != 5 || len(stats.Columns) != 4 {
+		t.Fatalf("Stats() = %+v", stats)
+	}
+	tests := []struct {
+		want   ColumnStats
+		issues []ValueIssue
+	}{
+		{
+			want: ColumnStats{Title: "编码", Type: CellKindString, DistinctCount: 5, Min: "001", Max: "005",
+				TopValues: []ValueCount{{"001", 1}, {"002", 1}, {"003", 1}, {"004", 1}, {"005", 1}}},
+		},
+		{
+			// 多数值是数字, 按数字比较大小, 文本和错误值为可疑值
+			want: ColumnStats{Title: "数量", Type: CellKindString, NullCount: 1, DistinctCount: 4, Min: "2.5", Max: "10",
+				TopValues: []ValueCount{{"#DIV/0!", 1}, {"10", 1}, {"2.5", 1}, {"abc", 1}}, IssueCount: 2},
+			issues: []ValueIssue{{4, "abc", IssueMixedType}, {5, "#DIV/0!", IssueErrorValue}},
+		},
+		{
+			want: ColumnStats{Title: "日期", Type: CellKindDate, NullCount: 1, DistinctCount: 4, Min: "2022-05-01", Max: "2023-12-31",
+				TopValues: []ValueCount{{"2022-05-01", 1}, {"2023-01-02", 1}, {"2023-07-16", 1}, {"2023-12-31", 1}}},
+		},
+		{
+			want: ColumnStats{Title: "备注", Type: CellKindString, DistinctCount: 3, Min: " b", Max: "１２",
+				TopValues: []ValueCount{{"a", 3}, {" b", 1}, {"１２", 1}}, IssueCount: 2},
+			issues: []ValueIssue{{2, " b", IssueSpaces}, {5, "１２", IssueFullWidthDigit}},
+		},
+	}
+	for i, tt := range tests {
+		got := *stats.Columns[i]
+		var issues []ValueIssue
+		for _, issue := range got.Issues {
+			issues = append(issues, *issue)
+		}
+		got.Issues = nil
+		if !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("Columns[%d] = %+v, want %+v", i, got, tt.want)
+		}
+		if !reflect.DeepEqual(issues, tt.issues) {
+			t.Errorf("Columns[%d].Issues = %+v, want %+v", i, issues, tt.issues)
+		}
+	}
+}
+
+// 没有 Header 时第一行作为表头; 常见值只保留出现次数最多的 5 个
+func TestSheetStatsWithoutHeader(t *testing.T) {
+	sheet := &ExcelSheet{SheetName: "Sheet1", Rows: [][]string{{"值"}, {"a"}, {"b"}, {"b"}, {"c"}, {"d"}, {"e"}, {"f"}}}
+	stats := sheet.Stats()
+	if stats.RowCount != 7 || len(stats.Columns) != 1 || stats.Columns[0].Title != "值" {
+		t.Fatalf("Stats() = %+v", stats)
+	}
+	want := []ValueCount{{"b", 2}, {"a", 1}, {"c", 1}, {"d", 1}, {"e", 1}}
+	if got := stats.Columns[0].TopValues; !reflect.DeepEqual(got, want) {
+		t.Errorf("TopValues = %v, want %v", got, want)
+	}
+}
+
+func TestFileStatsSaveAs(t *testing.T) {
+	file := &ExcelFile{FileName: "库存.xlsx", Sheets: []*ExcelSheet{newStatsSheet()}}
+	fileName := filepath.Join(t.TempDir(), "stats.xlsx")
+	if err := file.Stats().SaveAs(fileName); err != nil {
+		t.Fatal(err)
+	}
+	f, err := excelize.OpenFile(fileName)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	rows, err := f.GetRows("数据统计")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(rows) != 5 || !reflect.DeepEqual(rows[0], statsTitles) {
+		t.Fatalf("rows = %q", rows)
+	}
+	want := []string{"库存", "数量", "string", "5", "1", "4", "2.5", "10", "#DIV/0!(1), 10(1), 2.5(1), abc(1)", "2",
+		"第4行[abc]类型不一致\n第5行[#DIV/0!]错误值"}
+	if !reflect.DeepEqual(rows[2], want) {
+		t.Errorf("rows[2] = %q, want %q", rows[2], want)
+	}
+}
@@ -0,0 +1,349 @@
+package excelutil
+
+import (
+	"encoding"
+	"errors"
+	"fmt"
+	"reflect"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"go_file/utils/timeutil"
+
+	"github.com/xuri/excelize/v2"
+)
+
+// excelTag 结构体字段标签名, 格式为 `excel:"表头|别名1|别名2,选项"`, 支持的选项:
+//
+//	required        表头必须存在
+//	layout=布局      time.Time 字段的日期格式, 不指定时自动识别; 布局可以包含逗号, 需放在最后一个选项
+//	decimal         string 字段按十进制数校验, 并去掉千分位分隔符
+//
+// 标签为 "-" 的字段会被忽略
+const excelTag = "excel"
+
+var (
+	textUnmarshalerType = reflect.TypeOf((*encoding.TextUnmarshaler)(nil)).Elem()
+	timeType            = reflect.TypeOf(time.Time{})
+	decimalRegexp       = regexp.MustCompile(`^[-+]?(\d+(\.\d*)?|\.\d+)$`)
+)
+
+// CellError 单元格数据转换错误
+type CellError struct {
+	Sheet  string // 表单名称
+	Row    int    // 行号, 从1开始
+	Column string // 列号, 如 "A"
+	Title  string // 表头
+	Value  string // 单元格原始数据
+	Err    error
+}
+
+func (e *CellError) Error() string {
+	return fmt.Sprintf("表单[%s]第%d行%s列[%s]数据[%s]错误:%v", e.Sheet, e.Row, e.Column, e.Title, e.Value, e.Err)
+}
+
+func (e *CellError) Unwrap() error {
+	return e.Err
+}
+
+// CellErrors 多个单元格错误
+type CellErrors []*CellError
+
+func (e CellErrors) Error() string {
+	msgs := make([]string, 0, len(e))
+	for _, cellErr := range e {
+		msgs = append(msgs, cellErr.Error())
+	}
+	return strings.Join(msgs, "; ")
+}
+
+// fieldBinding 结构体字段和表头的绑定关系
+type fieldBinding struct {
+	name     string // 字段名, 作为 RowReader 映射后的表头
+	index    []int
+	titles   []string // 表头及别名, 第一个为主表头
+	required bool
+	layout   string
+	decimal  bool
+}
+
+// UnmarshalExcelFile 读取Excel文件, 按结构体字段的 excel 标签把每一行数据绑定到 out,
+// out 必须是结构体切片的指针, 如 *[]Material 或 *[]*Material.
+// 单元格数据转换失败或不符合 WithRules 设置的校验规则时会继续处理剩余数据, 最后返回 CellErrors
+func UnmarshalExcelFile(fileName string, out interface{}, opts ...ReadOption) error {
+	sliceValue, elemType, err := checkUnmarshalTarget(out)
+	if err != nil {
+		return err
+	}
+	structType := elemType
+	if structType.Kind() == reflect.Ptr {
+		structType = structType.Elem()
+	}
+	bindings, err := parseFieldBindings(structType)
+	if err != nil {
+		return err
+	}
+	var requiredTitles []string
+	dstTitleMap := make(map[string]string, len(bindings))
+	aliases := make(map[string][]string)
+	for _, binding := range bindings {
+		dstTitleMap[binding.titles[0]] = binding.name
+		if len(binding.titles) > 1 {
+			aliases[binding.titles[0]] = binding.titles[1:]
+		}
+		if binding.required {
+			requiredTitles = append(requiredTitles, binding.titles[0])
+		}
+	}
+	opts = append(opts[:len(opts):len(opts)], WithTitleAliases(aliases))
+	r, err := NewRowReader(fileName, requiredTitles, dstTitleMap, opts...)
+	if err != nil {
+		return err
+	}
+	defer r.Close()
+	var cellErrs CellErrors
+	for r.NextSheet() {
+		// 字段名 -> 当前表单中的列
+		columns := make(map[string]int, len(r.Header()))
+		for i, title := range r.Header() {
+			if _, ok := columns[title]; !ok {
+				columns[title] = i
+			}
+		}
+		for r.Next() {
+			elem := reflect.New(structType).Elem()
+			row := r.Row()
+			for _, binding := range bindings {
+				i, ok := columns[binding.name]
+				if !ok {
+					continue
+				}
+				if err := setFieldValue(elem.FieldByIndex(binding.index), row[i], binding); err != nil {
+					colName, _ := excelize.ColumnNumberToName(r.colIndexes[i] + 1)
+					cellErrs = append(cellErrs, &CellError{
+						Sheet:  r.SheetName(),
+						Row:    r.RowNum(),
+						Column: colName,
+						Title:  r.SourceHeader()[r.colIndexes[i]],
+						Value:  row[i],
+						Err:    err,
+					})
+				}
+			}
+			for _, v := range r.Violations() {
+				cellErrs = append(cellErrs, &CellError{
+					Sheet:  v.Sheet,
+					Row:    v.Row,
+					Column: v.Column,
+					Title:  v.Title,
+					Value:  v.Value,
+					Err:    errors.New(v.Message),
+				})
+			}
+			if elemType.Kind() == reflect.Ptr {
+				sliceValue.Set(reflect.Append(sliceValue, elem.Addr()))
+			} else {
+				sliceValue.Set(reflect.Append(sliceValue, elem))
+			}
+		}
+	}
+	if err = r.Err(); err != nil {
+		return err
+	}
+	if len(cellErrs) > 0 {
+		return cellErrs
+	}
+	return nil
+}
+
+// checkUnmarshalTarget 检查 out 是否为结构体切片的指针
+func checkUnmarshalTarget(out interface{}) (reflect.Value, reflect.Type, error) {
+	value := reflect.ValueOf(out)
+	if value.Kind() != reflect.Ptr || value.IsNil() || value.Elem().Kind() != reflect.Slice {
+		return reflect.Value{}, nil, errors.New("out 必须是结构体切片的指针")
+	}
+	elemType := value.Elem().Type().Elem()
+	structType := elemType
+	if structType.Kind() == reflect.Ptr {
+		structType = structType.Elem()
+	}
+	if structType.Kind() != reflect.Struct {
+		return reflect.Value{}, nil, errors.New("out 必须是结构体切片的指针")
+	}
+	return value.Elem(), elemType, nil
+}
+
+// parseFieldBindings 解析结构体字段的 excel 标签, 不同字段的表头或别名规范化后不能相同
+func parseFieldBindings(structType reflect.Type) ([]*fieldBinding, error) {
+	bindings := make([]*fieldBinding, 0, structType.NumField())
+	owners := make(map[string]string) // 规范化后的表头 -> 字段名
+	for i := 0; i < structType.NumField(); i++ {
+		field := structType.Field(i)
+		tag, ok := field.Tag.Lookup(excelTag)
+		if !ok || tag == "-" || !field.IsExported() {
+			continue
+		}
+		titles, opts, _ := strings.Cut(tag, ",")
+		binding := &fieldBinding{name: field.Name, index: field.Index}
+		for _, title := range strings.Split(titles, "|") {
+			if title = strings.TrimSpace(title); title != "" {
+				binding.titles = append(binding.titles, title)
+			}
+		}
+		if len(binding.titles) == 0 {
+			return nil, fmt.Errorf("字段%s的excel标签缺少表头", field.Name)
+		}
+		for _, title := range binding.titles {
+			normalized := NormalizeTitle(title)
+			if owner, ok := owners[normalized]; ok && owner != field.Name {
+				return nil, fmt.Errorf("字段%s和%s的excel表头%s重复", owner, field.Name, title)
+			}
+			owners[normalized] = field.Name
+		}
+		for opts != "" {
+			var opt string
+			if opts = strings.TrimSpace(opts); strings.HasPrefix(opts, "layout=") {
+				// 布局可以包含逗号, 到标签末尾为止
+				opt, opts = opts, ""
+			} else {
+				opt, opts, _ = strings.Cut(opts, ",")
+				opt = strings.TrimSpace(opt)
+			}
+			switch {
+			case opt == "required":
+				binding.required = true
+			case opt == "decimal":
+				binding.decimal = true
+			case strings.HasPrefix(opt, "layout="):
+				binding.layout = strings.TrimPrefix(opt, "layout=")
+			default:
+				return nil, fmt.Errorf("字段%s的excel标签选项%s不支持", field.Name, opt)
+			}
+		}
+		bindings = append(bindings, binding)
+	}
+	if len(bindings) == 0 {
+		return nil, fmt.Errorf("结构体%s没有带excel标签的字段", structType.Name())
+	}
+	return bindings, nil
+}
+
+// setFieldValue 把单元格数据转换后写入字段, 空单元格保持零值
+func setFieldValue(field reflect.Value, value string, binding *fieldBinding) error {
+	value = strings.TrimSpace(value)
+	if value == "" {
+		return nil
+	}
+	if field.Kind() == reflect.Ptr {
+		ptr := reflect.New(field.Type().Elem())
+		if err := setFieldValue(ptr.Elem(), value, binding); err != nil {
+			return err
+		}
+		field.Set(ptr)
+		return nil
+	}
+	if field.Type() == timeType {
+		t, err := parseTimeValue(value, binding.layout)
+		if err != nil {
+			return err
+		}
+		field.Set(reflect.ValueOf(t))
+		return nil
+	}
+	if field.CanAddr() && field.Addr().Type().Implements(textUnmarshalerType) {
+		return field.Addr().Interface().(encoding.TextUnmarshaler).UnmarshalText([]byte(value))
+	}
+	switch field.Kind() {
+	case reflect.String:
+		if binding.decimal {
+			decimal := strings.ReplaceAll(value, ",", "")
+			if !decimalRegexp.MatchString(decimal) {
+				return fmt.Errorf("不是有效的数字")
+			}
+			value = decimal
+		}
+		field.SetString(value)
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		n, err := parseIntValue(value)
+		if err != nil {
+			return err
+		}
+		if field.OverflowInt(n) {
+			return fmt.Errorf("数值超出范围")
+		}
+		field.SetInt(n)
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		n, err := parseIntValue(value)
+		if err != nil {
+			return err
+		}
+		if n < 0 || field.OverflowUint(uint64(n)) {
+			return fmt.Errorf("数值超出范围")
+		}
+		field.SetUint(uint64(n))
+	case reflect.Float32, reflect.Float64:
+		f, err := strconv.ParseFloat(strings.ReplaceAll(value, ",", ""), 64)
+		if err != nil {
+			return fmt.Errorf("不是有效的数字")
+		}
+		if field.OverflowFloat(f) {
+			return fmt.Errorf("数值超出范围")
+		}
+		field.SetFloat(f)
+	case reflect.Bool:
+		b, err := parseBoolValue(value)
+		if err != nil {
+			return err
+		}
+		field.SetBool(b)
+	default:
+		return fmt.Errorf("不支持的字段类型:%s", field.Type())
+	}
+	return nil
+}
+
+// parseIntValue 解析整数, 兼容 Excel 中 "12.0" 形式的整数
+func parseIntValue(value string) (int64, error) {
+	value = strings.ReplaceAll(value, ",", "")
+	if n, err := strconv.ParseInt(value, 10, 64); err == nil {
+		return n, nil
+	}
+	f, err := strconv.ParseFloat(value, 64)
+	if err != nil || f != float64(int64(f)) {
+		return 0, fmt.Errorf("不是有效的整数")
+	}
+	return int64(f), nil
+}
+
+// parseBoolValue 解析布尔值, 除 strconv.ParseBool 支持的格式外还支持 是/否, Y/N, yes/no
+func parseBoolValue(value string) (bool, error) {
+	switch strings.ToLower(value) {
+	case "是", "y", "yes":
+		return true, nil
+	case "否", "n", "no":
+		return false, nil
+	}
+	b, err := strconv.ParseBool(value)
+	if err != nil {
+		return false, fmt.Errorf("不是有效的布尔值")
+	}
+	return b, nil
+}
+
+// parseTimeValue 按指定格式解析时间, 未指定格式时自动识别; 不带时区的时间都按本地时区解析
+func parseTimeValue(value, layout string) (time.Time, error) {
+	if layout != "" {
+		t, err := time.ParseInLocation(layout, value, time.Local)
+		if err != nil {
+			return time.Time{}, fmt.Errorf("日期格式应为%s", layout)
+		}
+		return t, nil
+	}
+	t, err := timeutil.ParseDateIn(value, time.Local)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("不是有效的日期")
+	}
+	return t, nil
+}
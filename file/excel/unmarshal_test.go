@@ -0,0 +1,121 @@
+package excelutil
+
+import (
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestParseTimeValue(t *testing.T) {
+	tests := []struct {
+		value   string
+		layout  string
+		want    time.Time
+		wantErr bool
+	}{
+		{"2023-07-16 08:30:00", "", time.Date(2023, 7, 16, 8, 30, 0, 0, time.Local), false},
+		{"2023/7/16", "", time.Date(2023, 7, 16, 0, 0, 0, 0, time.Local), false},
+		{"16/07/2023", "02/01/2006", time.Date(2023, 7, 16, 0, 0, 0, 0, time.Local), false},
+		{"2023-07-16T08:30:00Z", "", time.Date(2023, 7, 16, 8, 30, 0, 0, time.UTC), false},
+		{"2023-07-16", "02/01/2006", time.Time{}, true},
+		{"明天", "", time.Time{}, true},
+	}
+	for _, tt := range tests {
+		got, err := parseTimeValue(tt.value, tt.layout)
+		if (err != nil) != tt.wantErr || !got.Equal(tt.want) {
+			t.Errorf("parseTimeValue(%q, %q) = %v, %v, want %v, error %v", tt.value, tt.layout, got, err, tt.want, tt.wantErr)
+		}
+	}
+}
+
+func TestParseFieldBindingsDuplicateTitle(t *testing.T) {
+	tests := []struct {
+		name string
+		typ  reflect.Type
+		want string
+	}{
+		{
+			"表头重复",
+			reflect.TypeOf(struct {
+				Code  string `excel:"物料编码"`
+				Code2 string `excel:"物料编码"`
+			}{}),
+			"字段Code和Code2的excel表头物料编码重复",
+		},
+		{
+			"别名和表头重复",
+			reflect.TypeOf(struct {
+				Code string `excel:"物料编码|料号"`
+				No   string `excel:"料号"`
+			}{}),
+			"字段Code和No的excel表头料号重复",
+		},
+		{
+			"规范化后重复",
+			reflect.TypeOf(struct {
+				SKU  string `excel:"SKU"`
+				Item string `excel:"品名|ｓｋｕ"`
+			}{}),
+			"字段SKU和Item的excel表头ｓｋｕ重复",
+		},
+	}
+	for _, tt := range tests {
+		if _, err := parseFieldBindings(tt.typ); err == nil || err.Error() != tt.want {
+			t.Errorf("%s: parseFieldBindings() error = %v, want %q", tt.name, err, tt.want)
+		}
+	}
+	// 同一个字段的别名和表头规范化后相同不算重复
+	bindings, err := parseFieldBindings(reflect.TypeOf(struct {
+		Code string `excel:"物料编码|物料 编码"`
+		Name string `excel:"名称"`
+	}{}))
+	if err != nil || len(bindings) != 2 {
+		t.Errorf("parseFieldBindings() = %v, %v", bindings, err)
+	}
+}
+
+// layout 到标签末尾为止, 可以包含逗号
+func TestParseFieldBindingsOptions(t *testing.T) {
+	tests := []struct {
+		name     string
+		tag      reflect.StructTag
+		required bool
+		decimal  bool
+		layout   string
+		wantErr  bool
+	}{
+		{"表头", `excel:"日期"`, false, false, "", false},
+		{"选项", `excel:"金额|价格, required ,decimal"`, true, true, "", false},
+		{"布局", `excel:"日期,layout=2006-01-02"`, false, false, "2006-01-02", false},
+		{"布局包含逗号", `excel:"日期,required,layout=2006,01,02"`, true, false, "2006,01,02", false},
+		{"布局包含逗号和空格", `excel:"日期,layout=Jan 2, 2006"`, false, false, "Jan 2, 2006", false},
+		{"布局之后的内容属于布局", `excel:"日期,layout=2006-01-02,bad"`, false, false, "2006-01-02,bad", false},
+		{"不支持的选项", `excel:"日期,bad,layout=2006"`, false, false, "", true},
+	}
+	for _, tt := range tests {
+		typ := reflect.StructOf([]reflect.StructField{{Name: "Date", Type: timeType, Tag: tt.tag}})
+		bindings, err := parseFieldBindings(typ)
+		if (err != nil) != tt.wantErr {
+			t.Errorf("%s: parseFieldBindings() error = %v, wantErr %v", tt.name, err, tt.wantErr)
+			continue
+		}
+		if err != nil {
+			continue
+		}
+		b := bindings[0]
+		if b.required != tt.required || b.decimal != tt.decimal || b.layout != tt.layout {
+			t.Errorf("%s: required = %v, decimal = %v, layout = %q, want %v, %v, %q",
+				tt.name, b.required, b.decimal, b.layout, tt.required, tt.decimal, tt.layout)
+		}
+	}
+	var rows []struct {
+		Date time.Time `excel:"日期,layout=2006,01,02"`
+	}
+	fileName := writeTestFile(t, "layout.csv", "日期\n\"2023,07,16\"\n")
+	if err := UnmarshalExcelFile(fileName, &rows); err != nil {
+		t.Fatal(err)
+	}
+	if want := time.Date(2023, 7, 16, 0, 0, 0, 0, time.Local); len(rows) != 1 || !rows[0].Date.Equal(want) {
+		t.Errorf("rows = %v, want %v", rows, want)
+	}
+}
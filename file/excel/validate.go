@@ -0,0 +1,391 @@
+package excelutil
+
+import (
+	"fmt"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+
+	"go_file/common"
+	"go_file/utils/timeutil"
+
+	"github.com/xuri/excelize/v2"
+)
+
+// 校验规则名称
+const (
+	RuleRequired  = "required"  // 必填
+	RuleType      = "type"      // 数据类型
+	RuleMin       = "min"       // 最小值
+	RuleMax       = "max"       // 最大值
+	RuleLength    = "length"    // 长度
+	RulePattern   = "pattern"   // 正则
+	RuleEnum      = "enum"      // 枚举值
+	RuleNormalize = "normalize" // 规范化
+	RuleUnique    = "unique"    // 主键重复
+)
+
+// 校验规则支持的数据类型
+const (
+	ColumnTypeInt    = "int"    // 整数
+	ColumnTypeNumber = "number" // 数字
+	ColumnTypeDate   = "date"   // 日期
+)
+
+// ColumnTypeText 导入配置中的文本列, 使用单元格保存的原始值, 长数字不会输出为科学计数法
+const ColumnTypeText = "text"
+
+// ColumnRule 列校验规则, 空单元格只校验 Required
+type ColumnRule struct {
+	Title    string   `json:"title,optional"`    // 源表头, 支持别名
+	Required bool     `json:"required,optional"` // 是否必填
+	Type     string   `json:"type,optional"`     // 数据类型: int, number, date
+	Min      *float64 `json:"min,optional"`      // 最小值, 只对 int, number 生效
+	Max      *float64 `json:"max,optional"`      // 最大值, 只对 int, number 生效
+	MinLen   int      `json:"minLen,optional"`   // 最小长度(字符数)
+	MaxLen   int      `json:"maxLen,optional"`   // 最大长度(字符数)
+	Pattern  string   `json:"pattern,optional"`  // 正则表达式
+	Enum     []string `json:"enum,optional"`     // 允许的值
+	Message  string   `json:"message,optional"`  // 自定义错误提示, 为空时使用默认提示
+	regexp   *regexp.Regexp
+}
+
+// Violation 校验不通过的单元格
+type Violation struct {
+	Sheet   string // 表单名称
+	Row     int    // 行号, 从1开始
+	Column  string // 列号, 如 "A"
+	Title   string // 源表头
+	Rule    string // 规则名称
+	Value   string // 单元格数据
+	Message string // 错误提示
+}
+
+func (v *Violation) Error() string {
+	return fmt.Sprintf("表单[%s]第%d行%s列[%s]:%s", v.Sheet, v.Row, v.Column, v.Title, v.Message)
+}
+
+// compileRules 编译校验规则中的正则表达式
+func compileRules(rules []*ColumnRule) error {
+	for _, rule := range rules {
+		if rule.Pattern == "" || rule.regexp != nil {
+			continue
+		}
+		re, err := regexp.Compile(rule.Pattern)
+		if err != nil {
+			return fmt.Errorf("列[%s]的校验规则正则表达式错误:%v", rule.Title, err)
+		}
+		rule.regexp = re
+	}
+	return nil
+}
+
+// boundRule 绑定到表单中某一列的校验规则
+type boundRule struct {
+	*ColumnRule
+	col    int    // 源数据中的列下标
+	column string // 列号
+	title  string // 表单中的表头
+
+	date     *DateColumn // 列的日期配置, 日期类型按配置的输入格式和序列号校验
+	date1904 bool
+}
+
+// check 设置了日期配置的列按列自己的解析方式校验日期类型, 其他同 ColumnRule.check
+func (rule *boundRule) check(value string) (string, string) {
+	if rule.date == nil || rule.Type != ColumnTypeDate || value == "" {
+		return rule.ColumnRule.check(value)
+	}
+	if _, err := rule.date.parse(value, rule.date1904); err != nil {
+		return RuleType, "应为日期"
+	}
+	other := *rule.ColumnRule
+	other.Type = ""
+	return other.check(value)
+}
+
+// bindRules 根据表头找到每条规则对应的列, 表单中不存在的列不校验
+func bindRules(header []string, rules []*ColumnRule, matcher *titleMatcher) []*boundRule {
+	bound := make([]*boundRule, 0, len(rules))
+	for _, rule := range rules {
+		canonical := matcher.canonical(rule.Title)
+		for i, title := range header {
+			if title == "" || matcher.canonical(title) != canonical {
+				continue
+			}
+			column, _ := excelize.ColumnNumberToName(i + 1)
+			bound = append(bound, &boundRule{ColumnRule: rule, col: i, column: column, title: title})
+			break
+		}
+	}
+	return bound
+}
+
+// validateRow 按规则校验一行数据
+func validateRow(sheet string, rowNum int, cells []string, rules []*boundRule) []*Violation {
+	var violations []*Violation
+	for _, rule := range rules {
+		var value string
+		if rule.col < len(cells) {
+			value = strings.TrimSpace(cells[rule.col])
+		}
+		name, message := rule.check(value)
+		if name == "" {
+			continue
+		}
+		if rule.Message != "" {
+			message = rule.Message
+		}
+		violations = append(violations, &Violation{
+			Sheet:   sheet,
+			Row:     rowNum,
+			Column:  rule.column,
+			Title:   rule.title,
+			Rule:    name,
+			Value:   value,
+			Message: message,
+		})
+	}
+	return violations
+}
+
+// check 校验单元格数据, 通过时返回空的规则名称
+func (rule *ColumnRule) check(value string) (string, string) {
+	if value == "" {
+		if rule.Required {
+			return RuleRequired, "不能为空"
+		}
+		return "", ""
+	}
+	var (
+		number    float64
+		isNumeric bool
+	)
+	switch rule.Type {
+	case ColumnTypeInt:
+		n, err := parseIntValue(value)
+		if err != nil {
+			return RuleType, "应为整数"
+		}
+		number, isNumeric = float64(n), true
+	case ColumnTypeNumber:
+		f, err := strconv.ParseFloat(strings.ReplaceAll(value, ",", ""), 64)
+		if err != nil {
+			return RuleType, "应为数字"
+		}
+		number, isNumeric = f, true
+	case ColumnTypeDate:
+		if _, err := timeutil.ParseDate(value); err != nil {
+			return RuleType, "应为日期"
+		}
+	}
+	if isNumeric && rule.Min != nil && number < *rule.Min {
+		return RuleMin, fmt.Sprintf("不能小于%v", *rule.Min)
+	}
+	if isNumeric && rule.Max != nil && number > *rule.Max {
+		return RuleMax, fmt.Sprintf("不能大于%v", *rule.Max)
+	}
+	length := utf8.RuneCountInString(value)
+	if rule.MinLen > 0 && length < rule.MinLen {
+		return RuleLength, fmt.Sprintf("长度不能小于%d", rule.MinLen)
+	}
+	if rule.MaxLen > 0 && length > rule.MaxLen {
+		return RuleLength, fmt.Sprintf("长度不能大于%d", rule.MaxLen)
+	}
+	if rule.regexp != nil && !rule.regexp.MatchString(value) {
+		return RulePattern, "格式不正确"
+	}
+	if len(rule.Enum) > 0 {
+		for _, v := range rule.Enum {
+			if v == value {
+				return "", ""
+			}
+		}
+		return RuleEnum, fmt.Sprintf("应为%s之一", strings.Join(rule.Enum, "/"))
+	}
+	return "", ""
+}
+
+// WriteViolationWorkbook 把原始文件另存为 dstFile, 并把校验不通过的单元格标红、添加批注,
+// 方便业务人员修改后重新上传. 其他格式的文件会转换为 .xlsx, dstFile 需为 .xlsx 文件;
+// 加密的 .xlsx 通过 WithPassword 提供密码, 标注后的文件使用相同的密码加密
+func WriteViolationWorkbook(srcFile, dstFile string, violations []*Violation, opts ...ReadOption) error {
+	if strings.ToLower(filepath.Ext(dstFile)) != common.FileTypeXlsx {
+		return fmt.Errorf("标注文件只支持%s格式", common.FileTypeXlsx)
+	}
+	var (
+		f       *excelize.File
+		err     error
+		options = newReadOptions(opts...)
+	)
+	if fileTypeOrExt(srcFile) == common.FileTypeXlsx {
+		f, err = openXLSXFile(srcFile, options.password)
+	} else {
+		f, err = copyToWorkbook(srcFile, options)
+	}
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	// 同一个单元格的多条错误合并为一条批注
+	type cellKey struct {
+		sheet, cell string
+	}
+	messages := make(map[cellKey][]string)
+	keys := make([]cellKey, 0, len(violations))
+	for _, v := range violations {
+		cell := fmt.Sprintf("%s%d", v.Column, v.Row)
+		key := cellKey{sheet: v.Sheet, cell: cell}
+		if _, ok := messages[key]; !ok {
+			keys = append(keys, key)
+		}
+		messages[key] = append(messages[key], fmt.Sprintf("%s:%s", v.Title, v.Message))
+	}
+	styles := make(map[int]int) // 原样式 -> 标红后的样式
+	for _, key := range keys {
+		if err = highlightCell(f, key.sheet, key.cell, styles); err != nil {
+			return err
+		}
+		if err = f.AddComment(key.sheet, excelize.Comment{
+			Cell:   key.cell,
+			Author: "校验",
+			Text:   strings.Join(messages[key], "\n"),
+		}); err != nil {
+			return err
+		}
+	}
+	return f.SaveAs(dstFile)
+}
+
+// highlightCell 在单元格原有样式的基础上设置红色背景
+func highlightCell(f *excelize.File, sheet, cell string, styles map[int]int) error {
+	styleID, err := f.GetCellStyle(sheet, cell)
+	if err != nil {
+		return err
+	}
+	newID, ok := styles[styleID]
+	if !ok {
+		style, err := f.GetStyle(styleID)
+		if err != nil {
+			return err
+		}
+		style.Fill = excelize.Fill{Type: "pattern", Pattern: 1, Color: []string{"FFC7CE"}}
+		if newID, err = f.NewStyle(style); err != nil {
+			return err
+		}
+		styles[styleID] = newID
+	}
+	return f.SetCellStyle(sheet, cell, cell, newID)
+}
+
+// copyToWorkbook 把 .xls 或 .csv 文件的原始数据复制到新的工作簿中
+func copyToWorkbook(fileName string, options *readOptions) (*excelize.File, error) {
+	src, err := newSheetSource(fileName, options)
+	if err != nil {
+		return nil, err
+	}
+	defer src.Close()
+	f := excelize.NewFile()
+	typed, _ := src.(typedSource)
+	writer := &cellWriter{file: f, styles: make(map[string]int)}
+	first := true
+	for src.NextSheet() {
+		name := src.SheetName()
+		if first {
+			if err = f.SetSheetName("Sheet1", name); err != nil {
+				f.Close()
+				return nil, err
+			}
+			first = false
+		} else if _, err = f.NewSheet(name); err != nil {
+			f.Close()
+			return nil, err
+		}
+		for row := 1; src.NextRow(); row++ {
+			cells := src.Cells()
+			if len(cells) == 0 {
+				continue
+			}
+			var hints []cellHint
+			if typed != nil {
+				hints = typed.CellHints()
+			}
+			if err = writer.writeRow(name, row, newCells(cells, hints, src.Date1904())); err != nil {
+				f.Close()
+				return nil, err
+			}
+		}
+	}
+	if err = src.Err(); err != nil {
+		f.Close()
+		return nil, err
+	}
+	return f, nil
+}
+
+// cellWriter 按单元格的类型写入数字、日期和布尔值, 避免数字保存为文本
+type cellWriter struct {
+	file   *excelize.File
+	styles map[string]int // 数字格式代码 -> 样式 ID, 缓存
+}
+
+// writeRow 写入第 row 行, 数字和日期按原来的数字格式显示, 没有格式的日期按 yyyy-mm-dd 显示
+func (w *cellWriter) writeRow(sheet string, row int, cells []Cell) error {
+	values := make([]interface{}, len(cells))
+	formats := make([]string, len(cells))
+	for i, c := range cells {
+		values[i], formats[i] = cellWriteValue(c)
+	}
+	if err := w.file.SetSheetRow(sheet, fmt.Sprintf("A%d", row), &values); err != nil {
+		return err
+	}
+	for i, format := range formats {
+		if format == "" {
+			continue
+		}
+		styleID, ok := w.styles[format]
+		if !ok {
+			var err error
+			if styleID, err = w.file.NewStyle(&excelize.Style{CustomNumFmt: &format}); err != nil {
+				return err
+			}
+			w.styles[format] = styleID
+		}
+		cell, err := excelize.CoordinatesToCellName(i+1, row)
+		if err != nil {
+			return err
+		}
+		if err = w.file.SetCellStyle(sheet, cell, cell, styleID); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// cellWriteValue 返回写入工作簿的值和数字格式代码; 文本文件中的百分比没有格式, 和 1900 年以前的日期一样按文本写入
+func cellWriteValue(c Cell) (interface{}, string) {
+	switch c.Kind {
+	case CellKindInt, CellKindFloat:
+		if c.Format == "" && strings.HasSuffix(strings.TrimSpace(c.Text), "%") {
+			return c.Text, ""
+		}
+		return c.Float, c.Format
+	case CellKindBool:
+		return c.Bool, ""
+	case CellKindDate:
+		if c.Time.Year() < 1900 {
+			return c.Text, ""
+		}
+		format := c.Format
+		if format == "" {
+			format = "yyyy-mm-dd"
+			if h, m, s := c.Time.Clock(); h != 0 || m != 0 || s != 0 {
+				format = "yyyy-mm-dd hh:mm:ss"
+			}
+		}
+		return c.Time, format
+	}
+	return c.Text, ""
+}
@@ -0,0 +1,191 @@
+package excelutil
+
+import (
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"github.com/xuri/excelize/v2"
+)
+
+func TestColumnRuleCheck(t *testing.T) {
+	low, high := 1.0, 100.0
+	tests := []struct {
+		name  string
+		rule  ColumnRule
+		value string
+		want  string // 规则名称, 通过时为空
+	}{
+		{"必填为空", ColumnRule{Required: true}, "", RuleRequired},
+		{"非必填为空", ColumnRule{Type: ColumnTypeInt, Min: &low}, "", ""},
+		{"整数", ColumnRule{Type: ColumnTypeInt}, "12", ""},
+		{"整数带小数", ColumnRule{Type: ColumnTypeInt}, "1.5", RuleType},
+		{"数字带千分位", ColumnRule{Type: ColumnTypeNumber}, "1,234.5", ""},
+		{"不是数字", ColumnRule{Type: ColumnTypeNumber}, "abc", RuleType},
+		{"小于最小值", ColumnRule{Type: ColumnTypeNumber, Min: &low, Max: &high}, "0.5", RuleMin},
+		{"大于最大值", ColumnRule{Type: ColumnTypeInt, Min: &low, Max: &high}, "101", RuleMax},
+		{"范围内", ColumnRule{Type: ColumnTypeInt, Min: &low, Max: &high}, "100", ""},
+		{"正则匹配", ColumnRule{Pattern: `^1\d{10}$`}, "13800138000", ""},
+		{"正则不匹配", ColumnRule{Pattern: `^1\d{10}$`}, "1380013800", RulePattern},
+		{"日期", ColumnRule{Type: ColumnTypeDate}, "2023-07-16", ""},
+		{"不是日期", ColumnRule{Type: ColumnTypeDate}, "2023-13-45", RuleType},
+		{"长度", ColumnRule{MaxLen: 2}, "螺丝钉", RuleLength},
+		{"枚举", ColumnRule{Enum: []string{"是", "否"}}, "对", RuleEnum},
+	}
+	for _, tt := range tests {
+		rule := tt.rule
+		if err := compileRules([]*ColumnRule{&rule}); err != nil {
+			t.Fatal(err)
+		}
+		if got, message := rule.check(tt.value); got != tt.want || (got != "") != (message != "") {
+			t.Errorf("%s: check(%q) = %q, %q, want %q", tt.name, tt.value, got, message, tt.want)
+		}
+	}
+	if err := compileRules([]*ColumnRule{{Title: "编码", Pattern: "("}}); err == nil {
+		t.Errorf("compileRules() invalid pattern error = nil, want error")
+	}
+}
+
+// 设置了日期配置的列按配置的输入格式和序列号校验
+func TestBoundRuleCheckDate(t *testing.T) {
+	rule := &boundRule{
+		ColumnRule: &ColumnRule{Type: ColumnTypeDate},
+		date:       &DateColumn{InputLayouts: []string{"02/01/2006"}, ExcelSerial: true},
+	}
+	for value, want := range map[string]string{"16/07/2023": "", "45123": "", "32/07/2023": RuleType} {
+		if got, _ := rule.check(value); got != want {
+			t.Errorf("check(%q) = %q, want %q", value, got, want)
+		}
+	}
+	if got, _ := (&ColumnRule{Type: ColumnTypeDate}).check("16/07/2023"); got != RuleType {
+		t.Errorf("ColumnRule.check(%q) = %q, want %q", "16/07/2023", got, RuleType)
+	}
+}
+
+func TestValidateRow(t *testing.T) {
+	rules := []*ColumnRule{
+		{Title: "编码", Required: true},
+		{Title: "数量", Type: ColumnTypeInt, Message: "数量填写错误"},
+		{Title: "不存在", Required: true},
+	}
+	bound := bindRules([]string{"编码", "名称", "数量"}, rules, newTitleMatcher(nil, nil))
+	if len(bound) != 2 {
+		t.Fatalf("bindRules() = %d rules, want 2", len(bound))
+	}
+	got := validateRow("Sheet1", 3, []string{" ", "螺丝", "十"}, bound)
+	want := []*Violation{
+		{Sheet: "Sheet1", Row: 3, Column: "A", Title: "编码", Rule: RuleRequired, Message: "不能为空"},
+		{Sheet: "Sheet1", Row: 3, Column: "C", Title: "数量", Rule: RuleType, Value: "十", Message: "数量填写错误"},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("validateRow() = %v, want %v", got, want)
+	}
+}
+
+func TestWriteViolationWorkbook(t *testing.T) {
+	rows := [][]string{{"编码", "数量"}, {"001", "10"}, {"", "abc"}}
+	tests := []struct {
+		name    string
+		srcFile string
+		sheet   string
+	}{
+		{"xlsx", writeTestXLSX(t, "src.xlsx", testSheet{name: "库存", rows: rows}), "库存"},
+		{"csv", writeTestFile(t, "src.csv", "编码,数量\n001,10\n,abc\n"), "csv"},
+	}
+	for _, tt := range tests {
+		file, err := ReadExcelFile(tt.srcFile, nil, map[string]string{"编码": "code", "数量": "qty"},
+			WithRules(ColumnRule{Title: "编码", Required: true}, ColumnRule{Title: "数量", Type: ColumnTypeInt}))
+		if err != nil {
+			t.Fatalf("%s: ReadExcelFile() error = %v", tt.name, err)
+		}
+		if len(file.Violations) != 2 {
+			t.Fatalf("%s: Violations = %v, want 2", tt.name, file.Violations)
+		}
+		dstFile := filepath.Join(t.TempDir(), "errors.xlsx")
+		if err = WriteViolationWorkbook(tt.srcFile, dstFile, file.Violations); err != nil {
+			t.Fatalf("%s: WriteViolationWorkbook() error = %v", tt.name, err)
+		}
+		f, err := excelize.OpenFile(dstFile)
+		if err != nil {
+			t.Fatal(err)
+		}
+		got, err := f.GetRows(tt.sheet)
+		if err != nil {
+			t.Fatal(err)
+		}
+		// 第三行第一列为空, GetRows 不返回末尾以外的空单元格
+		if want := [][]string{{"编码", "数量"}, {"001", "10"}, {"", "abc"}}; !reflect.DeepEqual(got, want) {
+			t.Errorf("%s: rows = %q, want %q", tt.name, got, want)
+		}
+		for _, cell := range []string{"A3", "B3", "A2", "B2"} {
+			styleID, err := f.GetCellStyle(tt.sheet, cell)
+			if err != nil {
+				t.Fatal(err)
+			}
+			style, err := f.GetStyle(styleID)
+			if err != nil {
+				t.Fatal(err)
+			}
+			highlighted := reflect.DeepEqual(style.Fill.Color, []string{"FFC7CE"})
+			if want := cell[1] == '3'; highlighted != want {
+				t.Errorf("%s: %s highlighted = %v, want %v", tt.name, cell, highlighted, want)
+			}
+		}
+		comments, err := f.GetComments(tt.sheet)
+		if err != nil {
+			t.Fatal(err)
+		}
+		texts := make(map[string]string)
+		for _, comment := range comments {
+			texts[comment.Cell] = comment.Text
+			for _, paragraph := range comment.Paragraph {
+				texts[comment.Cell] += paragraph.Text
+			}
+		}
+		want := map[string]string{"A3": "编码:不能为空", "B3": "数量:应为整数"}
+		if !reflect.DeepEqual(texts, want) {
+			t.Errorf("%s: comments = %q, want %q", tt.name, texts, want)
+		}
+		f.Close()
+	}
+	if err := WriteViolationWorkbook(tests[0].srcFile, filepath.Join(t.TempDir(), "errors.csv"), nil); err == nil {
+		t.Errorf("WriteViolationWorkbook() csv output error = nil, want error")
+	}
+}
+
+// 转换为 .xlsx 时数字和日期按类型写入, 不会保存为文本; 有前导零的编号和百分比仍为文本
+func TestCopyToWorkbookCellTypes(t *testing.T) {
+	srcFile := writeTestFile(t, "types.csv", "编码,数量,单价,日期,比例\n001,10,2.5,2024-01-02,12.5%\n")
+	f, err := copyToWorkbook(srcFile, newReadOptions())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	tests := []struct {
+		cell    string
+		value   string
+		numeric bool
+	}{
+		{"A2", "001", false},
+		{"B2", "10", true},
+		{"C2", "2.5", true},
+		{"D2", "2024-01-02", true},
+		{"E2", "12.5%", false},
+	}
+	for _, tt := range tests {
+		value, err := f.GetCellValue("csv", tt.cell)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if value != tt.value {
+			t.Errorf("%s = %q, want %q", tt.cell, value, tt.value)
+		}
+		cellType, err := f.GetCellType("csv", tt.cell)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if numeric := (xlsxCellAttr{cellType: cellType}).numeric(); numeric != tt.numeric {
+			t.Errorf("%s numeric = %v, want %v", tt.cell, numeric, tt.numeric)
+		}
+	}
+}
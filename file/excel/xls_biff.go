@@ -0,0 +1,254 @@
+package excelutil
+
+import (
+	"bufio"
+	"encoding/binary"
+	"errors"
+	"io"
+	"math"
+	"unicode/utf16"
+
+	"github.com/extrame/ole2"
+)
+
+// BIFF 记录类型, 见 [MS-XLS] 2.3
+const (
+	biffBOF        = 0x0809
+	biffEOF        = 0x000A
+	biffBoundSheet = 0x0085
+	biffDateMode   = 0x0022
+	biffFormat     = 0x041E
+	biffXF         = 0x00E0
+	biffNumber     = 0x0203
+	biffRK         = 0x027E
+	biffMulRK      = 0x00BD
+	biffFormula    = 0x0006
+	biffMergeCells = 0x00E5
+)
+
+// biffVersion8 BOF 记录中 BIFF8 的版本号, 更早的版本按 BIFF5 处理
+const biffVersion8 = 0x0600
+
+// xlsWorkbookInfo extrame/xls 没有提供的 .xls 工作簿信息: 日期系统、单元格格式和数值单元格
+type xlsWorkbookInfo struct {
+	date1904  bool
+	xfFormats []uint16          // 单元格格式(XF)下标 -> 数字格式编号
+	formats   map[uint16]string // 自定义数字格式编号 -> 格式代码
+	sheets    []*xlsSheetInfo   // 按 BOUNDSHEET 的顺序, 和 extrame/xls 的表单下标一致
+}
+
+// xlsSheetInfo .xls 表单信息
+type xlsSheetInfo struct {
+	name    string
+	offset  uint32 // 表单 BOF 记录在工作簿流中的位置
+	hidden  bool   // 隐藏或深度隐藏
+	merges  []MergeRange
+	numbers map[int][]xlsNumber // 行下标 -> 该行的数值单元格
+}
+
+// xlsNumber .xls 中的数值单元格, 包括 NUMBER、RK、MULRK 和结果为数值的公式
+type xlsNumber struct {
+	col   int
+	xf    uint16
+	value float64
+}
+
+// readXLSWorkbookInfo 扫描 .xls 文件的工作簿流, 读取数字格式和数值单元格
+func readXLSWorkbookInfo(r io.ReadSeeker) (*xlsWorkbookInfo, error) {
+	ole, err := ole2.Open(r, "utf-8")
+	if err != nil {
+		return nil, err
+	}
+	dir, err := ole.ListDir()
+	if err != nil {
+		return nil, err
+	}
+	var book, root *ole2.File
+	for _, f := range dir {
+		switch f.Name() {
+		case "Workbook", "Book":
+			book = f
+		case "Root Entry":
+			root = f
+		}
+	}
+	if book == nil {
+		return nil, errors.New("xls文件中没有工作簿")
+	}
+	return scanBIFF(bufio.NewReader(ole.OpenFile(book, root)))
+}
+
+// scanBIFF 顺序读取 BIFF 记录
+func scanBIFF(r io.Reader) (*xlsWorkbookInfo, error) {
+	info := &xlsWorkbookInfo{formats: make(map[uint16]string)}
+	var (
+		header  [4]byte
+		offset  uint32
+		depth   int // 表单中可能嵌套图表的 BOF/EOF
+		biff8   bool
+		current *xlsSheetInfo
+	)
+	for {
+		if _, err := io.ReadFull(r, header[:]); err != nil {
+			if err == io.EOF || err == io.ErrUnexpectedEOF {
+				return info, nil
+			}
+			return nil, err
+		}
+		id := binary.LittleEndian.Uint16(header[0:])
+		data := make([]byte, binary.LittleEndian.Uint16(header[2:]))
+		if _, err := io.ReadFull(r, data); err != nil {
+			return nil, err
+		}
+		switch id {
+		case biffBOF:
+			if offset == 0 && len(data) >= 2 {
+				biff8 = binary.LittleEndian.Uint16(data) == biffVersion8
+			}
+			if sheet := info.sheetAt(offset); sheet != nil {
+				current = sheet
+			}
+			depth++
+		case biffEOF:
+			if depth--; depth <= 0 {
+				current, depth = nil, 0
+			}
+		case biffBoundSheet:
+			if len(data) >= 6 {
+				info.sheets = append(info.sheets, &xlsSheetInfo{
+					name:    readBIFFString(data[6:], biff8, false),
+					offset:  binary.LittleEndian.Uint32(data),
+					hidden:  data[4]&0x03 != 0,
+					numbers: make(map[int][]xlsNumber),
+				})
+			}
+		case biffDateMode:
+			info.date1904 = len(data) >= 2 && binary.LittleEndian.Uint16(data) == 1
+		case biffFormat:
+			if len(data) >= 2 {
+				info.formats[binary.LittleEndian.Uint16(data)] = readBIFFString(data[2:], biff8, true)
+			}
+		case biffXF:
+			if len(data) >= 4 {
+				info.xfFormats = append(info.xfFormats, binary.LittleEndian.Uint16(data[2:]))
+			}
+		case biffNumber:
+			if current != nil && len(data) >= 14 {
+				current.add(data, math.Float64frombits(binary.LittleEndian.Uint64(data[6:])))
+			}
+		case biffRK:
+			if current != nil && len(data) >= 10 {
+				current.add(data, rkValue(binary.LittleEndian.Uint32(data[6:])))
+			}
+		case biffMulRK:
+			if current != nil && len(data) >= 6 {
+				row := int(binary.LittleEndian.Uint16(data))
+				col := int(binary.LittleEndian.Uint16(data[2:]))
+				for p := 4; p+6 <= len(data)-2; p += 6 {
+					current.numbers[row] = append(current.numbers[row], xlsNumber{
+						col:   col,
+						xf:    binary.LittleEndian.Uint16(data[p:]),
+						value: rkValue(binary.LittleEndian.Uint32(data[p+2:])),
+					})
+					col++
+				}
+			}
+		case biffMergeCells:
+			// 区域数量之后是每个区域的起始行、结束行、起始列、结束列, 从 0 开始
+			if current != nil && len(data) >= 2 {
+				count := int(binary.LittleEndian.Uint16(data))
+				for p := 2; count > 0 && p+8 <= len(data); p, count = p+8, count-1 {
+					current.merges = append(current.merges, newMergeRange(
+						int(binary.LittleEndian.Uint16(data[p:]))+1,
+						int(binary.LittleEndian.Uint16(data[p+4:]))+1,
+						int(binary.LittleEndian.Uint16(data[p+2:]))+1,
+						int(binary.LittleEndian.Uint16(data[p+6:]))+1,
+					))
+				}
+			}
+		case biffFormula:
+			// 结果的最后两个字节为 0xFFFF 时不是数值
+			if current != nil && len(data) >= 14 && binary.LittleEndian.Uint16(data[12:]) != 0xFFFF {
+				current.add(data, math.Float64frombits(binary.LittleEndian.Uint64(data[6:])))
+			}
+		}
+		offset += 4 + uint32(len(data))
+	}
+}
+
+// sheetAt 返回 BOF 记录位置为 offset 的表单
+func (info *xlsWorkbookInfo) sheetAt(offset uint32) *xlsSheetInfo {
+	for _, sheet := range info.sheets {
+		if sheet.offset == offset {
+			return sheet
+		}
+	}
+	return nil
+}
+
+// add 添加数值单元格, data 以行、列、XF 下标开头
+func (sheet *xlsSheetInfo) add(data []byte, value float64) {
+	row := int(binary.LittleEndian.Uint16(data))
+	sheet.numbers[row] = append(sheet.numbers[row], xlsNumber{
+		col:   int(binary.LittleEndian.Uint16(data[2:])),
+		xf:    binary.LittleEndian.Uint16(data[4:]),
+		value: value,
+	})
+}
+
+// format 按单元格的数字格式输出数值
+func (info *xlsWorkbookInfo) format(n xlsNumber) string {
+	var id uint16
+	if int(n.xf) < len(info.xfFormats) {
+		id = info.xfFormats[n.xf]
+	}
+	return formatNumber(n.value, info.date1904, int(id), info.formats[id])
+}
+
+// rkValue 解析 RK 压缩格式的数值
+func rkValue(rk uint32) float64 {
+	var value float64
+	if rk&0x02 != 0 {
+		value = float64(int32(rk) >> 2)
+	} else {
+		value = math.Float64frombits(uint64(rk&0xFFFFFFFC) << 32)
+	}
+	if rk&0x01 != 0 {
+		value /= 100
+	}
+	return value
+}
+
+// readBIFFString 读取 BIFF 字符串, BIFF8 中长度后有一个字节的标志, 最低位表示是否为 UTF-16;
+// wide 表示长度为两个字节
+func readBIFFString(data []byte, biff8, wide bool) string {
+	var n int
+	switch {
+	case wide && biff8 && len(data) >= 2:
+		n, data = int(binary.LittleEndian.Uint16(data)), data[2:]
+	case len(data) >= 1:
+		n, data = int(data[0]), data[1:]
+	default:
+		return ""
+	}
+	if !biff8 {
+		return string(data[:min(n, len(data))])
+	}
+	if len(data) < 1 {
+		return ""
+	}
+	flags, data := data[0], data[1:]
+	if flags&0x01 == 0 {
+		// 压缩的 Latin-1 字符
+		runes := make([]rune, 0, n)
+		for _, c := range data[:min(n, len(data))] {
+			runes = append(runes, rune(c))
+		}
+		return string(runes)
+	}
+	units := make([]uint16, 0, n)
+	for i := 0; i < n && 2*i+1 < len(data); i++ {
+		units = append(units, binary.LittleEndian.Uint16(data[2*i:]))
+	}
+	return string(utf16.Decode(units))
+}
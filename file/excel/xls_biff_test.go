@@ -0,0 +1,219 @@
+package excelutil
+
+import (
+	"bytes"
+	"encoding/binary"
+	"math"
+	"reflect"
+	"testing"
+	"unicode/utf16"
+)
+
+// biffRecord 生成一条 BIFF 记录
+func biffRecord(id uint16, data ...[]byte) []byte {
+	body := bytes.Join(data, nil)
+	record := binary.LittleEndian.AppendUint16(nil, id)
+	record = binary.LittleEndian.AppendUint16(record, uint16(len(body)))
+	return append(record, body...)
+}
+
+func u16(values ...uint16) []byte {
+	var b []byte
+	for _, v := range values {
+		b = binary.LittleEndian.AppendUint16(b, v)
+	}
+	return b
+}
+
+func u32(v uint32) []byte {
+	return binary.LittleEndian.AppendUint32(nil, v)
+}
+
+func f64(v float64) []byte {
+	return binary.LittleEndian.AppendUint64(nil, math.Float64bits(v))
+}
+
+// rkInt 生成整数的 RK 值, div100 表示数值需要除以 100
+func rkInt(v int32, div100 bool) uint32 {
+	rk := uint32(v)<<2 | 0x02
+	if div100 {
+		rk |= 0x01
+	}
+	return rk
+}
+
+func TestRKValue(t *testing.T) {
+	tests := []struct {
+		name string
+		rk   uint32
+		want float64
+	}{
+		{"整数", rkInt(123, false), 123},
+		{"负整数", rkInt(-5, false), -5},
+		{"整数除以100", rkInt(12345, true), 123.45},
+		{"浮点数", uint32(math.Float64bits(2.5) >> 32), 2.5},
+		{"浮点数除以100", uint32(math.Float64bits(150)>>32) | 0x01, 1.5},
+		{"零", 0, 0},
+	}
+	for _, tt := range tests {
+		if got := rkValue(tt.rk); got != tt.want {
+			t.Errorf("%s: rkValue(%#x) = %v, want %v", tt.name, tt.rk, got, tt.want)
+		}
+	}
+}
+
+func TestReadBIFFString(t *testing.T) {
+	tests := []struct {
+		name  string
+		data  []byte
+		biff8 bool
+		wide  bool
+		want  string
+	}{
+		{"BIFF5", append([]byte{5}, "Sheet"...), false, false, "Sheet"},
+		{"BIFF5两字节长度的格式仍为一个字节", append([]byte{4}, "0.00"...), false, true, "0.00"},
+		{"BIFF8压缩字符", append([]byte{4, 0}, "abc\xe9"...), true, false, "abcé"},
+		{"BIFF8 UTF-16", append([]byte{2, 1}, u16('数', '据')...), true, false, "数据"},
+		{"BIFF8两字节长度", append(append(u16(3), 0), "yyy"...), true, true, "yyy"},
+		{"BIFF8两字节长度 UTF-16", append(append(u16(2), 1), u16('年', '月')...), true, true, "年月"},
+		{"长度超出数据", append([]byte{10, 0}, "ab"...), true, false, "ab"},
+		{"截断的 UTF-16", append([]byte{2, 1}, u16('数')...), true, false, "数"},
+		{"空数据", nil, true, true, ""},
+		{"缺少标志", []byte{3}, true, false, ""},
+	}
+	for _, tt := range tests {
+		if got := readBIFFString(tt.data, tt.biff8, tt.wide); got != tt.want {
+			t.Errorf("%s: readBIFFString() = %q, want %q", tt.name, got, tt.want)
+		}
+	}
+}
+
+// biffSheet 测试用的 BIFF8 表单
+type biffSheet struct {
+	name    string
+	records [][]byte
+}
+
+// biffWorkbook 生成包含一个表单的 BIFF8 工作簿流
+func biffWorkbook(sheetRecords ...[]byte) []byte {
+	return biffSheets(biffSheet{name: "Sheet1", records: sheetRecords})
+}
+
+// biffSheets 生成包含多个表单的 BIFF8 工作簿流, 表单依次写在全局记录之后
+func biffSheets(sheets ...biffSheet) []byte {
+	xf := func(format uint16) []byte {
+		return biffRecord(biffXF, u16(0, format), make([]byte, 16))
+	}
+	globals := func(offsets []uint32) []byte {
+		records := [][]byte{
+			biffRecord(biffBOF, u16(biffVersion8, 0x0005), make([]byte, 12)),
+			biffRecord(biffDateMode, u16(0)),
+			biffRecord(biffFormat, u16(164, 10), []byte{0}, []byte("yyyy/mm/dd")),
+			xf(0),
+			xf(14),
+			xf(164),
+			xf(10),
+		}
+		for i, sheet := range sheets {
+			name := utf16.Encode([]rune(sheet.name))
+			records = append(records, biffRecord(biffBoundSheet, u32(offsets[i]), []byte{0, 0, byte(len(name)), 1},
+				u16(name...)))
+		}
+		return bytes.Join(append(records, biffRecord(biffEOF)), nil)
+	}
+	streams := make([][]byte, len(sheets))
+	for i, sheet := range sheets {
+		records := [][]byte{biffRecord(biffBOF, u16(biffVersion8, 0x0010), make([]byte, 12))}
+		records = append(records, sheet.records...)
+		streams[i] = bytes.Join(append(records, biffRecord(biffEOF)), nil)
+	}
+	offsets := make([]uint32, len(sheets))
+	offset := uint32(len(globals(offsets)))
+	for i, stream := range streams {
+		offsets[i] = offset
+		offset += uint32(len(stream))
+	}
+	return append(globals(offsets), bytes.Join(streams, nil)...)
+}
+
+// biffLabels 生成文本单元格的 ROW 和 LABEL 记录, extrame/xls 按 ROW 记录确定每行的列数
+func biffLabels(rows [][]string) [][]byte {
+	var records [][]byte
+	for i, row := range rows {
+		records = append(records, biffRecord(0x0208, u16(uint16(i), 0, uint16(len(row)), 0x00FF, 0, 0), u32(0x0100)))
+		for j, value := range row {
+			text := utf16.Encode([]rune(value))
+			records = append(records, biffRecord(0x0204, u16(uint16(i), uint16(j), 0, uint16(len(text))), []byte{1},
+				u16(text...)))
+		}
+	}
+	return records
+}
+
+func TestScanBIFF(t *testing.T) {
+	stream := biffWorkbook(
+		biffRecord(biffNumber, u16(0, 0, 0), f64(3.25)),
+		biffRecord(biffRK, u16(0, 1, 1), u32(rkInt(45123, false))),
+		biffRecord(biffMulRK, u16(1, 2), u16(2), u32(rkInt(45124, false)), u16(3), u32(rkInt(25, true)), u16(3)),
+		// 数值结果的公式和字符串结果的公式
+		biffRecord(biffFormula, u16(2, 0, 0), f64(42), make([]byte, 6)),
+		biffRecord(biffFormula, u16(2, 1, 0), []byte{0, 0, 0, 0, 0, 0}, u16(0xFFFF), make([]byte, 6)),
+		// 图表中的记录不属于表单数据
+		biffRecord(biffBOF, u16(biffVersion8, 0x0020), make([]byte, 12)),
+		biffRecord(biffEOF),
+		biffRecord(biffMergeCells, u16(2), u16(3, 4, 0, 1), u16(5, 9, 2, 2)),
+	)
+	info, err := scanBIFF(bytes.NewReader(stream))
+	if err != nil {
+		t.Fatalf("scanBIFF() error = %v", err)
+	}
+	if info.date1904 {
+		t.Errorf("date1904 = true, want false")
+	}
+	if got := info.formats[164]; got != "yyyy/mm/dd" {
+		t.Errorf("formats[164] = %q, want %q", got, "yyyy/mm/dd")
+	}
+	if want := []uint16{0, 14, 164, 10}; !reflect.DeepEqual(info.xfFormats, want) {
+		t.Errorf("xfFormats = %v, want %v", info.xfFormats, want)
+	}
+	if len(info.sheets) != 1 {
+		t.Fatalf("len(sheets) = %d, want 1", len(info.sheets))
+	}
+	sheet := info.sheets[0]
+	if sheet.name != "Sheet1" || sheet.hidden {
+		t.Errorf("sheet = %q hidden %v, want %q hidden false", sheet.name, sheet.hidden, "Sheet1")
+	}
+	wantNumbers := map[int][]xlsNumber{
+		0: {{col: 0, xf: 0, value: 3.25}, {col: 1, xf: 1, value: 45123}},
+		1: {{col: 2, xf: 2, value: 45124}, {col: 3, xf: 3, value: 0.25}},
+		2: {{col: 0, xf: 0, value: 42}},
+	}
+	if !reflect.DeepEqual(sheet.numbers, wantNumbers) {
+		t.Errorf("numbers = %v, want %v", sheet.numbers, wantNumbers)
+	}
+	wantMerges := []MergeRange{newMergeRange(4, 1, 5, 2), newMergeRange(6, 3, 10, 3)}
+	if !reflect.DeepEqual(sheet.merges, wantMerges) {
+		t.Errorf("merges = %v, want %v", sheet.merges, wantMerges)
+	}
+
+	// XF 1 为内置日期格式, XF 2 为自定义日期格式, XF 3 为百分比
+	wantFormatted := []string{"3.25", "2023-07-16", "2023-07-17", "0.25"}
+	var formatted []string
+	for _, n := range append(sheet.numbers[0], sheet.numbers[1]...) {
+		formatted = append(formatted, info.format(n))
+	}
+	if !reflect.DeepEqual(formatted, wantFormatted) {
+		t.Errorf("format() = %v, want %v", formatted, wantFormatted)
+	}
+}
+
+func TestScanBIFFTruncated(t *testing.T) {
+	stream := biffWorkbook(biffRecord(biffNumber, u16(0, 0, 0), f64(1)))
+	// 截断在记录头中间时返回已读取的内容, 截断在记录数据中间时返回错误
+	if _, err := scanBIFF(bytes.NewReader(append(stream, 0x03))); err != nil {
+		t.Errorf("scanBIFF() truncated header error = %v", err)
+	}
+	if _, err := scanBIFF(bytes.NewReader(append(stream, biffRecord(biffNumber, u16(0, 0, 0))[:6]...))); err == nil {
+		t.Errorf("scanBIFF() truncated record error = nil, want error")
+	}
+}
@@ -0,0 +1,177 @@
+package excelutil
+
+import (
+	"archive/zip"
+	"encoding/xml"
+	"io"
+	"strconv"
+
+	"github.com/xuri/excelize/v2"
+)
+
+// xlsxCellAttr 表单 XML 中单元格的类型、样式和是否有公式
+type xlsxCellAttr struct {
+	cellType excelize.CellType
+	style    int
+	formula  bool
+}
+
+// numeric 单元格是否按数字保存, 没有 t 属性的单元格也是数字
+func (a xlsxCellAttr) numeric() bool {
+	return a.cellType == excelize.CellTypeNumber || a.cellType == excelize.CellTypeUnset
+}
+
+// xlsxCellTypes 单元格 t 属性对应的类型, 和 excelize 一致
+var xlsxCellTypes = map[string]excelize.CellType{
+	"b":         excelize.CellTypeBool,
+	"d":         excelize.CellTypeDate,
+	"n":         excelize.CellTypeNumber,
+	"e":         excelize.CellTypeError,
+	"s":         excelize.CellTypeSharedString,
+	"str":       excelize.CellTypeFormula,
+	"inlineStr": excelize.CellTypeInlineString,
+}
+
+// xlsxCellScanner 和 excelize.Rows 同步地流式读取表单 XML 中单元格的属性;
+// excelize 的 GetCellType、GetCellStyle 和 GetCellFormula 第一次调用时会把整个表单读入内存
+type xlsxCellScanner struct {
+	rc      io.ReadCloser
+	decoder *xml.Decoder
+	rowNum  int            // attrs 对应的行号
+	attrs   []xlsxCellAttr // 第 rowNum 行的单元格属性, 下标为列号减一
+	pending bool           // 已读到下一个 <row> 的开始标签, 行号大于请求的行号
+	nextNum int            // 下一个 <row> 的行号
+	lastNum int            // 最后读到的 <row> 的行号, 用于没有 r 属性的行
+	done    bool
+}
+
+func newXLSXCellScanner(f *zip.File) (*xlsxCellScanner, error) {
+	rc, err := f.Open()
+	if err != nil {
+		return nil, err
+	}
+	return &xlsxCellScanner{rc: rc, decoder: xml.NewDecoder(rc), attrs: make([]xlsxCellAttr, 0, 16)}, nil
+}
+
+// row 返回第 rowNum 行的单元格属性, 没有数据的行返回空切片, 不会返回 nil; 行号需要递增
+func (s *xlsxCellScanner) row(rowNum int) ([]xlsxCellAttr, error) {
+	if rowNum == s.rowNum {
+		return s.attrs, nil
+	}
+	s.rowNum, s.attrs = rowNum, s.attrs[:0]
+	for !s.done {
+		if !s.pending {
+			if err := s.nextRow(); err != nil {
+				return nil, err
+			}
+			continue
+		}
+		switch {
+		case s.nextNum < rowNum:
+			if err := s.decoder.Skip(); err != nil {
+				return nil, err
+			}
+			s.pending = false
+		case s.nextNum == rowNum:
+			s.pending = false
+			return s.attrs, s.readCells()
+		default:
+			return s.attrs, nil
+		}
+	}
+	return s.attrs, nil
+}
+
+// nextRow 读到下一个 <row>, 没有更多的行时设置 done
+func (s *xlsxCellScanner) nextRow() error {
+	for {
+		token, err := s.decoder.Token()
+		if err == io.EOF {
+			s.done = true
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		if start, ok := token.(xml.StartElement); ok && start.Name.Local == "row" {
+			s.lastNum++
+			if n, err := strconv.Atoi(xmlAttr(start, "r")); err == nil {
+				s.lastNum = n
+			}
+			s.pending, s.nextNum = true, s.lastNum
+			return nil
+		}
+	}
+}
+
+// readCells 读取当前 <row> 中的单元格, 没有 r 属性的单元格在上一个单元格的右侧
+func (s *xlsxCellScanner) readCells() error {
+	col := 0
+	for {
+		token, err := s.decoder.Token()
+		if err != nil {
+			return err
+		}
+		switch t := token.(type) {
+		case xml.StartElement:
+			if t.Name.Local != "c" {
+				if err = s.decoder.Skip(); err != nil {
+					return err
+				}
+				continue
+			}
+			col++
+			if ref := xmlAttr(t, "r"); ref != "" {
+				if col, _, err = excelize.CellNameToCoordinates(ref); err != nil {
+					return err
+				}
+			}
+			attr := xlsxCellAttr{cellType: xlsxCellTypes[xmlAttr(t, "t")]}
+			attr.style, _ = strconv.Atoi(xmlAttr(t, "s"))
+			if attr.formula, err = s.hasFormula(); err != nil {
+				return err
+			}
+			for len(s.attrs) < col {
+				s.attrs = append(s.attrs, xlsxCellAttr{})
+			}
+			s.attrs[col-1] = attr
+		case xml.EndElement:
+			if t.Name.Local == "row" {
+				return nil
+			}
+		}
+	}
+}
+
+// hasFormula 读取单元格的子元素, 判断是否有 <f>
+func (s *xlsxCellScanner) hasFormula() (bool, error) {
+	formula := false
+	for depth := 1; depth > 0; {
+		token, err := s.decoder.Token()
+		if err != nil {
+			return false, err
+		}
+		switch t := token.(type) {
+		case xml.StartElement:
+			formula = formula || t.Name.Local == "f"
+			depth++
+		case xml.EndElement:
+			depth--
+		}
+	}
+	return formula, nil
+}
+
+func (s *xlsxCellScanner) Close() error {
+	return s.rc.Close()
+}
+
+// xmlAttr 读取不带命名空间的属性值
+func xmlAttr(start xml.StartElement, local string) string {
+	for _, attr := range start.Attr {
+		if attr.Name.Space == "" && attr.Name.Local == local {
+			return attr.Value
+		}
+	}
+	return ""
+}
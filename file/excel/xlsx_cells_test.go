@@ -0,0 +1,136 @@
+package excelutil
+
+import (
+	"archive/zip"
+	"bytes"
+	"reflect"
+	"testing"
+
+	"github.com/xuri/excelize/v2"
+)
+
+// newTestZipFile 生成只包含一个文件的 zip, 返回其中的文件
+func newTestZipFile(t *testing.T, name, content string) *zip.File {
+	t.Helper()
+	var buf bytes.Buffer
+	zw := zip.NewWriter(&buf)
+	w, err := zw.Create(name)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err = w.Write([]byte(content)); err != nil {
+		t.Fatal(err)
+	}
+	if err = zw.Close(); err != nil {
+		t.Fatal(err)
+	}
+	zr, err := zip.NewReader(bytes.NewReader(buf.Bytes()), int64(buf.Len()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return zr.File[0]
+}
+
+func TestXLSXCellScanner(t *testing.T) {
+	f := newTestZipFile(t, "sheet1.xml", `<?xml version="1.0" encoding="UTF-8"?>
+<worksheet xmlns="http://schemas.openxmlformats.org/spreadsheetml/2006/main">
+<sheetData>
+	<row r="1"><c r="A1" t="s"><v>0</v></c><c r="C1" s="2"><v>45123</v></c></row>
+	<row r="3"><c r="B3" t="str"><f>A1&amp;"x"</f><v>ax</v></c><c r="D3" t="b"><v>1</v></c></row>
+	<row><c t="inlineStr"><is><t>a</t></is></c><c s="1"><f t="shared" si="0"/><v>2</v></c></row>
+	<row r="6"><c r="A6" t="e"><v>#DIV/0!</v></c></row>
+</sheetData>
+<mergeCells count="1"><mergeCell ref="A1:B1"/></mergeCells>
+</worksheet>`)
+	scanner, err := newXLSXCellScanner(f)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer scanner.Close()
+	want := [][]xlsxCellAttr{
+		{{cellType: excelize.CellTypeSharedString}, {}, {cellType: excelize.CellTypeUnset, style: 2}},
+		{},
+		{{}, {cellType: excelize.CellTypeFormula, formula: true}, {}, {cellType: excelize.CellTypeBool}},
+		{{cellType: excelize.CellTypeInlineString}, {style: 1, formula: true}},
+		{},
+		{{cellType: excelize.CellTypeError}},
+		{},
+	}
+	for i, wantRow := range want {
+		// 同一行读取两次返回相同的结果
+		for n := 0; n < 2; n++ {
+			got, err := scanner.row(i + 1)
+			if err != nil {
+				t.Fatalf("row(%d) error = %v", i+1, err)
+			}
+			if got == nil || len(got) != len(wantRow) || len(got) > 0 && !reflect.DeepEqual(got, wantRow) {
+				t.Errorf("row(%d) = %+v, want %+v", i+1, got, wantRow)
+			}
+		}
+	}
+}
+
+// 流式读取的单元格类型和从 excelize 读取的一致
+func TestXLSXSourceCellHints(t *testing.T) {
+	f := excelize.NewFile()
+	dateStyle, err := f.NewStyle(&excelize.Style{NumFmt: 14})
+	if err != nil {
+		t.Fatal(err)
+	}
+	customStyle, err := f.NewStyle(&excelize.Style{CustomNumFmt: func() *string { s := "yyyy/mm/dd hh:mm"; return &s }()})
+	if err != nil {
+		t.Fatal(err)
+	}
+	values := map[string]any{
+		"A1": "编码", "B1": "数量", "C1": "日期", "D1": "完成", "E1": "合计",
+		"A2": "001", "B2": 10, "C2": 45123, "D2": true, "E2": 10.5,
+		"A4": "003", "B4": 2.25, "C4": 45124.5, "D4": false,
+	}
+	for cell, value := range values {
+		if err = f.SetCellValue("Sheet1", cell, value); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err = f.SetCellStyle("Sheet1", "C2", "C2", dateStyle); err != nil {
+		t.Fatal(err)
+	}
+	if err = f.SetCellStyle("Sheet1", "C4", "C4", customStyle); err != nil {
+		t.Fatal(err)
+	}
+	if err = f.SetCellFormula("Sheet1", "E4", "B2+B4"); err != nil {
+		t.Fatal(err)
+	}
+	buf, err := f.WriteToBuffer()
+	if err != nil {
+		t.Fatal(err)
+	}
+	r := bytes.NewReader(buf.Bytes())
+	src, err := newXLSXSource(r, r.Size(), "test.xlsx", nil, "", FormulaCached)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer src.Close()
+	if !src.NextSheet() {
+		t.Fatalf("NextSheet() = false, error %v", src.Err())
+	}
+	hinter := newXLSXHinter(src.file)
+	for src.NextRow() {
+		streamed := src.CellHints()
+		if src.Err() != nil {
+			t.Fatal(src.Err())
+		}
+		if src.scanner == nil {
+			t.Fatalf("row %d: cell attributes were not streamed", src.rowNum)
+		}
+		dom, err := hinter.row(src.SheetName(), src.rowNum, src.Cells(), src.RawCells(), nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !reflect.DeepEqual(streamed, dom) {
+			t.Errorf("row %d: streamed hints = %+v, want %+v", src.rowNum, streamed, dom)
+		}
+	}
+	if src.Err() != nil {
+		t.Fatal(src.Err())
+	}
+}
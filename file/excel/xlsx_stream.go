@@ -0,0 +1,341 @@
+package excelutil
+
+import (
+	"archive/zip"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"strconv"
+
+	"github.com/xuri/excelize/v2"
+)
+
+// xlsxStreamSource 直接从 zip 中流式读取 .xlsx 表单, 只读取用到的行和共享字符串, 用于预览;
+// excelize 打开文件时会把整个文件读入内存. 不支持加密的文件和公式计算, 数值按 formatNumber 输出,
+// 和 .xls 一致
+type xlsxStreamSource struct {
+	workbook  *xlsxZipWorkbook
+	strings   *xlsxSharedStrings
+	xfFormats []numFmt // 单元格样式下标 -> 数字格式
+	closer    io.Closer
+	index     int
+	rc        io.ReadCloser // 当前表单的 XML
+	decoder   *xml.Decoder
+	rowNum    int      // 当前行号
+	next      *xlsxRow // 已读取还没有输出的行, 行号大于当前行号
+	done      bool     // 当前表单已读完
+	cells     []string
+	rawCells  []string
+	err       error
+}
+
+// xlsxRow 表单 XML 中的 <row>
+type xlsxRow struct {
+	R     int `xml:"r,attr"`
+	Cells []struct {
+		R  string       `xml:"r,attr"`
+		T  string       `xml:"t,attr"`
+		S  int          `xml:"s,attr"`
+		F  *struct{}    `xml:"f"`
+		V  string       `xml:"v"`
+		IS xlsxRichText `xml:"is"`
+	} `xml:"c"`
+}
+
+// xlsxRichText 共享字符串 <si> 和行内字符串 <is>, 不包括拼音 <rPh>
+type xlsxRichText struct {
+	T    string `xml:"t"`
+	Runs []struct {
+		T string `xml:"t"`
+	} `xml:"r"`
+}
+
+func (t *xlsxRichText) String() string {
+	text := t.T
+	for _, run := range t.Runs {
+		text += run.T
+	}
+	return text
+}
+
+func newXLSXStreamSource(r io.ReaderAt, size int64, closer io.Closer) (*xlsxStreamSource, error) {
+	zr, err := zip.NewReader(r, size)
+	if err != nil {
+		return nil, err
+	}
+	workbook, err := readXLSXWorkbook(zr)
+	if err != nil {
+		return nil, err
+	}
+	s := &xlsxStreamSource{workbook: workbook, closer: closer, index: -1}
+	if workbook.sharedStrings != nil {
+		s.strings = &xlsxSharedStrings{file: workbook.sharedStrings}
+	}
+	if workbook.styles != nil {
+		if s.xfFormats, err = readXLSXNumFmts(workbook.styles); err != nil {
+			return nil, err
+		}
+	}
+	return s, nil
+}
+
+// readXLSXNumFmts 读取 styles.xml 中每个单元格样式的数字格式
+func readXLSXNumFmts(f *zip.File) ([]numFmt, error) {
+	var styles struct {
+		NumFmts []struct {
+			ID   int    `xml:"numFmtId,attr"`
+			Code string `xml:"formatCode,attr"`
+		} `xml:"numFmts>numFmt"`
+		CellXfs []struct {
+			NumFmtID int `xml:"numFmtId,attr"`
+		} `xml:"cellXfs>xf"`
+	}
+	if err := decodeZipXML(f, &styles); err != nil {
+		return nil, err
+	}
+	codes := make(map[int]string, len(styles.NumFmts))
+	for _, format := range styles.NumFmts {
+		codes[format.ID] = format.Code
+	}
+	formats := make([]numFmt, len(styles.CellXfs))
+	for i, xf := range styles.CellXfs {
+		formats[i] = numFmt{id: xf.NumFmtID, code: codes[xf.NumFmtID]}
+	}
+	return formats, nil
+}
+
+func (s *xlsxStreamSource) NextSheet() bool {
+	if s.err != nil {
+		return false
+	}
+	if s.rc != nil {
+		if s.err = s.rc.Close(); s.err != nil {
+			return false
+		}
+		s.rc, s.decoder = nil, nil
+	}
+	s.rowNum, s.next, s.done = 0, nil, false
+	s.index++
+	if s.index >= len(s.workbook.sheets) {
+		return false
+	}
+	if s.rc, s.err = s.workbook.sheets[s.index].file.Open(); s.err != nil {
+		return false
+	}
+	s.decoder = xml.NewDecoder(s.rc)
+	return true
+}
+
+func (s *xlsxStreamSource) SheetName() string {
+	if s.index < 0 || s.index >= len(s.workbook.sheets) {
+		return ""
+	}
+	return s.workbook.sheets[s.index].name
+}
+
+func (s *xlsxStreamSource) SheetIndex() int {
+	return s.index
+}
+
+func (s *xlsxStreamSource) Hidden() bool {
+	return s.index >= 0 && s.index < len(s.workbook.sheets) && s.workbook.sheets[s.index].hidden
+}
+
+func (s *xlsxStreamSource) MergeRanges() []MergeRange {
+	if s.err != nil || s.index < 0 || s.index >= len(s.workbook.sheets) {
+		return nil
+	}
+	var ranges []MergeRange
+	ranges, s.err = zipMergeRanges(s.workbook.sheets[s.index].file)
+	return ranges
+}
+
+// NextRow 没有 <row> 的行输出为空行, 行号和表单一致; 单元格和 xlsxSource 一样不包括末尾只有样式的空单元格
+func (s *xlsxStreamSource) NextRow() bool {
+	if s.err != nil || s.decoder == nil {
+		return false
+	}
+	if s.next == nil && !s.done {
+		if s.next, s.err = s.readRow(); s.err != nil {
+			return false
+		}
+		s.done = s.next == nil
+	}
+	if s.next == nil {
+		return false
+	}
+	s.rowNum++
+	s.cells, s.rawCells = s.cells[:0], s.rawCells[:0]
+	if s.next.R > s.rowNum {
+		return true
+	}
+	row := s.next
+	s.next = nil
+	col := 0
+	for _, c := range row.Cells {
+		col++
+		if c.R != "" {
+			var err error
+			if col, _, err = excelize.CellNameToCoordinates(c.R); err != nil {
+				s.err = err
+				return false
+			}
+		}
+		value, raw, err := s.cellValue(c.T, c.S, c.V, &c.IS)
+		if err != nil {
+			s.err = err
+			return false
+		}
+		if value == "" && c.F == nil {
+			// 和 excelize 一致, 只有样式的空单元格不输出
+			continue
+		}
+		for len(s.cells) < col {
+			s.cells, s.rawCells = append(s.cells, ""), append(s.rawCells, "")
+		}
+		s.cells[col-1], s.rawCells[col-1] = value, raw
+	}
+	return true
+}
+
+// readRow 读取下一个 <row>, 表单读完时返回 nil; 没有 r 属性的行在上一行的下一行
+func (s *xlsxStreamSource) readRow() (*xlsxRow, error) {
+	for {
+		token, err := s.decoder.Token()
+		if err == io.EOF {
+			return nil, nil
+		}
+		if err != nil {
+			return nil, err
+		}
+		switch t := token.(type) {
+		case xml.StartElement:
+			if t.Name.Local != "row" {
+				continue
+			}
+			row := &xlsxRow{}
+			if err = s.decoder.DecodeElement(row, &t); err != nil {
+				return nil, err
+			}
+			if row.R == 0 {
+				row.R = s.rowNum + 1
+			}
+			return row, nil
+		case xml.EndElement:
+			if t.Name.Local == "sheetData" {
+				return nil, nil
+			}
+		}
+	}
+}
+
+// cellValue 按单元格类型返回显示的值和原始值, 数值按样式的数字格式输出, 原始值中的科学计数法转换为完整的数字
+func (s *xlsxStreamSource) cellValue(cellType string, style int, value string, inline *xlsxRichText) (
+	string, string, error) {
+	switch cellType {
+	case "s":
+		i, err := strconv.Atoi(value)
+		if err != nil || s.strings == nil {
+			return "", "", fmt.Errorf("共享字符串下标%s错误", value)
+		}
+		text, err := s.strings.get(i)
+		return text, text, err
+	case "inlineStr":
+		text := inline.String()
+		return text, text, nil
+	case "b":
+		if value == "1" {
+			return "TRUE", value, nil
+		}
+		return "FALSE", value, nil
+	case "", "n":
+		f, err := strconv.ParseFloat(value, 64)
+		if err != nil {
+			return value, value, nil
+		}
+		var format numFmt
+		if style >= 0 && style < len(s.xfFormats) {
+			format = s.xfFormats[style]
+		}
+		return formatNumber(f, s.workbook.date1904, format.id, format.code), plainNumber(value), nil
+	}
+	return value, value, nil
+}
+
+func (s *xlsxStreamSource) Cells() []string {
+	return s.cells
+}
+
+func (s *xlsxStreamSource) RawCells() []string {
+	return s.rawCells
+}
+
+func (s *xlsxStreamSource) Date1904() bool {
+	return s.workbook.date1904
+}
+
+func (s *xlsxStreamSource) Err() error {
+	return s.err
+}
+
+func (s *xlsxStreamSource) Close() error {
+	var err error
+	if s.rc != nil {
+		err = s.rc.Close()
+	}
+	if s.strings != nil {
+		_ = s.strings.Close()
+	}
+	if s.closer != nil {
+		_ = s.closer.Close()
+	}
+	return err
+}
+
+// xlsxSharedStrings 按需流式读取共享字符串, 只读取到用到的最大下标
+type xlsxSharedStrings struct {
+	file    *zip.File
+	rc      io.ReadCloser
+	decoder *xml.Decoder
+	strings []string
+	done    bool
+}
+
+// get 返回第 i 个共享字符串
+func (s *xlsxSharedStrings) get(i int) (string, error) {
+	if s.rc == nil && !s.done {
+		rc, err := s.file.Open()
+		if err != nil {
+			return "", err
+		}
+		s.rc, s.decoder = rc, xml.NewDecoder(rc)
+	}
+	for len(s.strings) <= i && !s.done {
+		token, err := s.decoder.Token()
+		if err == io.EOF {
+			s.done = true
+			break
+		}
+		if err != nil {
+			return "", err
+		}
+		if start, ok := token.(xml.StartElement); ok && start.Name.Local == "si" {
+			var si xlsxRichText
+			if err = s.decoder.DecodeElement(&si, &start); err != nil {
+				return "", err
+			}
+			s.strings = append(s.strings, si.String())
+		}
+	}
+	if i < 0 || i >= len(s.strings) {
+		return "", fmt.Errorf("共享字符串下标%d超出范围", i)
+	}
+	return s.strings[i], nil
+}
+
+func (s *xlsxSharedStrings) Close() error {
+	if s.rc == nil {
+		return nil
+	}
+	return s.rc.Close()
+}
@@ -9,19 +9,53 @@ import (
 
 var specialFormats = []string{
 	"2006年01月02日15时04分05秒",
+	// Excel 中文日期格式, 如 2023年7月16日
+	"2006年1月2日",
+	"2006年1月2日 15:04:05",
+	"2006年1月2日 15:04",
 }
 
 const DefaultTimeLayout = "2006-01-02 15:04:05"
 
 // FormatDate 格式化日期
 func FormatDate(dateStr, timeLayout string) (string, error) {
+	t, err := ParseDate(dateStr)
+	if err != nil {
+		return "", err
+	}
+	return t.Format(timeLayout), nil
+}
+
+// ParseDate 解析日期
+func ParseDate(dateStr string) (time.Time, error) {
 	// 先使用第三方库解析
 	parsedDate, err := dateparse.ParseAny(dateStr)
 	if err == nil {
-		return parsedDate.Format(timeLayout), nil
+		return parsedDate, nil
 	}
 	// 如果第三方库解析失败，使用自定义解析
-	return FormatOtherDate(dateStr, timeLayout)
+	for _, format := range specialFormats {
+		t, err := time.Parse(format, dateStr)
+		if err == nil {
+			return t, nil
+		}
+	}
+	return time.Time{}, errors.New("constant.FormatDateErr")
+}
+
+// ParseDateIn 按指定时区解析不带时区的日期
+func ParseDateIn(dateStr string, loc *time.Location) (time.Time, error) {
+	parsedDate, err := dateparse.ParseIn(dateStr, loc)
+	if err == nil {
+		return parsedDate, nil
+	}
+	for _, format := range specialFormats {
+		t, err := time.ParseInLocation(format, dateStr, loc)
+		if err == nil {
+			return t, nil
+		}
+	}
+	return time.Time{}, errors.New("constant.FormatDateErr")
 }
 
 // FormatOtherDate 格式化特殊日期